@@ -0,0 +1,128 @@
+package ivy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Shared virtual address space. Pages are PAGE_SIZE bytes and page "P<n>" holds the addresses
+// [n*PAGE_SIZE, (n+1)*PAGE_SIZE). ReadAt/WriteAt fault in every page the range touches, one page
+// at a time: each page access is sequentially consistent, but a range spanning several pages is not
+// read or written atomically.
+//
+// A write travels as a patch, Content written at Offset, and is applied to the latest copy of the
+// page by whichever node holds it. Page contents are never modified in place: every write builds a
+// new slice, so a Page can be shared between the PageStore and messages.
+
+const PAGE_SIZE = 4096
+
+func pageName(pageIndex int) string {
+	return fmt.Sprintf("P%d", pageIndex)
+}
+
+// Returns a copy of content, zero-filled to PAGE_SIZE, with data written at offset
+func applyWrite(content []byte, offset int, data []byte) []byte {
+	page := make([]byte, PAGE_SIZE)
+	copy(page, content)
+	copy(page[offset:], data)
+	return page
+}
+
+func checkWrite(offset int, n int) error {
+	if offset < 0 || offset+n > PAGE_SIZE {
+		return fmt.Errorf("write of %d bytes at offset %d does not fit in a %d byte page", n, offset, PAGE_SIZE)
+	}
+	return nil
+}
+
+// Part of an address range that falls in one page: bytes [start, end) of the range are at offset in pageNo
+type pageSpan struct {
+	pageNo string
+	offset int
+	start  int
+	end    int
+}
+
+// Splits [addr, addr+n) at page boundaries
+func pageSpans(addr int, n int) ([]pageSpan, error) {
+	if addr < 0 || n < 0 {
+		return nil, fmt.Errorf("invalid address range [%d, %d)", addr, addr+n)
+	}
+	spans := []pageSpan{}
+	for start := 0; start < n; {
+		offset := (addr + start) % PAGE_SIZE
+		end := start + PAGE_SIZE - offset
+		if end > n {
+			end = n
+		}
+		spans = append(spans, pageSpan{pageNo: pageName((addr + start) / PAGE_SIZE), offset: offset, start: start, end: end})
+		start = end
+	}
+	return spans, nil
+}
+
+// Reads n bytes at addr. Pages nobody has written yet read as zeros.
+func (c *Client) ReadAt(addr int, n int) ([]byte, error) {
+	ctx, cancel := c.clock.WithTimeout(context.Background(), REQUEST_TIMEOUT)
+	defer cancel()
+	return c.ReadAtContext(ctx, addr, n)
+}
+
+// Same as ReadAt, but gives up when ctx is done
+func (c *Client) ReadAtContext(ctx context.Context, addr int, n int) ([]byte, error) {
+	spans, err := pageSpans(addr, n)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, n)
+	for _, span := range spans {
+		page, err := c.ReadContext(ctx, span.pageNo)
+		if errors.Is(err, errNoSuchPage) {
+			// Reads as zeros
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read at address %d: %w", addr+span.start, err)
+		}
+		if span.offset < len(page.Content) {
+			copy(buf[span.start:span.end], page.Content[span.offset:])
+		}
+	}
+	return buf, nil
+}
+
+// Writes buf at addr
+func (c *Client) WriteAt(addr int, buf []byte) error {
+	ctx, cancel := c.clock.WithTimeout(context.Background(), REQUEST_TIMEOUT)
+	defer cancel()
+	return c.WriteAtContext(ctx, addr, buf)
+}
+
+// Same as WriteAt, but gives up when ctx is done
+func (c *Client) WriteAtContext(ctx context.Context, addr int, buf []byte) error {
+	spans, err := pageSpans(addr, len(buf))
+	if err != nil {
+		return err
+	}
+	for _, span := range spans {
+		data := append([]byte{}, buf[span.start:span.end]...)
+		if err := c.writeAt(ctx, span.pageNo, span.offset, data); err != nil {
+			return fmt.Errorf("write at address %d: %w", addr+span.start, err)
+		}
+	}
+	return nil
+}
+
+// Number of bytes of a page shown when printing it
+const PAGE_PREVIEW = 64
+
+// Page contents with the trailing zeros trimmed and cut at PAGE_PREVIEW bytes, for printing
+func (p Page) String() string {
+	content := bytes.TrimRight(p.Content, "\x00")
+	if len(content) > PAGE_PREVIEW {
+		return fmt.Sprintf("{%s %q... %s}", p.Number, content[:PAGE_PREVIEW], p.Access)
+	}
+	return fmt.Sprintf("{%s %q %s}", p.Number, content, p.Access)
+}
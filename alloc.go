@@ -0,0 +1,181 @@
+package ivy
+
+import (
+	"context"
+	"fmt"
+)
+
+// Explicit page allocation and deletion. A page still comes into existence when it is written for
+// the first time, but it can also be allocated empty with ALLOCATE_PAGE, making the allocating
+// client its owner. FREE_PAGE invalidates every copy, has the owner drop the page from its
+// PageStore and deletes the page's MetaData, so the page number can be reused.
+// Both are handled by the page's manager, and wait for the transaction in progress on the page.
+
+// Creates MetaData for a new page owned by the requester, which installs the empty page once acknowledged
+func (cm *CentralManager) handleAllocatePage(ctx context.Context, msg Message) error {
+	pageNo := msg.Payload.AllocatePage.PageNo
+	holder := "allocate-" + msg.RequestID
+	if _, err := cm.pageLocks.acquire(ctx, pageNo, holder); err != nil {
+		return err
+	}
+	defer cm.pageLocks.release(pageNo, holder)
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if _, exists := cm.MetaData[pageNo]; exists {
+		return fmt.Errorf("page %s already exists", pageNo)
+	}
+	cm.MetaData[pageNo] = PageInfo{
+		Owner:   ClientPointer{ID: msg.FromID, IP: msg.FromIP},
+		CopySet: []ClientPointer{},
+	}
+	logsystem.Printf("Page %s allocated to Client %d\n", pageNo, msg.FromID)
+	return nil
+}
+
+// Invalidates every copy of the page, has the owner drop it, then forgets the page
+func (cm *CentralManager) handleFreePage(ctx context.Context, msg Message) error {
+	pageNo := msg.Payload.FreePage.PageNo
+	holder := "free-" + msg.RequestID
+	if _, err := cm.pageLocks.acquire(ctx, pageNo, holder); err != nil {
+		return err
+	}
+	defer cm.pageLocks.release(pageNo, holder)
+
+	cm.mu.Lock()
+	pageInfo, exists := cm.MetaData[pageNo]
+	cm.mu.Unlock()
+	if !exists {
+		return fmt.Errorf("page %s does not exist", pageNo)
+	}
+
+	invalidateCopy := Message{
+		Type: INVALIDATE_COPY,
+		Payload: Payload{
+			InvalidateCopy: InvalidateCopy{
+				WriteRequesterID: msg.FromID,
+				PageNumber:       pageNo,
+			},
+		},
+		RequestID: msg.RequestID,
+	}
+	err := fanOut(ctx, cm.clock, INVALIDATE_COPY, pageNo, otherHolders(pageInfo.CopySet, pageInfo.Owner, pageInfo.Owner.ID), func(ctx context.Context, clientPointer ClientPointer) Reply {
+		return cm.CallRPC(ctx, invalidateCopy, CLIENT, clientPointer.ID, clientPointer.IP)
+	})
+	if err != nil {
+		logerror.Printf("Cannot free Page %s\n", pageNo)
+		return err
+	}
+
+	// In IMPROVED mode the owner also invalidates the copy set it keeps
+	dropPage := Message{
+		Type: DROP_PAGE,
+		Payload: Payload{
+			DropPage: DropPage{
+				PageNumber: pageNo,
+			},
+		},
+		RequestID: msg.RequestID,
+	}
+	reply := cm.CallRPC(ctx, dropPage, CLIENT, pageInfo.Owner.ID, pageInfo.Owner.IP)
+	if !reply.Ack {
+		logerror.Printf("Msg [%s] from CM not acknowledged by Client %d\n", DROP_PAGE, pageInfo.Owner.ID)
+		return fmt.Errorf("%s for page %s not acknowledged by owner Client %d", DROP_PAGE, pageNo, pageInfo.Owner.ID)
+	}
+
+	cm.mu.Lock()
+	delete(cm.MetaData, pageNo)
+	cm.mu.Unlock()
+	logsystem.Printf("Page %s freed\n", pageNo)
+	return nil
+}
+
+// Returns false if the page could not be dropped, as some copies could not be invalidated
+func (c *Client) handleDropPage(ctx context.Context, msg Message) bool {
+	pageNo := msg.Payload.DropPage.PageNumber
+	if err := c.awaitAllocation(ctx, pageNo); err != nil {
+		logerror.Printf("Cannot drop Page %s: %v\n", pageNo, err)
+		return false
+	}
+	if c.mode == IMPROVED {
+		if err := c.invalidateOwnCopies(ctx, pageNo, c.ID, msg.RequestID); err != nil {
+			logerror.Printf("Cannot drop Page %s: %v\n", pageNo, err)
+			return false
+		}
+	}
+	c.dropPage(pageNo)
+	return true
+}
+
+// Allocates an empty page owned by this client. Fails if the page already exists.
+func (c *Client) AllocatePage(pageNo string) error {
+	if c.mode == DYNAMIC {
+		return fmt.Errorf("pages are allocated by the manager, there is none in %s mode", DYNAMIC)
+	}
+	if err := c.requireSequential("allocating pages"); err != nil {
+		return err
+	}
+	if _, exists := c.localPage(pageNo); exists {
+		return fmt.Errorf("page %s already exists", pageNo)
+	}
+
+	// The CM may forward requests for the page as soon as it is allocated, before its reply arrives.
+	// They wait for the page to be installed.
+	allocated := make(chan struct{})
+	c.mu.Lock()
+	if _, allocating := c.allocations[pageNo]; allocating {
+		c.mu.Unlock()
+		return fmt.Errorf("page %s is already being allocated", pageNo)
+	}
+	c.allocations[pageNo] = allocated
+	c.mu.Unlock()
+
+	err := c.callManager(pageNo, Message{
+		Type: ALLOCATE_PAGE,
+		Payload: Payload{
+			AllocatePage: AllocatePage{
+				PageNo: pageNo,
+			},
+		},
+	})
+	c.mu.Lock()
+	if err == nil {
+		c.PageStore[pageNo] = Page{Number: pageNo, Content: make([]byte, PAGE_SIZE), Access: READWRITE}
+	}
+	delete(c.allocations, pageNo)
+	c.mu.Unlock()
+	close(allocated)
+	return err
+}
+
+// Waits for the allocation of pageNo by this client, if one is in progress
+func (c *Client) awaitAllocation(ctx context.Context, pageNo string) error {
+	c.mu.Lock()
+	allocated, allocating := c.allocations[pageNo]
+	c.mu.Unlock()
+	if !allocating {
+		return nil
+	}
+	if _, err := receiveContext(c.clock, ctx, allocated); err != nil {
+		return fmt.Errorf("waiting for the allocation of page %s: %w", pageNo, err)
+	}
+	return nil
+}
+
+// Frees a page, wherever its copies are
+func (c *Client) FreePage(pageNo string) error {
+	if c.mode == DYNAMIC {
+		return fmt.Errorf("pages are freed by the manager, there is none in %s mode", DYNAMIC)
+	}
+	if err := c.requireSequential("freeing pages"); err != nil {
+		return err
+	}
+	return c.callManager(pageNo, Message{
+		Type: FREE_PAGE,
+		Payload: Payload{
+			FreePage: FreePage{
+				PageNo: pageNo,
+			},
+		},
+	})
+}
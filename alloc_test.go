@@ -0,0 +1,42 @@
+package ivy
+
+import (
+	"testing"
+)
+
+// The allocating client only installs the page once the manager has acknowledged the allocation
+func TestAllocatePageInstalledOnceAcknowledged(t *testing.T) {
+	dsm := newTestDSM(t, Config{}, 2)
+	c1, c2 := dsm.clients[0], dsm.clients[1]
+	installedEarly := false
+	for _, c := range dsm.clients {
+		c := c
+		c.transport = &lossyTransport{Transport: c.transport, drop: func(msg Message) bool {
+			if msg.Type == ALLOCATE_PAGE {
+				_, installed := c.localPage(msg.Payload.AllocatePage.PageNo)
+				installedEarly = installedEarly || installed
+			}
+			return false
+		}}
+	}
+
+	if err := c1.AllocatePage("0"); err != nil {
+		t.Fatal(err)
+	}
+	if installedEarly {
+		t.Error("page installed before the manager acknowledged the allocation")
+	}
+	if page, _ := c1.localPage("0"); page.Access != READWRITE {
+		t.Errorf("allocated page left %s", page.Access)
+	}
+
+	if err := c2.AllocatePage("0"); err == nil {
+		t.Fatal("page allocated twice")
+	}
+	if _, installed := c2.localPage("0"); installed {
+		t.Error("page installed by the refused allocation")
+	}
+	if _, err := c2.Read("0"); err != nil {
+		t.Fatal(err)
+	}
+}
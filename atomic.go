@@ -0,0 +1,176 @@
+package ivy
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+)
+
+// Atomic read-modify-write operations. If the client holds the page with READWRITE access, the
+// operation is applied to its copy under c.mu: every page transition, including giving the page up on
+// a WRITE_FORWARD, happens under c.mu, so no other write can come in between the read and the write.
+// Otherwise the client takes exclusive ownership of the page with a write fault that writes nothing,
+// and the operation is applied as the page is installed, before the WRITE_CONFIRMATION lets the
+// manager hand the page to anyone else.
+//
+// Under write-update the writer of a page that other clients hold only gets a READ copy, so atomic
+// operations fail on such pages.
+
+// Number of times an atomic operation faults the page in before giving up
+const ATOMIC_ATTEMPTS = 10
+
+// Replaces the page content with replacement if it is expected. Both are zero-padded to a full page,
+// as by Write. Reports whether the content was swapped.
+func (c *Client) CompareAndSwap(pageNo string, expected []byte, replacement []byte) (bool, error) {
+	if err := checkWrite(0, len(expected)); err != nil {
+		return false, err
+	}
+	if err := checkWrite(0, len(replacement)); err != nil {
+		return false, err
+	}
+	ctx, cancel := c.clock.WithTimeout(context.Background(), REQUEST_TIMEOUT)
+	defer cancel()
+	return c.compareAndSwapAt(ctx, pageNo, 0, applyWrite(nil, 0, expected), applyWrite(nil, 0, replacement))
+}
+
+// Adds delta to the int64 stored little-endian in the first 8 bytes of the page, as by a
+// SharedInt64, and returns its previous value
+func (c *Client) FetchAndAdd(pageNo string, delta int64) (int64, error) {
+	ctx, cancel := c.clock.WithTimeout(context.Background(), REQUEST_TIMEOUT)
+	defer cancel()
+	return c.fetchAndAddAt(ctx, pageNo, 0, delta)
+}
+
+// Writes replacement at offset if the bytes there are expected, which must have the same length
+func (c *Client) compareAndSwapAt(ctx context.Context, pageNo string, offset int, expected []byte, replacement []byte) (bool, error) {
+	swapped := false
+	err := c.atomic(ctx, pageNo, func(content []byte) []byte {
+		current := make([]byte, len(expected))
+		if offset < len(content) {
+			copy(current, content[offset:])
+		}
+		swapped = bytes.Equal(current, expected)
+		if !swapped {
+			return nil
+		}
+		return applyWrite(content, offset, replacement)
+	})
+	return swapped, err
+}
+
+func (c *Client) fetchAndAddAt(ctx context.Context, pageNo string, offset int, delta int64) (int64, error) {
+	var previous int64
+	err := c.atomic(ctx, pageNo, func(content []byte) []byte {
+		current := make([]byte, 8)
+		if offset < len(content) {
+			copy(current, content[offset:])
+		}
+		previous = int64(binary.LittleEndian.Uint64(current))
+		binary.LittleEndian.PutUint64(current, uint64(previous+delta))
+		return applyWrite(content, offset, current)
+	})
+	return previous, err
+}
+
+// Atomic operation waiting for its page to be installed with READWRITE access
+type atomicOp struct {
+	op      func(content []byte) []byte
+	applied bool
+}
+
+// Takes exclusive ownership of pageNo and applies op to its content
+func (c *Client) atomic(ctx context.Context, pageNo string, op func(content []byte) []byte) error {
+	if err := c.requireSequential("atomic operations"); err != nil {
+		return err
+	}
+	for attempt := 0; attempt < ATOMIC_ATTEMPTS; attempt++ {
+		if c.modifyLocal(pageNo, op) {
+			return nil
+		}
+		pending := &atomicOp{op: op}
+		c.mu.Lock()
+		c.atomicOps[pageNo] = append(c.atomicOps[pageNo], pending)
+		c.mu.Unlock()
+
+		err := c.writeAt(ctx, pageNo, 0, nil)
+		applied := c.removeAtomicOp(pageNo, pending)
+		if err != nil {
+			// Applied or not, this client may not own the page
+			return err
+		}
+		if applied {
+			return nil
+		}
+	}
+	return fmt.Errorf("could not hold page %s exclusively, atomic operations need a write-invalidate page", pageNo)
+}
+
+// Applies op to the content of pageNo if this client has READWRITE access. op returns the new
+// content, or nil to leave the page unchanged, and must not modify the content it is given.
+func (c *Client) modifyLocal(pageNo string, op func(content []byte) []byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	page, exists := c.PageStore[pageNo]
+	if !exists || page.Access != READWRITE {
+		return false
+	}
+	if content := op(page.Content); content != nil {
+		page.Content = content
+		c.PageStore[pageNo] = page
+	}
+	return true
+}
+
+// Applies the atomic operations waiting for page, which is being installed with READWRITE access.
+// Must be called with c.mu held.
+func (c *Client) applyAtomicOpsLocked(page *Page) {
+	for _, pending := range c.atomicOps[page.Number] {
+		if content := pending.op(page.Content); content != nil {
+			page.Content = content
+		}
+		pending.applied = true
+	}
+	delete(c.atomicOps, page.Number)
+}
+
+// Reports whether pending was applied, and stops waiting for the page if not
+func (c *Client) removeAtomicOp(pageNo string, pending *atomicOp) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if pending.applied {
+		return true
+	}
+	ops := c.atomicOps[pageNo]
+	for i, op := range ops {
+		if op == pending {
+			ops = append(ops[:i:i], ops[i+1:]...)
+			break
+		}
+	}
+	if len(ops) == 0 {
+		delete(c.atomicOps, pageNo)
+	} else {
+		c.atomicOps[pageNo] = ops
+	}
+	return false
+}
+
+// Stores n if the value is expected. Reports whether it was stored.
+func (v *SharedInt64) CompareAndSwap(expected int64, n int64) (bool, error) {
+	oldBytes := make([]byte, 8)
+	newBytes := make([]byte, 8)
+	binary.LittleEndian.PutUint64(oldBytes, uint64(expected))
+	binary.LittleEndian.PutUint64(newBytes, uint64(n))
+	ctx, cancel := v.c.clock.WithTimeout(context.Background(), REQUEST_TIMEOUT)
+	defer cancel()
+	return v.c.compareAndSwapAt(ctx, pageName(v.addr/PAGE_SIZE), v.addr%PAGE_SIZE, oldBytes, newBytes)
+}
+
+// Adds delta to the value and returns the new value
+func (v *SharedInt64) Add(delta int64) (int64, error) {
+	ctx, cancel := v.c.clock.WithTimeout(context.Background(), REQUEST_TIMEOUT)
+	defer cancel()
+	previous, err := v.c.fetchAndAddAt(ctx, pageName(v.addr/PAGE_SIZE), v.addr%PAGE_SIZE, delta)
+	return previous + delta, err
+}
@@ -0,0 +1,161 @@
+package ivy
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// Runs op on every client concurrently, ops times each
+func contend(t *testing.T, clients []*Client, ops int, op func(c *Client) error) {
+	t.Helper()
+	done := make(chan struct{}, len(clients))
+	for _, c := range clients {
+		c.clock.Go(func() {
+			defer func() { done <- struct{}{} }()
+			for i := 0; i < ops; i++ {
+				if err := op(c); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		})
+	}
+	for range clients {
+		clients[0].clock.Select(done)
+	}
+}
+
+// Transport that loses the messages drop picks before they are delivered, and the replies to the
+// messages dropReply picks after they are handled
+type lossyTransport struct {
+	Transport
+	drop      func(msg Message) bool
+	dropReply func(msg Message) bool
+}
+
+func (t *lossyTransport) Send(ctx context.Context, target Target, msg Message) (Reply, error) {
+	if t.drop != nil && t.drop(msg) {
+		return Reply{}, fmt.Errorf("%w: %s lost", ErrNotDelivered, msg.Type)
+	}
+	reply, err := t.Transport.Send(ctx, target, msg)
+	if err == nil && t.dropReply != nil && t.dropReply(msg) {
+		return Reply{}, fmt.Errorf("reply to %s lost", msg.Type)
+	}
+	return reply, err
+}
+
+func TestFetchAndAddUnderContention(t *testing.T) {
+	for _, mode := range []string{CENTRAL, IMPROVED, FIXED, DYNAMIC} {
+		t.Run(mode, func(t *testing.T) {
+			dsm := newTestDSM(t, Config{Mode: mode}, 3)
+			var mu sync.Mutex
+			previous := []int{}
+			contend(t, dsm.clients, 5, func(c *Client) error {
+				n, err := c.FetchAndAdd("0", 1)
+				mu.Lock()
+				previous = append(previous, int(n))
+				mu.Unlock()
+				return err
+			})
+			// Every addition saw the one before it
+			sort.Ints(previous)
+			for i, n := range previous {
+				if n != i {
+					t.Fatalf("additions saw %v, want 0 to 14 once each", previous)
+				}
+			}
+			if n, err := dsm.clients[0].FetchAndAdd("0", 0); err != nil || n != 15 {
+				t.Errorf("counter at %d, %v, want 15", n, err)
+			}
+		})
+	}
+}
+
+func TestCompareAndSwapUnderContention(t *testing.T) {
+	for _, mode := range []string{CENTRAL, IMPROVED, FIXED, DYNAMIC} {
+		t.Run(mode, func(t *testing.T) {
+			dsm := newTestDSM(t, Config{Mode: mode}, 3)
+			// Exactly one client claims the page
+			var mu sync.Mutex
+			winners := []int{}
+			contend(t, dsm.clients, 1, func(c *Client) error {
+				swapped, err := c.CompareAndSwap("0", nil, []byte(fmt.Sprint(c.ID)))
+				if swapped {
+					mu.Lock()
+					winners = append(winners, c.ID)
+					mu.Unlock()
+				}
+				return err
+			})
+			if len(winners) != 1 {
+				t.Fatalf("clients %v claimed the page, want one", winners)
+			}
+			page, err := dsm.clients[0].Read("0")
+			if err != nil || historyValue(page.Content) != fmt.Sprint(winners[0]) {
+				t.Fatalf("page read as %q, %v, want the winner", historyValue(page.Content), err)
+			}
+
+			// Increments retried until their swap succeeds, on copies that may be stale
+			if err := dsm.clients[0].Write("1", []byte{0}); err != nil {
+				t.Fatal(err)
+			}
+			contend(t, dsm.clients, 3, func(c *Client) error {
+				for {
+					page, err := c.Read("1")
+					if err != nil {
+						return err
+					}
+					current := page.Content[0]
+					swapped, err := c.CompareAndSwap("1", []byte{current}, []byte{current + 1})
+					if err != nil || swapped {
+						return err
+					}
+				}
+			})
+			if page, err := dsm.clients[1].Read("1"); err != nil || page.Content[0] != 9 {
+				t.Errorf("counter at %v, %v, want 9", page.Content[:1], err)
+			}
+		})
+	}
+}
+
+// Under write-update the writer of a shared page only gets a READ copy, which cannot be held exclusively
+func TestAtomicFailsOnSharedWriteUpdatePage(t *testing.T) {
+	dsm := newTestDSM(t, Config{}, 2)
+	c1, c2 := dsm.clients[0], dsm.clients[1]
+	if err := c1.Write("0", []byte{1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c1.SetCoherence("0", WRITE_UPDATE); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c2.Read("0"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c1.FetchAndAdd("0", 1); err == nil {
+		t.Error("atomic operation on a shared write-update page succeeded")
+	}
+	page, err := c2.Read("0")
+	if err != nil || !reflect.DeepEqual(page.Content[:2], []byte{1, 0}) {
+		t.Errorf("page read as %v, %v, want it unchanged", page.Content[:2], err)
+	}
+}
+
+// An operation applied as the page is installed fails if the manager never hears that the page moved
+func TestAtomicFailsOnUnconfirmedOwnership(t *testing.T) {
+	dsm := newTestDSM(t, Config{}, 2)
+	c1, c2 := dsm.clients[0], dsm.clients[1]
+	if err := c1.Write("0", []byte{1}); err != nil {
+		t.Fatal(err)
+	}
+	c2.transport = &lossyTransport{Transport: c2.transport, drop: func(msg Message) bool {
+		return msg.Type == WRITE_CONFIRMATION
+	}}
+	if _, err := c2.FetchAndAdd("0", 1); err == nil {
+		t.Error("atomic operation succeeded without the page changing hands at the manager")
+	}
+}
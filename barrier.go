@@ -0,0 +1,153 @@
+package ivy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Barriers, hosted by the same manager as the lock of the same name. The CM answers each
+// BARRIER_WAIT once n clients have arrived at the barrier, then starts a new generation, so the same
+// barrier can separate the phases of a computation. Arrivals are identified by RequestID, so a retried
+// BARRIER_WAIT is not counted twice. The CM waits at the barrier under the deadline of the
+// BARRIER_WAIT, and no longer counts an arrival whose client has stopped waiting.
+//
+// Under release consistency a client sends its writes to their homes before waiting at a barrier and
+// drops its cached copies after, so every client sees the writes made by all of them before the barrier.
+
+// Barriers kept by the CM
+type barrierTable struct {
+	mu       sync.Mutex
+	clock    clock
+	barriers map[string]*barrier
+}
+
+type barrier struct {
+	n int
+	// BARRIER_WAITs waiting for each arrival, as a retry may come in while the first one still waits
+	arrived map[string]int
+	// Closed when the current generation is complete
+	release chan struct{}
+	// Arrivals of the previous generation, which may retry if the reply to them was lost
+	passed map[string]int
+}
+
+func newBarrierTable(clock clock) *barrierTable {
+	return &barrierTable{clock: clock, barriers: make(map[string]*barrier)}
+}
+
+// Blocks until n arrivals, including this one, have reached the barrier.
+// Fails if the clients waiting at the barrier expect a different n, or if ctx is done first, and the
+// arrival is withdrawn.
+func (bt *barrierTable) wait(ctx context.Context, name string, arrival string, n int) error {
+	bt.mu.Lock()
+	b, exists := bt.barriers[name]
+	if !exists {
+		b = &barrier{n: n, arrived: make(map[string]int), release: make(chan struct{})}
+		bt.barriers[name] = b
+	}
+	if b.passed[arrival] > 0 {
+		bt.mu.Unlock()
+		return nil
+	}
+	if b.n != n {
+		if len(b.arrived) > 0 {
+			bt.mu.Unlock()
+			return fmt.Errorf("barrier %s is waiting for %d clients, not %d", name, b.n, n)
+		}
+		b.n = n
+	}
+
+	b.arrived[arrival]++
+	if len(b.arrived) >= b.n {
+		close(b.release)
+		b.passed = b.arrived
+		b.arrived = make(map[string]int)
+		b.release = make(chan struct{})
+		bt.mu.Unlock()
+		return nil
+	}
+	release := b.release
+	arrived := len(b.arrived)
+	bt.mu.Unlock()
+
+	logsystem.Printf("Barrier %s: %d of %d clients arrived\n", name, arrived, n)
+	if _, err := receiveContext(bt.clock, ctx, release); err == nil {
+		return nil
+	}
+
+	bt.mu.Lock()
+	defer bt.mu.Unlock()
+	if b.release != release {
+		// Released just as ctx was done
+		return nil
+	}
+	if b.arrived[arrival]--; b.arrived[arrival] == 0 {
+		delete(b.arrived, arrival)
+	}
+	return fmt.Errorf("waiting at barrier %s: %w", name, ctx.Err())
+}
+
+func (cm *CentralManager) handleBarrierWait(ctx context.Context, msg Message) error {
+	wait := msg.Payload.Barrier
+	if wait.N < 1 {
+		return fmt.Errorf("barrier %s needs at least 1 client, not %d", wait.Name, wait.N)
+	}
+	if err := cm.barriers.wait(ctx, wait.Name, msg.RequestID, wait.N); err != nil {
+		return err
+	}
+	logsystem.Printf("Barrier %s released Client %d\n", wait.Name, msg.FromID)
+	return nil
+}
+
+// Blocks until n clients, this one included, have called Barrier with the same name.
+// The barrier can be used again once released.
+func (c *Client) Barrier(name string, n int) error {
+	return c.BarrierContext(context.Background(), name, n)
+}
+
+// Same as Barrier, but gives up when ctx is done. A client that gave up at the deadline of ctx is no
+// longer counted at the barrier.
+func (c *Client) BarrierContext(ctx context.Context, name string, n int) error {
+	if c.mode == DYNAMIC {
+		return fmt.Errorf("barriers are hosted by the manager, there is none in %s mode", DYNAMIC)
+	}
+	if c.consistency == RELEASE {
+		if _, err := c.flushDiffs(ctx); err != nil {
+			return err
+		}
+	}
+	msg := Message{
+		Type: BARRIER_WAIT,
+		Payload: Payload{
+			Barrier: Barrier{
+				Name: name,
+				N:    n,
+			},
+		},
+		FromID:    c.ID,
+		FromIP:    c.IP,
+		RequestID: c.pending.newRequestID(c.ID),
+	}
+	for {
+		reply := c.CallRPC(ctx, msg, CENTRALMANAGER, -1, c.managerIP(name))
+		if reply.Ack {
+			if c.consistency == RELEASE {
+				c.dropCachedPages()
+			}
+			return nil
+		}
+		// The manager gives up at the same deadline
+		if ctx.Err() != nil {
+			return fmt.Errorf("%s for barrier %s: %w", BARRIER_WAIT, name, ctx.Err())
+		}
+		if reply.Err != "" {
+			return errors.New(reply.Err)
+		}
+		logwarning.Printf("Msg [%s] for barrier %s not acknowledged, retrying...\n", BARRIER_WAIT, name)
+		if _, err := receiveContext(c.clock, ctx, c.clock.After(RETRY_INTERVAL)); err != nil {
+			return fmt.Errorf("%s for barrier %s: %w", BARRIER_WAIT, name, err)
+		}
+	}
+}
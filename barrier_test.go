@@ -0,0 +1,108 @@
+package ivy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestBarrierTableGenerations(t *testing.T) {
+	bt := newBarrierTable(newSimClock())
+	released := make(chan string, 3)
+	arrive := func(arrival string) {
+		go func() {
+			if err := bt.wait(context.Background(), "b", arrival, 3); err != nil {
+				t.Errorf("%s: %v", arrival, err)
+			}
+			released <- arrival
+		}()
+	}
+	arrive("a1")
+	arrive("b1")
+	arrivals := func(arrival string) int {
+		bt.mu.Lock()
+		defer bt.mu.Unlock()
+		if b, exists := bt.barriers["b"]; exists {
+			return b.arrived[arrival]
+		}
+		return 0
+	}
+	waitFor(t, func() bool { return arrivals("a1") == 1 && arrivals("b1") == 1 })
+	// A retry of a waiting arrival is not counted again
+	arrive("a1")
+	waitFor(t, func() bool { return arrivals("a1") == 2 })
+	select {
+	case arrival := <-released:
+		t.Fatalf("%s released before the barrier filled", arrival)
+	case <-time.After(10 * time.Millisecond):
+	}
+	if err := bt.wait(context.Background(), "b", "c1", 4); err == nil {
+		t.Error("arrival expecting another n accepted")
+	}
+
+	if err := bt.wait(context.Background(), "b", "c1", 3); err != nil {
+		t.Fatal(err)
+	}
+	receive(t, released, "a1", "a1", "b1")
+
+	// A retry from the previous generation passes at once, and the next generation starts empty
+	if err := bt.wait(context.Background(), "b", "b1", 3); err != nil {
+		t.Fatal(err)
+	}
+	if arrivals("b1") != 0 {
+		t.Error("retry counted in the new generation")
+	}
+}
+
+// Each client writes its page, and every client sees all the writes of a phase once past the barrier
+func TestBarrierSeparatesPhases(t *testing.T) {
+	for _, mode := range []string{CENTRAL, FIXED} {
+		t.Run(mode, func(t *testing.T) {
+			const n = 3
+			dsm := newTestDSM(t, Config{Mode: mode}, n)
+			contend(t, dsm.clients, 1, func(c *Client) error {
+				// Client IDs start at 1
+				i := c.ID - 1
+				for phase := 1; phase <= 3; phase++ {
+					if err := c.Write(fmt.Sprint(i), []byte(fmt.Sprint(phase))); err != nil {
+						return err
+					}
+					if err := c.Barrier("written", n); err != nil {
+						return err
+					}
+					for j := 0; j < n; j++ {
+						page, err := c.Read(fmt.Sprint(j))
+						if err != nil || historyValue(page.Content) != fmt.Sprint(phase) {
+							t.Errorf("phase %d: Client %d read page %d as %q, %v", phase, c.ID, j, historyValue(page.Content), err)
+						}
+					}
+					if err := c.Barrier("read", n); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		})
+	}
+}
+
+// A client that gave up at its deadline is no longer counted, so the barrier does not let the next
+// client through on its own
+func TestBarrierContextWithdrawsArrival(t *testing.T) {
+	dsm := newTestDSM(t, Config{}, 2)
+	for i := 0; i < 2; i++ {
+		ctx, cancel := dsm.clock.WithTimeout(context.Background(), time.Second)
+		err := dsm.clients[0].BarrierContext(ctx, "b", 2)
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+		}
+		dsm.waitFor(t, func() bool {
+			dsm.cm.barriers.mu.Lock()
+			defer dsm.cm.barriers.mu.Unlock()
+			return len(dsm.cm.barriers.barriers["b"].arrived) == 0
+		})
+	}
+}
@@ -1,8 +1,12 @@
-package main
+package ivy
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"math/rand"
+	"sync"
 	"time"
 )
 
@@ -13,11 +17,60 @@ const (
 	NIL       = "NIL"
 )
 
+// Returned when the CM reports that a retried request has already completed
+var errAlreadyCompleted = errors.New("request already completed")
+
+// Returned when reading a page nobody has written or allocated
+var errNoSuchPage = errors.New("page does not exist")
+
 type Client struct {
 	ID        int
 	IP        string
 	PageStore map[string]Page
 	CMIP      string
+
+	// Guards PageStore, CMIP, invalidatedFetches, atomicOps, allocations, diffs, unsentDiffs, homeVersions
+	// and peers.
+	// Never held across an RPC.
+	mu *sync.Mutex
+	// Pages that were invalidated while in FETCHING_READ
+	invalidatedFetches map[string]bool
+	// Atomic operations to apply when each page arrives with READWRITE access
+	atomicOps map[string][]*atomicOp
+	// Pages this client is allocating, each closed once the allocation is over
+	allocations map[string]chan struct{}
+	// Under release consistency: writes not released yet, HOME_DIFFs to resend, and the home version
+	// of each cached copy
+	diffs        map[string][]Diff
+	unsentDiffs  []Message
+	homeVersions map[string]int
+	pending      *pendingTable
+	// IPs of the other clients by ID, used in FIXED mode
+	peers map[int]string
+	// Manager for this client's slice of the page space in FIXED mode
+	manager *CentralManager
+	// Probable owner of each page in DYNAMIC mode.
+	// Copy set of each page this client owns, in DYNAMIC and IMPROVED modes.
+	probOwners map[string]ClientPointer
+	copySets   map[string][]ClientPointer
+	// Serializes the faults and requests for each page in DYNAMIC mode
+	pageLocks *pageLockTable
+	stats     *messageStats
+	// Set when the client records its history
+	history *historyRecorder
+
+	mode        string
+	consistency string
+	// Number of clients sharing the page space in FIXED mode
+	nodes    int
+	registry registry
+	// Set by Start, closed by Stop
+	listener io.Closer
+	// How the node sends and receives messages
+	transport Transport
+	// Deadlines and retries of the messages the node sends
+	calls callPolicy
+	clock clock
 }
 
 type ClientPointer struct {
@@ -25,26 +78,84 @@ type ClientPointer struct {
 	IP string
 }
 
+// Content is always PAGE_SIZE bytes once the page has been written, and never modified in place
 type Page struct {
 	Number  string
-	Content string
+	Content []byte
 	Access  string
 }
 
+func newClient(cfg Config, id int, ip string, cmip string) *Client {
+	c := &Client{
+		ID:                 id,
+		IP:                 ip,
+		PageStore:          make(map[string]Page),
+		CMIP:               cmip,
+		mu:                 &sync.Mutex{},
+		invalidatedFetches: make(map[string]bool),
+		atomicOps:          make(map[string][]*atomicOp),
+		allocations:        make(map[string]chan struct{}),
+		diffs:              make(map[string][]Diff),
+		homeVersions:       make(map[string]int),
+		pending:            newPendingTable(cfg.clock.Now()),
+		peers:              make(map[int]string),
+		probOwners:         make(map[string]ClientPointer),
+		copySets:           make(map[string][]ClientPointer),
+		pageLocks:          newPageLockTable(cfg.clock),
+		stats:              newMessageStats(),
+		mode:               cfg.Mode,
+		consistency:        cfg.Consistency,
+		nodes:              cfg.Nodes,
+		registry:           registry{dir: cfg.DataDir},
+		transport:          cfg.Transport,
+		calls:              newCallPolicy(cfg),
+		clock:              cfg.clock,
+	}
+	if c.mode == FIXED {
+		// Manage this client's slice of the page space on the same address
+		c.manager = newCentralManager(cfg, ip, true)
+	}
+	return c
+}
+
 func (c *Client) HandleIncomingMessage(msg Message, reply *Reply) error {
 	logincoming.Printf("Message of Type [%s] received\n", msg.Type)
+	c.stats.countReceived(msg.Type)
+	ctx, cancel := c.calls.context(msg)
+	defer cancel()
 	switch msg.Type {
-	case READ_FORWARD:
-		c.handleReadForward(msg)
+	case READ_REQUEST, WRITE_REQUEST:
+		// Only sent to clients in DYNAMIC mode
+		if err := c.handleDynamicRequest(ctx, msg); err != nil {
+			reply.Err = err.Error()
+			return nil
+		}
 		reply.Ack = true
+	case READ_FORWARD:
+		reply.Ack = c.handleReadForward(ctx, msg)
 	case PAGE_SEND:
-		c.handlePageSend(msg)
+		if c.mode == DYNAMIC {
+			c.handleDynamicPageSend(ctx, msg)
+		} else {
+			c.handlePageSend(msg)
+		}
 		reply.Ack = true
 	case INVALIDATE_COPY:
-		reply.Ack = c.handleInvalidateCopy(msg)
+		if c.mode == DYNAMIC {
+			c.handleDynamicInvalidateCopy(msg)
+			reply.Ack = true
+		} else {
+			reply.Ack = c.handleInvalidateCopy(msg)
+		}
 	case WRITE_FORWARD:
-		c.handleWriteForward(msg)
+		reply.Ack = c.handleWriteForward(ctx, msg)
+	case UPDATE_COPY:
+		c.handleUpdateCopy(msg)
 		reply.Ack = true
+	case UPGRADE_GRANT:
+		reply.Ack = c.handleUpgradeGrant(msg)
+	case DROP_PAGE:
+		reply.Ack = c.handleDropPage(ctx, msg)
 	case CHANGE_CM:
 		c.handleChangeCM(msg)
 		reply.Ack = true
@@ -52,11 +163,20 @@ func (c *Client) HandleIncomingMessage(msg Message, reply *Reply) error {
 	return nil
 }
 
+// Downgrades own copy to READ
 // Sends PageSend to ReadRequester
-func (c *Client) handleReadForward(msg Message) {
+func (c *Client) handleReadForward(ctx context.Context, msg Message) bool {
 	// Construct PageSend message
 	requestedPageNo := msg.Payload.ReadForward.PageNo
-	requestedPage := c.PageStore[requestedPageNo]
+	if err := c.awaitAllocation(ctx, requestedPageNo); err != nil {
+		logerror.Println("Cannot send page: ", err)
+		return false
+	}
+	requestedPage, exists := c.downgradePage(requestedPageNo)
+	if !exists {
+		logerror.Printf("Page %s requested (to read) by Client %d does not exist in Client %d's PageStore\n", requestedPageNo, msg.Payload.ReadForward.ReadRequesterID, c.ID)
+		return false
+	}
 	pageSendMsg := Message{
 		Type: PAGE_SEND,
 		Payload: Payload{
@@ -64,22 +184,41 @@ func (c *Client) handleReadForward(msg Message) {
 				Purpose: READ,
 				Page:    requestedPage,
 			}},
-		FromID: c.ID,
-		FromIP: c.IP,
+		FromID:    c.ID,
+		FromIP:    c.IP,
+		RequestID: msg.RequestID,
 	}
 
 	readRequestedID := msg.Payload.ReadForward.ReadRequesterID
 	readRequesterIP := msg.Payload.ReadForward.ReadRequesterIP
 
+	// In IMPROVED mode the owner tracks the copy set. The requester is added before the page is sent,
+	// as its READ_CONFIRMATION lets the CM forward the next write to this client.
+	var previousCopySet []ClientPointer
+	if c.mode == IMPROVED {
+		c.mu.Lock()
+		previousCopySet = c.copySets[requestedPageNo]
+		c.copySets[requestedPageNo] = addToCopySet(previousCopySet, ClientPointer{ID: readRequestedID, IP: readRequesterIP})
+		c.mu.Unlock()
+	}
+
 	logoutgoing.Printf("Client %d sending Msg %s to Client %d\n", c.ID, PAGE_SEND, readRequestedID)
-	reply := c.CallRPC(pageSendMsg, CLIENT, readRequestedID, readRequesterIP)
+	reply := c.CallRPC(ctx, pageSendMsg, CLIENT, readRequestedID, readRequesterIP)
 	if !reply.Ack {
 		logerror.Printf("Msg [%s] from Client %d not acknowledged by Client %d\n", pageSendMsg.Type, c.ID, readRequestedID)
-		return
+		if c.mode == IMPROVED {
+			c.mu.Lock()
+			c.copySets[requestedPageNo] = previousCopySet
+			c.mu.Unlock()
+		}
+		return false
 	}
+	return true
 }
 
 // Replaces old page with new page in PageSend.
+// The page is installed before confirming with the CM, as the CM may hand the page to the next
+// request as soon as it has the confirmation.
 func (c *Client) handlePageSend(msg Message) {
 	// Add page to PageStore
 	sentPageNo := msg.Payload.PageSend.Page.Number
@@ -87,7 +226,7 @@ func (c *Client) handlePageSend(msg Message) {
 	purpose := msg.Payload.PageSend.Purpose
 
 	if purpose == READ {
-		sentPage.Access = READ
+		sentPage = c.installPage(sentPage, READ)
 		readConf := Message{
 			Type: READ_CONFIRMATION,
 			Payload: Payload{
@@ -99,68 +238,106 @@ func (c *Client) handlePageSend(msg Message) {
 					SenderIP:        msg.FromIP,
 				},
 			},
+			RequestID: msg.RequestID,
 		}
-		reply := c.CallRPC(readConf, CENTRALMANAGER, -1, c.CMIP)
+		// Sent even if the PAGE_SEND's sender has stopped waiting, as the page is installed and the CM
+		// holds the page until it is confirmed
+		reply := c.CallRPC(context.Background(), readConf, CENTRALMANAGER, -1, c.managerIP(sentPageNo))
 		if !reply.Ack {
 			logerror.Printf("Msg [%s] from Client %d not acknowledged by CM\n", READ_CONFIRMATION, c.ID)
+			// The CM does not know about this copy and would not invalidate it
+			c.invalidatePage(sentPageNo)
+			c.pending.complete(msg.RequestID, requestResult{err: fmt.Errorf("%s for page %s not acknowledged by CM", READ_CONFIRMATION, sentPageNo)})
 			return
 		}
 
 	} else if purpose == WRITE {
-		sentPage.Access = READWRITE
-
-		writeConf := Message{
-			Type: WRITE_CONFIRMATION,
-			Payload: Payload{
-				WriteConfirmation: WriteConfirmation{
-					PageNumber: sentPageNo,
-					WriterID:   c.ID,
-					WriterIP:   c.IP,
-				},
-			},
+		access := READWRITE
+		if msg.Payload.PageSend.Shared {
+			access = READ
 		}
-		reply := c.CallRPC(writeConf, CENTRALMANAGER, -1, c.CMIP)
-		if !reply.Ack {
-			logerror.Printf("Msg [%s] from Client %d not acknowledged by CM\n", WRITE_CONFIRMATION, c.ID)
+		sentPage = c.installPage(sentPage, access)
+		if c.mode == IMPROVED {
+			// Ownership comes with the copy set
+			c.mu.Lock()
+			if len(msg.Payload.PageSend.CopySet) > 0 {
+				c.copySets[sentPageNo] = msg.Payload.PageSend.CopySet
+			} else {
+				delete(c.copySets, sentPageNo)
+			}
+			c.mu.Unlock()
+		}
+
+		if err := c.confirmWrite(sentPageNo, msg.RequestID); err != nil {
+			// Only the reply may have been lost. The CM answers the resent request with Done if it
+			// completed the write, and keeps it waiting otherwise.
+			c.pending.retry(msg.RequestID)
 			return
 		}
 	}
 
-	c.PageStore[sentPageNo] = sentPage
+	c.pending.complete(msg.RequestID, requestResult{page: sentPage})
 }
 
-// Sets targetPage.Access as NIL
-func (c *Client) handleInvalidateCopy(msg Message) bool {
-	targetPageNo := msg.Payload.InvalidateCopy.PageNumber
-	targetPage, exists := c.PageStore[targetPageNo]
-	if !exists {
-		logerror.Printf("Page %s doesn't exist in Node %d's PageStore. Cannot invalidate", targetPageNo, c.ID)
-		return false
+// Sends WRITE_CONFIRMATION for a page this client now owns, ending the write transaction at the CM.
+// Like READ_CONFIRMATION it does not share the deadline of the message that delivered the page.
+func (c *Client) confirmWrite(pageNo string, requestID string) error {
+	writeConf := Message{
+		Type: WRITE_CONFIRMATION,
+		Payload: Payload{
+			WriteConfirmation: WriteConfirmation{
+				PageNumber: pageNo,
+				WriterID:   c.ID,
+				WriterIP:   c.IP,
+			},
+		},
+		RequestID: requestID,
+	}
+	reply := c.CallRPC(context.Background(), writeConf, CENTRALMANAGER, -1, c.managerIP(pageNo))
+	if !reply.Ack {
+		logerror.Printf("Msg [%s] from Client %d not acknowledged by CM\n", WRITE_CONFIRMATION, c.ID)
+		return fmt.Errorf("%s for page %s not acknowledged by CM", WRITE_CONFIRMATION, pageNo)
 	}
+	return nil
+}
 
-	targetPage.Access = NIL
-	c.PageStore[targetPageNo] = targetPage
+// Sets targetPage.Access as NIL
+func (c *Client) handleInvalidateCopy(msg Message) bool {
+	c.invalidatePage(msg.Payload.InvalidateCopy.PageNumber)
 	return true
 }
 
 // Sets own Page.Access to NIL
 // Sends Page to writeRequester
-func (c *Client) handleWriteForward(msg Message) {
+func (c *Client) handleWriteForward(ctx context.Context, msg Message) bool {
 	// Extract WRITEFORWARD msg content
 	writeRequesterID := msg.Payload.WriteForward.WriteRequesterID
 	writeRequesterIP := msg.Payload.WriteForward.WriteRequesterIP
 	requestedPage := msg.Payload.WriteForward.PageNumber
+	offset := msg.Payload.WriteForward.Offset
 	content := msg.Payload.WriteForward.Content
+	if err := c.awaitAllocation(ctx, requestedPage); err != nil {
+		logerror.Println("Cannot hand over page: ", err)
+		return false
+	}
 
-	// Get page from PageStore, set access to NIL, update content.
-	page, exists := c.PageStore[requestedPage]
-	page.Access = NIL
-	page.Content = content
-	c.PageStore[requestedPage] = page
+	if msg.Payload.WriteForward.Coherence == WRITE_UPDATE {
+		return c.handleUpdateWriteForward(ctx, msg)
+	}
 
+	// In IMPROVED mode the owner invalidates the copies before giving up the page
+	if c.mode == IMPROVED {
+		if err := c.invalidateOwnCopies(ctx, requestedPage, writeRequesterID, msg.RequestID); err != nil {
+			logerror.Println("Cannot hand over page: ", err)
+			return false
+		}
+	}
+
+	// Get page from PageStore, set access to NIL, apply the write.
+	page, exists := c.surrenderPage(requestedPage, offset, content)
 	if !exists {
-		logerror.Printf("Page %s requested (to write) by Client %d does not exist in Client %d's PageStore", requestedPage, writeRequesterID, c.ID)
-		return
+		logerror.Printf("Page %s requested (to write) by Client %d does not exist in Client %d's PageStore\n", requestedPage, writeRequesterID, c.ID)
+		return false
 	}
 
 	pageSend := Message{
@@ -171,91 +348,291 @@ func (c *Client) handleWriteForward(msg Message) {
 				Page:    page,
 			},
 		},
-		FromID: c.ID,
-		FromIP: c.IP,
+		FromID:    c.ID,
+		FromIP:    c.IP,
+		RequestID: msg.RequestID,
 	}
 	logoutgoing.Printf("Client %d sending Msg %s to Client %d\n", c.ID, PAGE_SEND, writeRequesterID)
-	reply := c.CallRPC(pageSend, CLIENT, writeRequesterID, writeRequesterIP)
+	reply := c.CallRPC(ctx, pageSend, CLIENT, writeRequesterID, writeRequesterIP)
 	if !reply.Ack {
 		logerror.Printf("Msg [%s] from Client %d not acknowledged by Client %d\n", INVALIDATE_CONFIRMATION, c.ID, writeRequesterID)
-		return
+		return false
+	}
+	return true
+}
+
+// Blocks until pageNo has landed in the PageStore with READ access, or REQUEST_TIMEOUT elapses
+func (c *Client) Read(pageNo string) (Page, error) {
+	ctx, cancel := c.clock.WithTimeout(context.Background(), REQUEST_TIMEOUT)
+	defer cancel()
+	return c.ReadContext(ctx, pageNo)
+}
+
+// Same as Read, but gives up when ctx is done
+func (c *Client) ReadContext(ctx context.Context, pageNo string) (Page, error) {
+	invoke := c.clock.Now()
+	page, err := c.read(ctx, pageNo)
+	c.recordRead(pageNo, invoke, page, err)
+	return page, err
+}
+
+func (c *Client) read(ctx context.Context, pageNo string) (Page, error) {
+	if c.consistency == RELEASE {
+		return c.releaseRead(ctx, pageNo)
+	}
+	if page, exists := c.localPage(pageNo); exists {
+		logsystem.Printf("Page %s exists in local storage with %s access\n", pageNo, page.Access)
+		return page, nil
+	}
+	if c.mode == DYNAMIC {
+		return c.dynamicRead(ctx, pageNo)
 	}
+
+	for {
+		c.beginFetch(pageNo, FETCHING_READ)
+		page, err := c.awaitRequest(ctx, c.newReadRequest(pageNo))
+		if err != nil {
+			c.abortFetch(pageNo, FETCHING_READ)
+		}
+		if err == errAlreadyCompleted {
+			// The CM saw this read complete before, but we never got to hear about it. Reads are idempotent, so just read again.
+			continue
+		}
+		return page, err
+	}
+}
+
+// Replaces the content of pageNo, zero-filling the rest of the page.
+// Blocks until the write has been confirmed with the CM, or REQUEST_TIMEOUT elapses.
+func (c *Client) Write(pageNo string, content []byte) error {
+	ctx, cancel := c.clock.WithTimeout(context.Background(), REQUEST_TIMEOUT)
+	defer cancel()
+	return c.WriteContext(ctx, pageNo, content)
+}
+
+// Same as Write, but gives up when ctx is done
+func (c *Client) WriteContext(ctx context.Context, pageNo string, content []byte) error {
+	if err := checkWrite(0, len(content)); err != nil {
+		return err
+	}
+	invoke := c.clock.Now()
+	err := c.writeAt(ctx, pageNo, 0, applyWrite(nil, 0, content))
+	c.recordWrite(pageNo, content, invoke, err)
+	return err
+}
+
+// Writes data at offset in pageNo, faulting the page in if needed
+func (c *Client) writeAt(ctx context.Context, pageNo string, offset int, data []byte) error {
+	if c.consistency == RELEASE {
+		return c.releaseWrite(ctx, pageNo, offset, data)
+	}
+	if c.writeLocal(pageNo, offset, data) {
+		return nil
+	}
+	if c.mode == DYNAMIC {
+		return c.dynamicWrite(ctx, pageNo, offset, data)
+	}
+
+	writeRequest := c.newWriteFault(pageNo, offset, data)
+	c.beginFetch(pageNo, FETCHING_WRITE)
+	_, err := c.awaitRequest(ctx, writeRequest)
+	if err != nil {
+		c.abortFetch(pageNo, FETCHING_WRITE)
+	}
+	if err == errAlreadyCompleted {
+		// The CM already applied this write, only the confirmation got lost. The page was installed
+		// here before the write was confirmed, but may have moved on to another client since, so it is
+		// fetched again: the copy holds this write or a later one.
+		if page, exists := c.localPage(pageNo); exists && page.Access == READWRITE {
+			return nil
+		}
+		if _, err := c.read(ctx, pageNo); err != nil {
+			return fmt.Errorf("write to page %s completed, fetching the page again: %w", pageNo, err)
+		}
+		return nil
+	}
+	return err
 }
 
-func (c *Client) sendReadRequest(pageNo string) {
-	readRequest := Message{
+// Sends a READ_REQUEST/WRITE_REQUEST to the CM and blocks until its PAGE_SEND has been confirmed.
+// The request is resent under the same RequestID after CHANGE_CM or every RETRY_INTERVAL, so a
+// request lost in a CM failover is picked up by the new CM. The CM deduplicates the retries.
+// In DYNAMIC mode there is nothing to deduplicate retries, so the request is only sent once.
+func (c *Client) awaitRequest(ctx context.Context, msg Message) (Page, error) {
+	req := c.pending.add(msg)
+	defer c.pending.remove(msg.RequestID)
+
+	var retry <-chan time.Time
+	if c.mode != DYNAMIC {
+		retryTicker := c.clock.NewTicker(RETRY_INTERVAL)
+		defer retryTicker.Stop()
+		retry = retryTicker.Ticks()
+	}
+
+	for {
+		nodeType, targetID, targetIP := c.requestTarget(requestPageNo(msg))
+		reply := c.CallRPC(ctx, msg, nodeType, targetID, targetIP)
+		if reply.Err == errNoSuchPage.Error() {
+			return Page{}, fmt.Errorf("%w: %s", errNoSuchPage, requestPageNo(msg))
+		}
+		if reply.Err != "" {
+			return Page{}, errors.New(reply.Err)
+		}
+		if reply.Done {
+			return Page{}, errAlreadyCompleted
+		}
+		if !reply.Ack {
+			logwarning.Printf("Msg [%s] %s from Client %d not acknowledged by CM, will retry\n", msg.Type, msg.RequestID, c.ID)
+		}
+
+		// A request completed while the ticker fired is not retried
+		select {
+		case result := <-req.done:
+			return result.page, result.err
+		default:
+		}
+		switch i, received := c.clock.Select(req.done, req.retry, retry, ctx.Done()); i {
+		case 0:
+			result := received.(requestResult)
+			return result.page, result.err
+		case 1:
+			logsystem.Printf("Retrying Msg [%s] %s with new CM\n", msg.Type, msg.RequestID)
+		case 2:
+			logsystem.Printf("Msg [%s] %s timed out, retrying\n", msg.Type, msg.RequestID)
+		default:
+			return Page{}, fmt.Errorf("%s %s for page %s: %w", msg.Type, msg.RequestID, requestPageNo(msg), ctx.Err())
+		}
+	}
+}
+
+func requestPageNo(msg Message) string {
+	if msg.Type == WRITE_REQUEST || msg.Type == UPGRADE_REQUEST {
+		return msg.Payload.WriteRequest.PageNo
+	}
+	return msg.Payload.ReadRequest.PageNo
+}
+
+func (c *Client) newReadRequest(pageNo string) Message {
+	return Message{
 		Type: READ_REQUEST,
 		Payload: Payload{
 			ReadRequest: ReadRequest{
 				PageNo: pageNo,
 			},
 		},
-		FromID: c.ID,
-		FromIP: c.IP,
-	}
-
-	reply := c.CallRPC(readRequest, CENTRALMANAGER, -1, c.CMIP)
-	if !reply.Ack {
-		logerror.Printf("Msg [%s] from Client %d not acknowledged by CM\n", READ_REQUEST, c.ID)
+		FromID:    c.ID,
+		FromIP:    c.IP,
+		RequestID: c.pending.newRequestID(c.ID),
 	}
 }
 
-func (c *Client) sendWriteRequest(pageNo string, content string) {
-	page, exists := c.PageStore[pageNo]
-	if exists {
-		if page.Access == READWRITE {
-			logsystem.Printf("Page %s exists in local storage with %s access\n", pageNo, page.Access)
-			logsystem.Printf("Writing new content in local page...\n")
-			page.Content = content
-			c.PageStore[pageNo] = page
-			return
-		} else {
-			logsystem.Printf("Page %s exists in local storage with %s access\n", pageNo, page.Access)
-			logsystem.Println("Page Fault...")
-		}
-	} else {
-		logsystem.Printf("Page %s does not exist in local storage\n", pageNo)
-		logsystem.Println("Page Fault...")
-	}
-
-	writeRequest := Message{
+func (c *Client) newWriteRequest(pageNo string, offset int, data []byte) Message {
+	return Message{
 		Type: WRITE_REQUEST,
 		Payload: Payload{
 			WriteRequest: WriteRequest{
 				PageNo:  pageNo,
-				Content: content,
+				Offset:  offset,
+				Content: data,
 			},
 		},
-		FromID: c.ID,
-		FromIP: c.IP,
+		FromID:    c.ID,
+		FromIP:    c.IP,
+		RequestID: c.pending.newRequestID(c.ID),
 	}
+}
 
-	reply := c.CallRPC(writeRequest, CENTRALMANAGER, -1, c.CMIP)
+// WRITE_REQUEST for a write fault, or UPGRADE_REQUEST if this client holds a READ copy that the CM
+// can upgrade in place. Must be called before beginFetch.
+func (c *Client) newWriteFault(pageNo string, offset int, data []byte) Message {
+	writeRequest := c.newWriteRequest(pageNo, offset, data)
+	if c.mode != CENTRAL && c.mode != FIXED {
+		return writeRequest
+	}
+	if page, exists := c.localPage(pageNo); exists && page.Access == READ {
+		writeRequest.Type = UPGRADE_REQUEST
+	}
+	return writeRequest
+}
+
+// Fire-and-forget READ_REQUEST, returns once the CM has acknowledged it.
+// In DYNAMIC mode faults are serialized per page, so this blocks like Read.
+func (c *Client) sendReadRequest(pageNo string) error {
+	if c.mode == DYNAMIC || c.consistency == RELEASE {
+		_, err := c.Read(pageNo)
+		return err
+	}
+	c.beginFetch(pageNo, FETCHING_READ)
+	readRequest := c.newReadRequest(pageNo)
+	reply := c.CallRPC(context.Background(), readRequest, CENTRALMANAGER, -1, c.managerIP(pageNo))
 	if !reply.Ack {
+		c.abortFetch(pageNo, FETCHING_READ)
 		logerror.Printf("Msg [%s] from Client %d not acknowledged by CM\n", READ_REQUEST, c.ID)
+		return fmt.Errorf("%s for page %s not acknowledged by CM", READ_REQUEST, pageNo)
 	}
+	return nil
+}
+
+// Fire-and-forget WRITE_REQUEST, returns once the CM has acknowledged it.
+// In DYNAMIC mode faults are serialized per page, so this blocks like Write.
+func (c *Client) sendWriteRequest(pageNo string, content []byte) error {
+	if c.mode == DYNAMIC || c.consistency == RELEASE {
+		return c.Write(pageNo, content)
+	}
+	if err := checkWrite(0, len(content)); err != nil {
+		return err
+	}
+	data := applyWrite(nil, 0, content)
+	if c.writeLocal(pageNo, 0, data) {
+		return nil
+	}
+
+	writeRequest := c.newWriteFault(pageNo, 0, data)
+	c.beginFetch(pageNo, FETCHING_WRITE)
+	reply := c.CallRPC(context.Background(), writeRequest, CENTRALMANAGER, -1, c.managerIP(pageNo))
+	if !reply.Ack {
+		c.abortFetch(pageNo, FETCHING_WRITE)
+		logerror.Printf("Msg [%s] from Client %d not acknowledged by CM\n", writeRequest.Type, c.ID)
+		return fmt.Errorf("%s for page %s not acknowledged by CM", writeRequest.Type, pageNo)
+	}
+	return nil
 }
 
 func (c *Client) handleChangeCM(msg Message) {
+	c.mu.Lock()
 	c.CMIP = msg.Payload.ChangeCM.NewCMIP
-	logsystem.Printf("Changed CMIP to %s\n", c.CMIP)
+	c.mu.Unlock()
+	logsystem.Printf("Changed CMIP to %s\n", msg.Payload.ChangeCM.NewCMIP)
+
+	// Requests in flight may have died with the old CM
+	c.pending.retryAll()
 }
 
-func (c *Client) seedPages() {
+// Writes pages P1 to P10 without waiting for the pages to arrive
+func (c *Client) SeedPages() {
 	for i := 1; i <= 10; i++ {
-		c.sendWriteRequest(fmt.Sprintf("P%d", i), fmt.Sprintf("Content by Client %d", c.ID))
+		c.sendWriteRequest(fmt.Sprintf("P%d", i), []byte(fmt.Sprintf("Content by Client %d", c.ID)))
 	}
 }
 
-func (c *Client) randomizeRWRequests() {
+// Sends n random reads and writes on pages P0 to P9, one per second, without waiting for the pages to
+// arrive unless the client records its history
+func (c *Client) RandomRequests(n int) {
 
-	for i := 0; i < NUM_REQS; i++ {
-		time.Sleep(1 * time.Second)
+	for i := 0; i < n; i++ {
+		c.clock.Sleep(1 * time.Second)
 		randomNumber := rand.Intn(2)
-		if randomNumber == 0 {
-			c.sendWriteRequest(fmt.Sprintf("P%d", rand.Intn(10)), fmt.Sprintf("Content by Client %d", c.ID))
-		} else {
-			c.sendReadRequest(fmt.Sprintf("P%d", rand.Intn(10)))
+		pageNo := fmt.Sprintf("P%d", rand.Intn(10))
+		switch {
+		case randomNumber == 0 && c.history != nil:
+			// Requests wait for their response to be recorded, and write values tell writes apart
+			c.Write(pageNo, []byte(fmt.Sprintf("Content %d by Client %d", i, c.ID)))
+		case randomNumber == 0:
+			c.sendWriteRequest(pageNo, []byte(fmt.Sprintf("Content by Client %d", c.ID)))
+		case c.history != nil:
+			c.Read(pageNo)
+		default:
+			c.sendReadRequest(pageNo)
 		}
 	}
 }
@@ -0,0 +1,55 @@
+package ivy
+
+import (
+	"testing"
+)
+
+// A write whose WRITE_CONFIRMATION reply is lost is resent, and completes when the CM answers that
+// it was already done. The client then holds a copy with this write, or a later one if the page
+// moved on to another client before the retry.
+func TestWriteRetriedAfterLostConfirmation(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		// Write made by Client 2 between the confirmation and the retry
+		between string
+		want    string
+	}{
+		{"page still held", "", "earlier"},
+		{"page taken since", "later", "later"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			dsm := newTestDSM(t, Config{}, 2)
+			c1, c2 := dsm.clients[0], dsm.clients[1]
+			if err := c2.Write("0", []byte("first")); err != nil {
+				t.Fatal(err)
+			}
+			lost := false
+			c1.transport = &lossyTransport{Transport: c1.transport, dropReply: func(msg Message) bool {
+				if msg.Type != WRITE_CONFIRMATION || lost {
+					return false
+				}
+				lost = true
+				if test.between != "" {
+					if err := c2.Write("0", []byte(test.between)); err != nil {
+						t.Error(err)
+					}
+				}
+				return true
+			}}
+
+			if err := c1.Write("0", []byte("earlier")); err != nil {
+				t.Fatal(err)
+			}
+			if !lost {
+				t.Fatal("no WRITE_CONFIRMATION sent")
+			}
+			page, exists := c1.localPage("0")
+			if !exists || historyValue(page.Content) != test.want {
+				t.Errorf("page held as %q with %s access, want %q", historyValue(page.Content), page.Access, test.want)
+			}
+			if page, err := c2.Read("0"); err != nil || historyValue(page.Content) != test.want {
+				t.Errorf("page read as %q, %v, want %q", historyValue(page.Content), err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package ivy
+
+import (
+	"context"
+	"reflect"
+	"time"
+)
+
+// Source of time of a node. Nodes run on the wall clock, except in a simulation, where every node
+// shares the simulated clock of the scheduler so that the timers of the protocol (retries, pulses,
+// leases, deadlines) fire at the same point of every replay.
+type clock interface {
+	Now() time.Time
+	// Receives the time once d has elapsed
+	After(d time.Duration) <-chan time.Time
+	Sleep(d time.Duration)
+	// Calls f in its own goroutine once d has elapsed, unless the timer is stopped first
+	AfterFunc(d time.Duration, f func()) clockTimer
+	NewTicker(d time.Duration) clockTicker
+	// Same as context.WithTimeout, with the deadline on this clock
+	WithTimeout(parent context.Context, d time.Duration) (context.Context, context.CancelFunc)
+	// Runs f in its own goroutine. The scheduler of a simulation only moves on once the goroutines
+	// started this way are all waiting on it.
+	Go(f func())
+	// Blocks until one of chans can be received from, as a select over them would, and returns its
+	// index and the value received, nil if the channel is closed. Nil channels are never ready.
+	Select(chans ...any) (int, any)
+}
+
+// Satisfied by *time.Timer
+type clockTimer interface {
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+type clockTicker interface {
+	// Receives the time at every tick, dropping ticks for a slow receiver like time.Ticker
+	Ticks() <-chan time.Time
+	Stop()
+}
+
+type wallClock struct{}
+
+func (wallClock) Now() time.Time {
+	return time.Now()
+}
+
+func (wallClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (wallClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+func (wallClock) AfterFunc(d time.Duration, f func()) clockTimer {
+	return time.AfterFunc(d, f)
+}
+
+func (wallClock) NewTicker(d time.Duration) clockTicker {
+	return wallTicker{time.NewTicker(d)}
+}
+
+func (wallClock) WithTimeout(parent context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(parent, d)
+}
+
+func (wallClock) Go(f func()) {
+	go f()
+}
+
+func (wallClock) Select(chans ...any) (int, any) {
+	return selectChans(chans, true)
+}
+
+type wallTicker struct {
+	*time.Ticker
+}
+
+func (t wallTicker) Ticks() <-chan time.Time {
+	return t.C
+}
+
+// Receives from ch, or fails with ctx.Err() if ctx is done first
+func receiveContext[T any](clk clock, ctx context.Context, ch <-chan T) (T, error) {
+	var value T
+	i, received := clk.Select(ch, ctx.Done())
+	if i == 1 {
+		return value, ctx.Err()
+	}
+	if received != nil {
+		value = received.(T)
+	}
+	return value, nil
+}
+
+// Receives from the first of chans that is ready. Without wait, returns -1 at once if none is.
+func selectChans(chans []any, wait bool) (int, any) {
+	cases := make([]reflect.SelectCase, len(chans), len(chans)+1)
+	for i, ch := range chans {
+		cases[i] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch)}
+	}
+	if !wait {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectDefault})
+	}
+	i, value, ok := reflect.Select(cases)
+	if i == len(chans) {
+		return -1, nil
+	}
+	if !ok {
+		return i, nil
+	}
+	return i, value.Interface()
+}
@@ -1,57 +1,310 @@
-package main
+package ivy
 
-import "time"
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
 
 type CentralManager struct {
 	IP        string
 	MetaData  map[string]PageInfo
 	IsPrimary bool
+	Requests  map[string]RequestRecord
+	// Home copies of the pages under release consistency
+	Homes map[string]HomePage
+
+	// Guards MetaData, IsPrimary, Requests and Homes. Never held across an RPC.
+	mu *sync.Mutex
+	// Serializes the read and write transactions on each page
+	pageLocks *pageLockTable
+	// Named locks of the lock service
+	locks    *lockTable
+	barriers *barrierTable
+	stats    *messageStats
+
+	mode     string
+	registry registry
+	// Set by Start, closed by Stop
+	listener io.Closer
+	// How the node sends and receives messages
+	transport Transport
+	done      chan struct{}
+	// Deadlines and retries of the messages the node sends
+	calls callPolicy
+	clock clock
+	// Set on a restarted primary, which takes the MetaData back from the backup when it starts
+	reclaim bool
 }
 
 type PageInfo struct {
 	Owner   ClientPointer
 	CopySet []ClientPointer
+	// WRITE_INVALIDATE (also when empty) or WRITE_UPDATE
+	Coherence string
+}
+
+func newCentralManager(cfg Config, ip string, isPrimary bool) *CentralManager {
+	return &CentralManager{
+		IP:        ip,
+		MetaData:  map[string]PageInfo{},
+		IsPrimary: isPrimary,
+		Requests:  map[string]RequestRecord{},
+		Homes:     map[string]HomePage{},
+		mu:        &sync.Mutex{},
+		pageLocks: newPageLockTable(cfg.clock),
+		locks:     newLockTable(cfg.clock),
+		barriers:  newBarrierTable(cfg.clock),
+		stats:     newMessageStats(),
+		mode:      cfg.Mode,
+		registry:  registry{dir: cfg.DataDir},
+		transport: cfg.Transport,
+		calls:     newCallPolicy(cfg),
+		clock:     cfg.clock,
+		done:      make(chan struct{}),
+	}
 }
 
 func (cm *CentralManager) HandleIncomingMessage(msg Message, reply *Reply) error {
 	logincoming.Printf("Message of Type [%s] received\n", msg.Type)
-	if cm.IsPrimary {
+	cm.stats.countReceived(msg.Type)
+	ctx, cancel := cm.calls.context(msg)
+	defer cancel()
+	cm.mu.Lock()
+	isPrimary := cm.IsPrimary
+	cm.mu.Unlock()
+
+	if isPrimary {
 		switch msg.Type {
 		case READ_REQUEST:
-			cm.handleReadRequest(msg)
-			reply.Ack = true
+			cm.handleRequest(ctx, msg, reply, msg.Payload.ReadRequest.PageNo, cm.handleReadRequest)
 		case READ_CONFIRMATION:
 			cm.handleReadConfirmation(msg)
 			reply.Ack = true
 		case WRITE_REQUEST:
-			cm.handleWriteRequest(msg)
-			reply.Ack = true
+			cm.handleRequest(ctx, msg, reply, msg.Payload.WriteRequest.PageNo, cm.handleWriteRequest)
+		case UPGRADE_REQUEST:
+			cm.handleRequest(ctx, msg, reply, msg.Payload.WriteRequest.PageNo, cm.handleUpgradeRequest)
 		case WRITE_CONFIRMATION:
 			cm.handleWriteConfirmation(msg)
 			reply.Ack = true
+		case SET_COHERENCE:
+			if err := cm.handleSetCoherence(ctx, msg); err != nil {
+				reply.Err = err.Error()
+			} else {
+				reply.Ack = true
+			}
+		case ALLOCATE_PAGE:
+			if err := cm.handleAllocatePage(ctx, msg); err != nil {
+				reply.Err = err.Error()
+			} else {
+				reply.Ack = true
+			}
+		case FREE_PAGE:
+			if err := cm.handleFreePage(ctx, msg); err != nil {
+				reply.Err = err.Error()
+			} else {
+				reply.Ack = true
+			}
+		case LOCK_ACQUIRE:
+			cm.handleLockAcquire(ctx, msg, reply)
+		case LOCK_RELEASE:
+			cm.handleLockRelease(msg, reply)
+		case LOCK_RENEW:
+			cm.handleLockRenew(msg, reply)
+		case TXN_LOCK:
+			if err := cm.handleTxnLock(ctx, msg); err != nil {
+				reply.Err = err.Error()
+			} else {
+				reply.Ack = true
+			}
+		case TXN_UNLOCK:
+			reply.Ack = cm.pageLocks.release(msg.Payload.TxnLock.PageNo, msg.RequestID)
+		case HOME_FETCH:
+			if err := cm.handleHomeFetch(msg, reply); err != nil {
+				reply.Err = err.Error()
+			} else {
+				reply.Ack = true
+			}
+		case HOME_DIFF:
+			cm.handleHomeDiff(msg, reply)
+			reply.Ack = true
+		case BARRIER_WAIT:
+			if err := cm.handleBarrierWait(ctx, msg); err != nil {
+				reply.Err = err.Error()
+			} else {
+				reply.Ack = true
+			}
 		case PULSE:
-			reply.Payload = cm.MetaData
+			reply.Payload, reply.Requests = cm.snapshot()
+			reply.Homes = cm.homesSnapshot()
 			reply.Ack = true
 		case IM_BACK:
+			cm.mu.Lock()
 			cm.IsPrimary = false
-			reply.Payload = cm.MetaData
+			cm.mu.Unlock()
+			reply.Payload, reply.Requests = cm.snapshot()
+			reply.Homes = cm.homesSnapshot()
 			reply.Ack = true
-			go cm.pulseCheck()
+			cm.clock.Go(cm.pulseCheck)
 		}
 	}
 
 	return nil
 }
 
+// Copy of the MetaData, safe to print while RPCs are being handled
+func (cm *CentralManager) Pages() map[string]PageInfo {
+	metaData, _ := cm.snapshot()
+	return metaData
+}
+
+// Copies MetaData and Requests so they can be sent to the other CM without holding cm.mu
+func (cm *CentralManager) snapshot() (map[string]PageInfo, map[string]RequestRecord) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	metaData := make(map[string]PageInfo, len(cm.MetaData))
+	for pageNo, pageInfo := range cm.MetaData {
+		pageInfo.CopySet = append([]ClientPointer{}, pageInfo.CopySet...)
+		metaData[pageNo] = pageInfo
+	}
+	requests := make(map[string]RequestRecord, len(cm.Requests))
+	for requestID, record := range cm.Requests {
+		requests[requestID] = record
+	}
+	return metaData, requests
+}
+
+// Runs handler for a READ_REQUEST/WRITE_REQUEST on pageNo unless the request is a retry of one the CM has already seen.
+//   - Already completed: reply with Done so the client stops retrying. Nothing is applied twice.
+//   - Still in progress: the original will complete on its own, just ack.
+//   - In progress for longer than REQUEST_TIMEOUT: assume it was lost and drive it again.
+//
+// The handler runs while holding the lock on pageNo. The lock stays held after a successful handler
+// and is only released by the matching READ_CONFIRMATION/WRITE_CONFIRMATION, so the whole transaction
+// on the page completes before the next request for it is processed.
+// If the requester stops waiting while the request is queued for the lock, ctx is done and the request
+// leaves the queue, so the retry starts over.
+func (cm *CentralManager) handleRequest(ctx context.Context, msg Message, reply *Reply, pageNo string, handler func(context.Context, Message) error) {
+	if msg.RequestID == "" {
+		// The page lock and confirmation are matched by request ID
+		msg.RequestID = fmt.Sprintf("cm-%d-%d", msg.FromID, cm.clock.Now().UnixNano())
+	}
+
+	cm.mu.Lock()
+	record, seen := cm.Requests[msg.RequestID]
+	if seen && record.Status == DONE {
+		cm.mu.Unlock()
+		logwarning.Printf("Request %s already completed, ignoring retry\n", msg.RequestID)
+		reply.Ack = true
+		reply.Done = true
+		return
+	}
+	if seen && cm.clock.Now().Sub(record.Started) < REQUEST_TIMEOUT {
+		cm.mu.Unlock()
+		logwarning.Printf("Request %s already in progress, ignoring retry\n", msg.RequestID)
+		reply.Ack = true
+		return
+	}
+	cm.pruneRequests()
+	cm.Requests[msg.RequestID] = RequestRecord{Status: IN_PROGRESS, Started: cm.clock.Now()}
+	cm.mu.Unlock()
+
+	// The requests of a transaction run under the page lock the transaction holds, which their
+	// confirmations do not release
+	holder := msg.RequestID
+	if msg.TxnID != "" {
+		holder = msg.TxnID
+	}
+	granted, err := cm.pageLocks.acquire(ctx, pageNo, holder)
+	if err != nil {
+		// The requester stopped waiting, its retry starts over
+		logwarning.Printf("Request %s abandoned: %v\n", msg.RequestID, err)
+		cm.mu.Lock()
+		if cm.Requests[msg.RequestID].Status == IN_PROGRESS {
+			delete(cm.Requests, msg.RequestID)
+		}
+		cm.mu.Unlock()
+		reply.Err = err.Error()
+		return
+	}
+	if !granted {
+		logwarning.Printf("Request %s already waiting for Page %s, ignoring retry\n", msg.RequestID, pageNo)
+		reply.Ack = true
+		return
+	}
+
+	// Time spent queueing for the page does not count towards REQUEST_TIMEOUT
+	cm.mu.Lock()
+	if cm.Requests[msg.RequestID].Status == IN_PROGRESS {
+		cm.Requests[msg.RequestID] = RequestRecord{Status: IN_PROGRESS, Started: cm.clock.Now()}
+	}
+	cm.mu.Unlock()
+
+	if err := handler(ctx, msg); err != nil {
+		cm.mu.Lock()
+		if cm.Requests[msg.RequestID].Status == IN_PROGRESS {
+			delete(cm.Requests, msg.RequestID)
+		}
+		cm.mu.Unlock()
+		cm.pageLocks.release(pageNo, msg.RequestID)
+		reply.Err = err.Error()
+		return
+	}
+	reply.Ack = true
+}
+
+// Marks a request as completed. Returns false if it had already been completed,
+// in which case the confirmation is a duplicate and must not be applied again.
+// Must be called with cm.mu held.
+func (cm *CentralManager) completeRequest(requestID string) bool {
+	if requestID == "" {
+		return true
+	}
+	if record, seen := cm.Requests[requestID]; seen && record.Status == DONE {
+		logwarning.Printf("Request %s already completed, ignoring duplicate confirmation\n", requestID)
+		return false
+	}
+	cm.Requests[requestID] = RequestRecord{Status: DONE, Started: cm.clock.Now()}
+	return true
+}
+
+// Forgets completed requests older than REQUEST_RETENTION. No client retries for that long.
+// Must be called with cm.mu held.
+func (cm *CentralManager) pruneRequests() {
+	for requestID, record := range cm.Requests {
+		if record.Status == DONE && cm.clock.Now().Sub(record.Started) > REQUEST_RETENTION {
+			delete(cm.Requests, requestID)
+		}
+	}
+}
+
+// Called when this CM takes over as primary. Requests it only knows as in progress
+// may have died with the old primary, so they are dropped and driven again when the client retries.
+// Must be called with cm.mu held.
+func (cm *CentralManager) dropInProgressRequests() {
+	for requestID, record := range cm.Requests {
+		if record.Status == IN_PROGRESS {
+			delete(cm.Requests, requestID)
+		}
+	}
+}
+
 // Sends ReadForward to PageOwner
-func (cm *CentralManager) handleReadRequest(msg Message) {
+// Returns an error if the request was denied, so the requester does not wait for a PAGE_SEND.
+func (cm *CentralManager) handleReadRequest(ctx context.Context, msg Message) error {
 	// Check if page exists
 	pageNo := msg.Payload.ReadRequest.PageNo
+	cm.mu.Lock()
 	page, exists := cm.MetaData[pageNo]
+	cm.mu.Unlock()
 	if !exists {
 		logerror.Printf("Page %s does not exist in CM\n", pageNo)
 		logerror.Printf("ReadRequest by Client %d denied\n", msg.FromID)
-		return
+		return errNoSuchPage
 	}
 	pageOwner := page.Owner
 
@@ -64,41 +317,65 @@ func (cm *CentralManager) handleReadRequest(msg Message) {
 				ReadRequesterIP: msg.FromIP,
 				PageNo:          msg.Payload.ReadRequest.PageNo,
 			}},
+		RequestID: msg.RequestID,
 	}
 
 	// Send page owner ReadForward
 	logoutgoing.Printf("CM sending Msg %s to Client %d\n", READ_FORWARD, pageOwner.ID)
-	reply := cm.CallRPC(readForward, CLIENT, pageOwner.ID, pageOwner.IP)
+	reply := cm.CallRPC(ctx, readForward, CLIENT, pageOwner.ID, pageOwner.IP)
 	if !reply.Ack {
 		logerror.Printf("Msg [%s] from CM not acknowledged\n", readForward.Type)
-		return
+		return fmt.Errorf("%s for page %s not acknowledged by owner Client %d", READ_FORWARD, pageNo, pageOwner.ID)
 	}
+	return nil
 }
 
-// Updates CopySet for Page
+// Updates CopySet for Page and ends the read transaction on it
 func (cm *CentralManager) handleReadConfirmation(msg Message) {
 	requestedPage := msg.Payload.ReadConfirmation.PageNumber
 	readRequesterID := msg.Payload.ReadConfirmation.ReadRequesterID
 	readRequesterIP := msg.Payload.ReadConfirmation.ReadRequesterIP
 
+	defer cm.pageLocks.release(requestedPage, msg.RequestID)
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if !cm.completeRequest(msg.RequestID) {
+		return
+	}
+	if cm.mode == IMPROVED {
+		// The owner added the requester to its own copy set
+		return
+	}
+
 	// TODO assert senderID == requestedPage.Owner
 	// senderID := msg.Payload.ReadConfirmation.SenderID
 
 	// Add requester to copyset. Update PageInfo
-	pageOwner := cm.MetaData[requestedPage].Owner
+	pageInfo := cm.MetaData[requestedPage]
 	requesterPointer := ClientPointer{ID: readRequesterID, IP: readRequesterIP}
-	updatedCopySet := append(cm.MetaData[requestedPage].CopySet, requesterPointer)
-	cm.MetaData[requestedPage] = PageInfo{Owner: pageOwner, CopySet: updatedCopySet}
+	updatedCopySet := pageInfo.CopySet
+	for _, clientPointer := range updatedCopySet {
+		if clientPointer.ID == readRequesterID {
+			logsystem.Printf("Client %d already in CopySet of Page %s\n", readRequesterID, requestedPage)
+			return
+		}
+	}
+	updatedCopySet = append(updatedCopySet, requesterPointer)
+	pageInfo.CopySet = updatedCopySet
+	cm.MetaData[requestedPage] = pageInfo
 	logsystem.Println("CM updated CopySet after receiving ReadConfirmation: ", updatedCopySet)
 }
 
-// 1. Sends InvalidateCopy to clients in CopySet
-// 2. Returns if any client did not ACK InvalidateCopy
+// 1. Sends InvalidateCopy to all clients in CopySet concurrently
+// 2. Returns if any client did not ACK InvalidateCopy within FAN_OUT_TIMEOUT
 // 3. If all InvalidateCopy ACKs received, send WriteForward to PageOwner
-func (cm *CentralManager) handleWriteRequest(msg Message) {
+// Returns an error if the write could not be forwarded.
+// In IMPROVED mode the CopySet is always empty and the owner invalidates the copies itself.
+func (cm *CentralManager) handleWriteRequest(ctx context.Context, msg Message) error {
 
 	// Extract WRITEREQUEST msg info
 	targetPageNo := msg.Payload.WriteRequest.PageNo
+	offset := msg.Payload.WriteRequest.Offset
 	content := msg.Payload.WriteRequest.Content
 	writeRequesterID := msg.FromID
 	writeRequesterIP := msg.FromIP
@@ -107,6 +384,7 @@ func (cm *CentralManager) handleWriteRequest(msg Message) {
 		IP: writeRequesterIP,
 	}
 
+	cm.mu.Lock()
 	pageInfo, exists := cm.MetaData[targetPageNo]
 	// If page doesnt exist (ie first time writing this page), add it to MetaData and page back to writeRequester.
 	if !exists {
@@ -117,6 +395,7 @@ func (cm *CentralManager) handleWriteRequest(msg Message) {
 			CopySet: []ClientPointer{},
 		}
 		logsystem.Printf("PageInfo stored:\n%v\n", cm.MetaData[targetPageNo])
+		cm.mu.Unlock()
 
 		pageSend := Message{
 			Type: PAGE_SEND,
@@ -125,19 +404,23 @@ func (cm *CentralManager) handleWriteRequest(msg Message) {
 					Purpose: WRITE,
 					Page: Page{
 						Number:  targetPageNo,
-						Content: content,
+						Content: applyWrite(nil, offset, content),
 					},
 				},
 			},
+			RequestID: msg.RequestID,
 		}
-		reply := cm.CallRPC(pageSend, CLIENT, writeRequesterID, writeRequesterIP)
+		reply := cm.CallRPC(ctx, pageSend, CLIENT, writeRequesterID, writeRequesterIP)
 		if !reply.Ack {
 			logerror.Printf("Msg [%s] from CM not acknowledged by Client %d\n", PAGE_SEND, writeRequesterID)
+			return fmt.Errorf("%s for page %s not acknowledged by Client %d", PAGE_SEND, targetPageNo, writeRequesterID)
 		}
-		return
+		return nil
 	}
+	cm.mu.Unlock()
 
-	for _, clientPointer := range pageInfo.CopySet {
+	// Under write-update the owner pushes the write to the copy set instead
+	if pageInfo.Coherence != WRITE_UPDATE {
 		invalidateCopy := Message{
 			Type: INVALIDATE_COPY,
 			Payload: Payload{
@@ -146,13 +429,14 @@ func (cm *CentralManager) handleWriteRequest(msg Message) {
 					PageNumber:       targetPageNo,
 				},
 			},
+			RequestID: msg.RequestID,
 		}
-
-		reply := cm.CallRPC(invalidateCopy, CLIENT, clientPointer.ID, clientPointer.IP)
-		if !reply.Ack {
-			logerror.Printf("Msg [%s] from CM not acknowledged by Client %d\n", invalidateCopy.Type, clientPointer.ID)
+		err := fanOut(ctx, cm.clock, INVALIDATE_COPY, targetPageNo, pageInfo.CopySet, func(ctx context.Context, clientPointer ClientPointer) Reply {
+			return cm.CallRPC(ctx, invalidateCopy, CLIENT, clientPointer.ID, clientPointer.IP)
+		})
+		if err != nil {
 			logerror.Println("Cannot forward Write Request")
-			return
+			return err
 		}
 	}
 
@@ -165,25 +449,37 @@ func (cm *CentralManager) handleWriteRequest(msg Message) {
 				WriteRequesterID: writeRequesterID,
 				WriteRequesterIP: writeRequesterIP,
 				PageNumber:       targetPageNo,
+				Offset:           offset,
 				Content:          content,
+				Coherence:        pageInfo.Coherence,
+				CopySet:          pageInfo.CopySet,
 			},
 		},
+		RequestID: msg.RequestID,
 	}
-	updatedPageInfo := cm.MetaData[targetPageNo]
-	ownerID := updatedPageInfo.Owner.ID
-	ownerIP := updatedPageInfo.Owner.IP
-	reply := cm.CallRPC(writeForward, CLIENT, ownerID, ownerIP)
+	ownerID := pageInfo.Owner.ID
+	ownerIP := pageInfo.Owner.IP
+	reply := cm.CallRPC(ctx, writeForward, CLIENT, ownerID, ownerIP)
 	if !reply.Ack {
 		logerror.Printf("Msg [%s] from CM not acknowledged by Client %d\n", writeForward.Type, ownerID)
-		return
+		return fmt.Errorf("%s for page %s not acknowledged by owner Client %d", WRITE_FORWARD, targetPageNo, ownerID)
 	}
+	return nil
 }
 
+// Ends the write transaction on the page
 func (cm *CentralManager) handleWriteConfirmation(msg Message) {
 	// change owner of page to sender of writeConfirmation.
 	// make sure copyset is null until other reads come in
 
 	newlyWrittenPageNo := msg.Payload.WriteConfirmation.PageNumber
+	defer cm.pageLocks.release(newlyWrittenPageNo, msg.RequestID)
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if !cm.completeRequest(msg.RequestID) {
+		return
+	}
+
 	newlyWrittenPage, exists := cm.MetaData[newlyWrittenPageNo]
 	if !exists {
 		logerror.Printf("CM does not have PageInfo of Page %s", newlyWrittenPageNo)
@@ -193,15 +489,22 @@ func (cm *CentralManager) handleWriteConfirmation(msg Message) {
 	writerID := msg.Payload.WriteConfirmation.WriterID
 	writerIP := msg.Payload.WriteConfirmation.WriterIP
 
-	// Update Owner of page and clear CopySet
+	// Update Owner of page and clear CopySet.
+	// Under write-update the other holders, including the previous owner, kept their copies.
+	if newlyWrittenPage.Coherence == WRITE_UPDATE && cm.mode != IMPROVED {
+		newlyWrittenPage.CopySet = otherHolders(newlyWrittenPage.CopySet, newlyWrittenPage.Owner, writerID)
+	} else {
+		newlyWrittenPage.CopySet = []ClientPointer{}
+	}
 	newlyWrittenPage.Owner = ClientPointer{ID: writerID, IP: writerIP}
-	newlyWrittenPage.CopySet = []ClientPointer{}
 	cm.MetaData[newlyWrittenPageNo] = newlyWrittenPage
 }
 
 func (cm *CentralManager) pulseCheck() {
 	for {
-		time.Sleep(2 * time.Second)
+		if i, _ := cm.clock.Select(cm.done, cm.clock.After(2*time.Second)); i == 0 {
+			return
+		}
 
 		pulse := Message{
 			Type: PULSE,
@@ -211,21 +514,24 @@ func (cm *CentralManager) pulseCheck() {
 				},
 			},
 		}
-		primaryCMIP, err := getPrimaryCMIP()
+		primaryCMIP, err := cm.registry.primaryCMIP()
 		if err != nil {
 			logerror.Println("Backup CM could not get primary CMIP")
 			return
 		}
-		reply := cm.CallRPC(pulse, CENTRALMANAGER, -1, primaryCMIP)
+		reply := cm.CallRPC(context.Background(), pulse, CENTRALMANAGER, -1, primaryCMIP)
 		if !reply.Ack {
 			logerror.Println("PULSE not returned by Primary CM")
 			logerror.Println("Primary CM is likely dead!!")
 			logsystem.Println("It's time...")
 			logsystem.Println("Backup CM undergoing transformation...")
+			cm.mu.Lock()
 			cm.IsPrimary = true
+			cm.dropInProgressRequests()
+			cm.mu.Unlock()
 			logsystem.Println("Backup CM is now Primary CM!!!")
 
-			clientArr := getAllClients()
+			clientArr := cm.registry.clients()
 			for _, client := range clientArr {
 				changeCM := Message{
 					Type: CHANGE_CM,
@@ -235,7 +541,7 @@ func (cm *CentralManager) pulseCheck() {
 						},
 					},
 				}
-				reply := cm.CallRPC(changeCM, CLIENT, client.ID, client.IP)
+				reply := cm.CallRPC(context.Background(), changeCM, CLIENT, client.ID, client.IP)
 				if !reply.Ack {
 					logerror.Printf("Msg [%s] from CM not acknowledged by Client %d\n", CHANGE_CM, client.ID)
 					return
@@ -243,7 +549,21 @@ func (cm *CentralManager) pulseCheck() {
 			}
 			return
 		} else {
+			cm.mu.Lock()
 			cm.MetaData = reply.Payload
+			cm.Requests = reply.Requests
+			cm.Homes = reply.Homes
+			// gob does not transmit empty maps
+			if cm.MetaData == nil {
+				cm.MetaData = map[string]PageInfo{}
+			}
+			if cm.Requests == nil {
+				cm.Requests = map[string]RequestRecord{}
+			}
+			if cm.Homes == nil {
+				cm.Homes = map[string]HomePage{}
+			}
+			cm.mu.Unlock()
 		}
 	}
 }
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/s4nat/ivy"
+)
+
+// Measures the round trip latency of messages to a CM running in this process, with a new connection
+// per message and with pooled connections
+func bench(args []string) bool {
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	n := flags.Int("n", 2000, "messages sent with each transport")
+	concurrency := flags.Int("concurrency", 1, "number of senders sending at the same time")
+	flags.Parse(args)
+	if *concurrency < 1 {
+		*concurrency = 1
+	}
+	// The logs of this command go to the same output, so results are printed with fmt
+	ivy.SetLogOutput(io.Discard)
+
+	dataDir, err := os.MkdirTemp("", "ivy-bench")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error creating data directory: ", err)
+		return false
+	}
+	defer os.RemoveAll(dataDir)
+	port, err := ivy.GetFreePort()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error assigning port number: ", err)
+		return false
+	}
+	addr := "127.0.0.1:" + strconv.Itoa(port)
+	cm, err := ivy.NewCentralManager(ivy.Config{Addr: addr, DataDir: dataDir})
+	if err == nil {
+		err = cm.Start()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not start CM: ", err)
+		return false
+	}
+	defer cm.Stop()
+
+	// A PULSE to the primary CM only reads its MetaData
+	target := ivy.Target{NodeType: ivy.CENTRALMANAGER, ID: -1, Addr: addr}
+	msg := ivy.Message{Type: ivy.PULSE, Payload: ivy.Payload{Pulse: ivy.Pulse{FromIP: "bench"}}}
+	runs := []struct {
+		name      string
+		transport *ivy.RPCTransport
+	}{
+		{"new connection per message", &ivy.RPCTransport{MaxIdleConns: -1}},
+		{"pooled connections", &ivy.RPCTransport{}},
+	}
+	fmt.Printf("Sending %d PULSEs to a CM at %s with %d senders\n", *n, addr, *concurrency)
+	for _, run := range runs {
+		latencies, failed, elapsed := sendAll(run.transport, target, msg, *n, *concurrency)
+		run.transport.CloseIdleConnections()
+		if len(latencies) == 0 {
+			fmt.Printf("%-28s all %d messages failed\n", run.name, failed)
+			return false
+		}
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		var total time.Duration
+		for _, latency := range latencies {
+			total += latency
+		}
+		fmt.Printf("%-28s mean %-10v p50 %-10v p99 %-10v %8.0f msgs/s  %d connections dialed, %d failed\n",
+			run.name,
+			(total / time.Duration(len(latencies))).Round(time.Microsecond),
+			latencies[len(latencies)/2].Round(time.Microsecond),
+			latencies[len(latencies)*99/100].Round(time.Microsecond),
+			float64(len(latencies))/elapsed.Seconds(),
+			run.transport.Dialed(), failed)
+	}
+	return true
+}
+
+// Sends n messages split between the senders, and returns the latencies of the ones that got an Ack
+func sendAll(transport ivy.Transport, target ivy.Target, msg ivy.Message, n int, concurrency int) ([]time.Duration, int, time.Duration) {
+	var mu sync.Mutex
+	latencies := []time.Duration{}
+	failed := 0
+	var wg sync.WaitGroup
+	start := time.Now()
+	for sender := 0; sender < concurrency; sender++ {
+		count := n / concurrency
+		if sender < n%concurrency {
+			count++
+		}
+		wg.Add(1)
+		go func(count int) {
+			defer wg.Done()
+			for i := 0; i < count; i++ {
+				sent := time.Now()
+				reply, err := transport.Send(context.Background(), target, msg)
+				latency := time.Since(sent)
+				mu.Lock()
+				if err != nil || !reply.Ack {
+					failed++
+				} else {
+					latencies = append(latencies, latency)
+				}
+				mu.Unlock()
+			}
+		}(count)
+	}
+	wg.Wait()
+	return latencies, failed, time.Since(start)
+}
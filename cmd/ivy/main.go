@@ -0,0 +1,527 @@
+// Interactive CM or Client node
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/s4nat/ivy"
+)
+
+// Number of random reads and writes sent by 'x'
+const NUM_REQS = 10
+
+// Color coded logs
+var logsystem = color.New(color.FgCyan).Add(color.BgBlack)
+var logerror = color.New(color.FgHiRed).Add(color.BgBlack)
+
+// Locks taken from the prompt, by name
+var locks = map[string]*ivy.RWMutex{}
+
+func main() {
+	var cfg ivy.Config
+	flag.StringVar(&cfg.Mode, "mode", ivy.CENTRAL, "manager algorithm: 'central', 'improved', 'fixed' or 'dynamic'")
+	flag.IntVar(&cfg.Nodes, "nodes", 0, "number of clients sharing the page space in 'fixed' mode")
+	flag.StringVar(&cfg.DataDir, "data", "data", "directory holding cm.json and client.json")
+	flag.StringVar(&cfg.Consistency, "consistency", ivy.SEQUENTIAL, "consistency model of the clients: 'sequential' or 'release'")
+	flag.StringVar(&cfg.HistoryDir, "history", "", "directory a client records the history of its reads and writes to")
+	flag.IntVar(&cfg.Retry.Retries, "retries", 0, "times a node sends again a message it could not deliver")
+	transport := flag.String("transport", "rpc", "how the nodes exchange messages: 'rpc' or 'grpc' (needs a build with -tags grpc)")
+	flag.Parse()
+
+	// ivy check-history <file or directory>...
+	if flag.Arg(0) == "check-history" {
+		if !checkHistory(flag.Args()[1:]) {
+			os.Exit(1)
+		}
+		return
+	}
+	// ivy bench [-n messages] [-concurrency senders]
+	if flag.Arg(0) == "bench" {
+		if !bench(flag.Args()[1:]) {
+			os.Exit(1)
+		}
+		return
+	}
+	// ivy simulate [-seed n] ...
+	if flag.Arg(0) == "simulate" {
+		if !simulate(cfg.Mode, flag.Args()[1:]) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	switch *transport {
+	case "rpc":
+	case "grpc":
+		var err error
+		if cfg.Transport, err = grpcTransport(); err != nil {
+			logerror.Println(err)
+			os.Exit(1)
+		}
+	default:
+		logerror.Println("Unknown transport: ", *transport)
+		os.Exit(1)
+	}
+
+	ipAddress := ivy.GetOutboundIP().String()
+	port, err := ivy.GetFreePort()
+	if err != nil {
+		logerror.Println("Error assigning port number: ", err)
+		return
+	}
+	cfg.Addr = ipAddress + ":" + strconv.Itoa(port)
+	logsystem.Println("Node running on IP Address: ", cfg.Addr)
+
+	// Specify type of Node: {Client, Central Manager}
+	reader := bufio.NewReader(os.Stdin)
+	logsystem.Println("Enter Node type ('1': CM, '2': Client, 'restartCM', 'restartBackup')")
+	nodeType, err := reader.ReadString('\n')
+	if err != nil {
+		logerror.Println("Error reading input: ", err)
+		return
+	}
+	nodeType = strings.TrimRight(nodeType, "\n")
+
+	switch nodeType {
+	case "1":
+		cm, err := ivy.NewCentralManager(cfg)
+		runCM(cm, err, reader)
+	case "2":
+		c, err := ivy.NewClient(cfg)
+		runClient(c, err, reader)
+	case "restartCM":
+		cm, err := ivy.RestartCentralManager(cfg, true)
+		runCM(cm, err, reader)
+	case "restartBackup":
+		cm, err := ivy.RestartCentralManager(cfg, false)
+		runCM(cm, err, reader)
+	default:
+		logerror.Println("Invalid input bro...")
+	}
+}
+
+func runCM(cm *ivy.CentralManager, err error, reader *bufio.Reader) {
+	if err == nil {
+		err = cm.Start()
+	}
+	if err != nil {
+		logerror.Println("Could not start CM: ", err)
+		return
+	}
+
+	for {
+		// Print a prompt
+		logsystem.Print("> ")
+
+		// read input from user
+		input, err := reader.ReadString('\n')
+		if err != nil {
+			logerror.Fprintln(os.Stderr, "Error reading input:", err)
+		}
+
+		// Parse the input to handle commands
+		handleCMInput(cm, strings.TrimSpace(input))
+	}
+}
+
+func runClient(c *ivy.Client, err error, reader *bufio.Reader) {
+	if err == nil {
+		err = c.Start()
+	}
+	if err != nil {
+		logerror.Println("Could not start Client: ", err)
+		return
+	}
+
+	for {
+		// Print a prompt
+		logsystem.Print("> ")
+
+		// read input from user
+		input, err := reader.ReadString('\n')
+		if err != nil {
+			logerror.Fprintln(os.Stderr, "Error reading input:", err)
+		}
+
+		// Parse the input to handle commands
+		handleClientInput(c, strings.TrimSpace(input))
+	}
+}
+
+func handleCMInput(cm *ivy.CentralManager, input string) {
+	parts := strings.Fields(input)
+	if len(parts) == 0 {
+		return
+	}
+
+	command := parts[0]
+	// parameters := parts[1:]
+
+	switch command {
+	case "print":
+		logsystem.Println("Printing MetaData...")
+		logsystem.Println(cm.Pages())
+	case "stats":
+		logsystem.Println("Printing message counts...")
+		logsystem.Println(cm.Stats())
+	default:
+		logsystem.Println("Invalid input brother...")
+	}
+}
+
+func handleClientInput(c *ivy.Client, input string) {
+	parts := strings.Fields(input)
+	if len(parts) == 0 {
+		return
+	}
+
+	command := parts[0]
+	parameters := parts[1:]
+
+	switch command {
+	case "readPage":
+		if len(parameters) != 1 {
+			logerror.Println("Usage: readPage <pageNo>")
+			return
+		}
+		pageNo := parameters[0]
+		page, err := c.Read(pageNo)
+		if err != nil {
+			logerror.Printf("Read of page %s failed: %v\n", pageNo, err)
+			return
+		}
+		logsystem.Printf("Page %s [%s]: %s\n", page.Number, page.Access, bytes.TrimRight(page.Content, "\x00"))
+
+	case "writePage":
+		if len(parameters) != 2 {
+			logerror.Println("Usage: writePage <pageNo> <content>")
+			return
+		}
+		pageNo := parameters[0]
+		content := parameters[1]
+		if err := c.Write(pageNo, []byte(content)); err != nil {
+			logerror.Printf("Write of page %s failed: %v\n", pageNo, err)
+			return
+		}
+		logsystem.Printf("Page %s written: %s\n", pageNo, content)
+
+	case "readAt":
+		if len(parameters) != 2 {
+			logerror.Println("Usage: readAt <addr> <n>")
+			return
+		}
+		addr, errAddr := strconv.Atoi(parameters[0])
+		n, errN := strconv.Atoi(parameters[1])
+		if errAddr != nil || errN != nil {
+			logerror.Println("Usage: readAt <addr> <n>")
+			return
+		}
+		buf, err := c.ReadAt(addr, n)
+		if err != nil {
+			logerror.Printf("Read of %d bytes at address %d failed: %v\n", n, addr, err)
+			return
+		}
+		logsystem.Printf("Address %d: %q\n", addr, buf)
+
+	case "writeAt":
+		if len(parameters) != 2 {
+			logerror.Println("Usage: writeAt <addr> <content>")
+			return
+		}
+		addr, err := strconv.Atoi(parameters[0])
+		if err != nil {
+			logerror.Println("Usage: writeAt <addr> <content>")
+			return
+		}
+		if err := c.WriteAt(addr, []byte(parameters[1])); err != nil {
+			logerror.Printf("Write at address %d failed: %v\n", addr, err)
+			return
+		}
+		logsystem.Printf("Address %d written: %s\n", addr, parameters[1])
+
+	case "allocPage":
+		if len(parameters) != 1 {
+			logerror.Println("Usage: allocPage <pageNo>")
+			return
+		}
+		pageNo := parameters[0]
+		if err := c.AllocatePage(pageNo); err != nil {
+			logerror.Printf("Allocation of page %s failed: %v\n", pageNo, err)
+			return
+		}
+		logsystem.Printf("Page %s allocated\n", pageNo)
+
+	case "freePage":
+		if len(parameters) != 1 {
+			logerror.Println("Usage: freePage <pageNo>")
+			return
+		}
+		pageNo := parameters[0]
+		if err := c.FreePage(pageNo); err != nil {
+			logerror.Printf("Freeing page %s failed: %v\n", pageNo, err)
+			return
+		}
+		logsystem.Printf("Page %s freed\n", pageNo)
+
+	case "txn":
+		if len(parameters) == 0 || len(parameters)%2 != 0 {
+			logerror.Println("Usage: txn <pageNo> <content> [<pageNo> <content>...]")
+			return
+		}
+		txn := c.NewTransaction()
+		for i := 0; i < len(parameters); i += 2 {
+			if err := txn.Write(parameters[i], []byte(parameters[i+1])); err != nil {
+				logerror.Printf("Write of page %s failed: %v\n", parameters[i], err)
+				return
+			}
+		}
+		if err := txn.Commit(); err != nil {
+			logerror.Printf("Transaction failed: %v\n", err)
+			return
+		}
+		logsystem.Println("Transaction committed")
+
+	case "cas":
+		if len(parameters) != 3 {
+			logerror.Println("Usage: cas <pageNo> <old> <new>")
+			return
+		}
+		pageNo := parameters[0]
+		swapped, err := c.CompareAndSwap(pageNo, []byte(parameters[1]), []byte(parameters[2]))
+		if err != nil {
+			logerror.Printf("Compare-and-swap on page %s failed: %v\n", pageNo, err)
+			return
+		}
+		logsystem.Printf("Page %s swapped: %v\n", pageNo, swapped)
+
+	case "faa":
+		if len(parameters) != 2 {
+			logerror.Println("Usage: faa <pageNo> <delta>")
+			return
+		}
+		pageNo := parameters[0]
+		delta, err := strconv.ParseInt(parameters[1], 10, 64)
+		if err != nil {
+			logerror.Println("Usage: faa <pageNo> <delta>")
+			return
+		}
+		previous, err := c.FetchAndAdd(pageNo, delta)
+		if err != nil {
+			logerror.Printf("Fetch-and-add on page %s failed: %v\n", pageNo, err)
+			return
+		}
+		logsystem.Printf("Page %s: %d -> %d\n", pageNo, previous, previous+delta)
+
+	case "lock", "unlock", "rlock", "runlock":
+		if len(parameters) != 1 {
+			logerror.Printf("Usage: %s <name>\n", command)
+			return
+		}
+		name := parameters[0]
+		lock, exists := locks[name]
+		if !exists {
+			var err error
+			if lock, err = c.NewRWMutex(name); err != nil {
+				logerror.Printf("Cannot use lock %s: %v\n", name, err)
+				return
+			}
+			locks[name] = lock
+		}
+		var err error
+		switch command {
+		case "lock":
+			err = lock.LockContext(context.Background())
+		case "unlock":
+			err = lock.UnlockContext(context.Background())
+		case "rlock":
+			err = lock.RLockContext(context.Background())
+		case "runlock":
+			err = lock.RUnlockContext(context.Background())
+		}
+		if err != nil {
+			logerror.Printf("%s %s failed: %v\n", command, name, err)
+			return
+		}
+		logsystem.Printf("Done %s %s\n", command, name)
+
+	case "coherence":
+		if len(parameters) != 2 {
+			logerror.Printf("Usage: coherence <pageNo> <%s|%s>\n", ivy.WRITE_INVALIDATE, ivy.WRITE_UPDATE)
+			return
+		}
+		pageNo, policy := parameters[0], parameters[1]
+		if err := c.SetCoherence(pageNo, policy); err != nil {
+			logerror.Printf("Setting coherence of page %s failed: %v\n", pageNo, err)
+			return
+		}
+		logsystem.Printf("Page %s now uses write-%s\n", pageNo, policy)
+
+	case "print":
+		logsystem.Println("Printing PageStore...")
+		logsystem.Println(c.Pages())
+		if c.Mode() == ivy.FIXED {
+			logsystem.Println("Printing MetaData of managed pages...")
+			logsystem.Println(c.ManagedPages())
+		}
+		if c.Mode() == ivy.DYNAMIC {
+			probOwners, copySets := c.Ownership()
+			logsystem.Println("Printing probable owners and copy sets...")
+			logsystem.Println(probOwners)
+			logsystem.Println(copySets)
+		}
+		if c.Mode() == ivy.IMPROVED {
+			_, copySets := c.Ownership()
+			logsystem.Println("Printing copy sets of owned pages...")
+			logsystem.Println(copySets)
+		}
+
+	case "stats":
+		logsystem.Println("Printing message counts...")
+		logsystem.Println(c.Stats())
+
+	case "seed":
+		c.SeedPages()
+
+	case "barrier":
+		if len(parameters) != 2 {
+			logerror.Println("Usage: barrier <name> <n>")
+			return
+		}
+		n, err := strconv.Atoi(parameters[1])
+		if err != nil {
+			logerror.Println("Usage: barrier <name> <n>")
+			return
+		}
+		if err := c.Barrier(parameters[0], n); err != nil {
+			logerror.Printf("Barrier %s failed: %v\n", parameters[0], err)
+			return
+		}
+		logsystem.Printf("Passed barrier %s\n", parameters[0])
+
+	case "x":
+		if len(parameters) == 1 {
+			// Start together with the other n clients
+			n, err := strconv.Atoi(parameters[0])
+			if err != nil {
+				logerror.Println("Usage: x [n]")
+				return
+			}
+			if err := c.Barrier("x", n); err != nil {
+				logerror.Printf("Barrier x failed: %v\n", err)
+				return
+			}
+		} else {
+			// Give some time to key in 'x' on all N terminals
+			time.Sleep(30 * time.Second)
+		}
+		start := time.Now().UnixMilli()
+		c.RandomRequests(NUM_REQS)
+		end := time.Now().UnixMilli()
+		timeTaken := end - start
+		logsystem.Printf("TIME TAKEN: %v\n", timeTaken)
+	default:
+		logsystem.Println("Invalid input brother...")
+	}
+
+}
+
+// Checks the histories recorded by the clients and prints a counterexample if they are not consistent
+func checkHistory(paths []string) bool {
+	if len(paths) == 0 {
+		logerror.Println("Usage: ivy check-history <file or directory>...")
+		return false
+	}
+	ops, err := ivy.LoadHistory(paths...)
+	if err != nil {
+		logerror.Println("Error loading history: ", err)
+		return false
+	}
+	clients := map[int]bool{}
+	pages := map[string]bool{}
+	for _, op := range ops {
+		clients[op.Client] = true
+		pages[op.PageNo] = true
+	}
+	logsystem.Printf("Checking %d operations by %d clients on %d pages...\n", len(ops), len(clients), len(pages))
+
+	result, err := ivy.CheckHistory(ops)
+	if err != nil {
+		logerror.Println("Could not check history: ", err)
+		return false
+	}
+	logsystem.Printf("Sequentially consistent: %v\n", result.SequentiallyConsistent)
+	logsystem.Printf("Linearizable: %v\n", result.Linearizable)
+	if result.Linearizable {
+		return true
+	}
+
+	logerror.Printf("Counterexample (%d operations):\n", len(result.Counterexample))
+	start := result.Counterexample[0].Invoke
+	for _, op := range result.Counterexample {
+		logerror.Println("  " + op.Format(start))
+	}
+	return false
+}
+
+// Runs simulations of the DSM inside this process, and prints the seeds whose history is not consistent
+func simulate(mode string, args []string) bool {
+	flags := flag.NewFlagSet("simulate", flag.ExitOnError)
+	simCfg := ivy.SimConfig{Mode: mode}
+	flags.Int64Var(&simCfg.Seed, "seed", 1, "seed of the first simulation")
+	seeds := flags.Int("seeds", 1, "number of simulations to run, with consecutive seeds")
+	flags.IntVar(&simCfg.Clients, "clients", 3, "number of clients")
+	flags.BoolVar(&simCfg.Backup, "backup", true, "run a backup CM in 'central' and 'improved' modes")
+	flags.IntVar(&simCfg.Ops, "ops", 20, "reads and writes made by each client")
+	flags.IntVar(&simCfg.Pages, "pages", 3, "number of pages the clients use")
+	flags.Float64Var(&simCfg.DropRate, "drop", 0, "probability that a message is lost")
+	flags.Float64Var(&simCfg.CrashRate, "crash", 0, "probability that a CM crashes after each message")
+	flags.Float64Var(&simCfg.ClientCrashRate, "crash-client", 0, "probability that a client crashes after each message, in 'central' and 'improved' modes")
+	flags.IntVar(&simCfg.RestartAfter, "restart", 50, "messages delivered before a crashed node restarts")
+	trace := flags.Bool("trace", false, "print the messages delivered and the faults injected")
+	logs := flags.Bool("logs", false, "print the logs of the nodes")
+	flags.Parse(args)
+	if !*logs {
+		// The logs of this command go to the same output, so results are printed with fmt
+		ivy.SetLogOutput(io.Discard)
+	}
+
+	ok := true
+	first := simCfg.Seed
+	for seed := first; seed < first+int64(*seeds); seed++ {
+		simCfg.Seed = seed
+		result, err := ivy.Simulate(simCfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Seed %d: %v\n", seed, err)
+			ok = false
+			continue
+		}
+		if *trace {
+			for _, line := range result.Trace {
+				fmt.Println(line)
+			}
+		}
+		check := result.Check
+		fmt.Printf("Seed %d: %d operations, %d trace entries, sequentially consistent: %v, linearizable: %v\n",
+			seed, len(result.History), len(result.Trace), check.SequentiallyConsistent, check.Linearizable)
+		if check.Linearizable {
+			continue
+		}
+		ok = false
+		fmt.Printf("Counterexample (%d operations):\n", len(check.Counterexample))
+		start := check.Counterexample[0].Invoke
+		for _, op := range check.Counterexample {
+			fmt.Println("  " + op.Format(start))
+		}
+	}
+	return ok
+}
@@ -0,0 +1,9 @@
+//go:build grpc
+
+package main
+
+import "github.com/s4nat/ivy"
+
+func grpcTransport() (ivy.Transport, error) {
+	return &ivy.GRPCTransport{}, nil
+}
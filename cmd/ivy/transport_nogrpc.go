@@ -0,0 +1,13 @@
+//go:build !grpc
+
+package main
+
+import (
+	"errors"
+
+	"github.com/s4nat/ivy"
+)
+
+func grpcTransport() (ivy.Transport, error) {
+	return nil, errors.New("this ivy was built without gRPC support, rebuild it with -tags grpc")
+}
@@ -0,0 +1,152 @@
+package ivy
+
+import (
+	"context"
+	"fmt"
+)
+
+// Coherence policies, selected per page with SET_COHERENCE.
+// Under write-invalidate (the default) a write invalidates every other copy of the page.
+// Under write-update the owner pushes the write to every other holder with UPDATE_COPY instead, so
+// readers keep valid copies. While copies exist, the writer only gets a READ copy, so that each of
+// its writes goes through the manager and reaches the other holders.
+const (
+	WRITE_INVALIDATE = "invalidate"
+	WRITE_UPDATE     = "update"
+)
+
+// Sets the coherence policy of a page once the transaction in progress on it, if any, has ended
+func (cm *CentralManager) handleSetCoherence(ctx context.Context, msg Message) error {
+	pageNo := msg.Payload.SetCoherence.PageNo
+	policy := msg.Payload.SetCoherence.Policy
+	if policy != WRITE_INVALIDATE && policy != WRITE_UPDATE {
+		return fmt.Errorf("unknown coherence policy %s", policy)
+	}
+
+	holder := "coherence-" + msg.RequestID
+	if _, err := cm.pageLocks.acquire(ctx, pageNo, holder); err != nil {
+		return err
+	}
+	defer cm.pageLocks.release(pageNo, holder)
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	pageInfo, exists := cm.MetaData[pageNo]
+	if !exists {
+		return fmt.Errorf("page %s does not exist, write it first", pageNo)
+	}
+	pageInfo.Coherence = policy
+	cm.MetaData[pageNo] = pageInfo
+	logsystem.Printf("Page %s now uses write-%s\n", pageNo, policy)
+	return nil
+}
+
+// WRITE_FORWARD under write-update. The owner applies the write to its copy, which stays readable,
+// pushes the write to the other holders, and sends the page to the writer. In IMPROVED mode the
+// owner keeps the copy set and hands it over with the page, otherwise the CM sends it along.
+func (c *Client) handleUpdateWriteForward(ctx context.Context, msg Message) bool {
+	writeRequesterID := msg.Payload.WriteForward.WriteRequesterID
+	writeRequesterIP := msg.Payload.WriteForward.WriteRequesterIP
+	pageNo := msg.Payload.WriteForward.PageNumber
+	offset := msg.Payload.WriteForward.Offset
+	content := msg.Payload.WriteForward.Content
+
+	page, exists := c.sharePage(pageNo, offset, content)
+	if !exists {
+		logerror.Printf("Page %s requested (to write) by Client %d does not exist in Client %d's PageStore\n", pageNo, writeRequesterID, c.ID)
+		return false
+	}
+
+	copySet := msg.Payload.WriteForward.CopySet
+	if c.mode == IMPROVED {
+		c.mu.Lock()
+		copySet = c.copySets[pageNo]
+		delete(c.copySets, pageNo)
+		c.mu.Unlock()
+	}
+
+	holders := otherHolders(copySet, ClientPointer{ID: c.ID, IP: c.IP}, writeRequesterID)
+	others := []ClientPointer{}
+	for _, holder := range holders {
+		if holder.ID != c.ID {
+			others = append(others, holder)
+		}
+	}
+	updateCopy := Message{
+		Type: UPDATE_COPY,
+		Payload: Payload{
+			UpdateCopy: UpdateCopy{
+				PageNumber: pageNo,
+				Offset:     offset,
+				Content:    content,
+			},
+		},
+		FromID:    c.ID,
+		FromIP:    c.IP,
+		RequestID: msg.RequestID,
+	}
+	err := fanOut(ctx, c.clock, UPDATE_COPY, pageNo, others, func(ctx context.Context, holder ClientPointer) Reply {
+		return c.CallRPC(ctx, updateCopy, CLIENT, holder.ID, holder.IP)
+	})
+	if err != nil {
+		c.restoreCopySet(pageNo, copySet)
+		return false
+	}
+
+	pageSend := Message{
+		Type: PAGE_SEND,
+		Payload: Payload{
+			PageSend: PageSend{
+				Purpose: WRITE,
+				Page:    page,
+				CopySet: holders,
+				Shared:  len(holders) > 0,
+			},
+		},
+		FromID:    c.ID,
+		FromIP:    c.IP,
+		RequestID: msg.RequestID,
+	}
+	logoutgoing.Printf("Client %d sending Msg %s to Client %d\n", c.ID, PAGE_SEND, writeRequesterID)
+	reply := c.CallRPC(ctx, pageSend, CLIENT, writeRequesterID, writeRequesterIP)
+	if !reply.Ack {
+		logerror.Printf("Msg [%s] from Client %d not acknowledged by Client %d\n", PAGE_SEND, c.ID, writeRequesterID)
+		c.restoreCopySet(pageNo, copySet)
+		return false
+	}
+	return true
+}
+
+// Puts back the copy set of a page this client is still the owner of, in IMPROVED mode
+func (c *Client) restoreCopySet(pageNo string, copySet []ClientPointer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.mode == IMPROVED && len(copySet) > 0 {
+		c.copySets[pageNo] = copySet
+	}
+}
+
+// UPDATE_COPY carries a write to a page this client holds a copy of
+func (c *Client) handleUpdateCopy(msg Message) {
+	c.updatePage(msg.Payload.UpdateCopy.PageNumber, msg.Payload.UpdateCopy.Offset, msg.Payload.UpdateCopy.Content)
+}
+
+// Selects the coherence policy of pageNo at its manager: WRITE_INVALIDATE or WRITE_UPDATE.
+// The page must have been written once.
+func (c *Client) SetCoherence(pageNo string, policy string) error {
+	if c.mode == DYNAMIC {
+		return fmt.Errorf("coherence policies are kept by the manager, there is none in %s mode", DYNAMIC)
+	}
+	if err := c.requireSequential("setting coherence policies"); err != nil {
+		return err
+	}
+	return c.callManager(pageNo, Message{
+		Type: SET_COHERENCE,
+		Payload: Payload{
+			SetCoherence: SetCoherence{
+				PageNo: pageNo,
+				Policy: policy,
+			},
+		},
+	})
+}
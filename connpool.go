@@ -0,0 +1,142 @@
+package ivy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"sync"
+	"time"
+)
+
+// Pool of net/rpc connections to the other nodes. A connection carries one message at a time, and is
+// closed rather than reused once a call on it fails.
+
+const (
+	// Idle connections kept to each peer, unless RPCTransport.MaxIdleConns is set
+	DEFAULT_MAX_IDLE_CONNS = 4
+	// How long an idle connection is kept, unless RPCTransport.IdleTimeout is set
+	DEFAULT_IDLE_TIMEOUT = 90 * time.Second
+)
+
+type connPool struct {
+	mu sync.Mutex
+	// Idle connections by peer address, the most recently used last
+	idle map[string][]*pooledConn
+	// Number of connections dialed, for benchmarks
+	dialed int
+}
+
+type pooledConn struct {
+	client *rpc.Client
+	conn   *trackedConn
+	// When the connection went idle
+	since time.Time
+}
+
+// TCP connection of a pooledConn, which remembers whether a write failed
+type trackedConn struct {
+	net.Conn
+	mu       sync.Mutex
+	writeErr error
+}
+
+func (c *trackedConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if err != nil {
+		c.mu.Lock()
+		c.writeErr = err
+		c.mu.Unlock()
+	}
+	return n, err
+}
+
+func (c *trackedConn) writeFailed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.writeErr != nil
+}
+
+// An idle connection to addr that has not timed out, or nil if there is none
+func (p *connPool) get(addr string, timeout time.Duration) *pooledConn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	conns := p.idle[addr]
+	for len(conns) > 0 {
+		conn := conns[len(conns)-1]
+		conns = conns[:len(conns)-1]
+		if time.Since(conn.since) < timeout && !peerClosed(conn.conn.Conn) {
+			p.idle[addr] = conns
+			return conn
+		}
+		conn.client.Close()
+	}
+	delete(p.idle, addr)
+	return nil
+}
+
+// Returns a healthy connection to the pool of addr, closing it if the pool is full
+func (p *connPool) put(addr string, conn *pooledConn, maxIdle int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.idle == nil {
+		p.idle = make(map[string][]*pooledConn)
+	}
+	conn.since = time.Now()
+	conns := append(p.idle[addr], conn)
+	if len(conns) > maxIdle {
+		// The least recently used connections go first
+		for _, extra := range conns[:len(conns)-maxIdle] {
+			extra.client.Close()
+		}
+		conns = append([]*pooledConn{}, conns[len(conns)-maxIdle:]...)
+	}
+	p.idle[addr] = conns
+}
+
+func (p *connPool) dial(ctx context.Context, addr string) (*pooledConn, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	p.dialed++
+	p.mu.Unlock()
+	tracked := &trackedConn{Conn: conn}
+	return &pooledConn{client: rpc.NewClient(tracked), conn: tracked}, nil
+}
+
+func (p *connPool) closeIdle() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for addr, conns := range p.idle {
+		for _, conn := range conns {
+			conn.client.Close()
+		}
+		delete(p.idle, addr)
+	}
+}
+
+// Makes the call on conn. The connection is closed if the call fails or ctx is done first.
+func (conn *pooledConn) call(ctx context.Context, serviceMethod string, msg Message) (Reply, error) {
+	var reply Reply
+	call := conn.client.Go(serviceMethod, msg, &reply, make(chan *rpc.Call, 1))
+	select {
+	case <-call.Done:
+		if call.Error != nil {
+			conn.client.Close()
+			if errors.Is(call.Error, rpc.ErrShutdown) || conn.conn.writeFailed() {
+				// The message did not go out in full, so the peer cannot have handled it
+				return Reply{}, fmt.Errorf("%w: %w", ErrNotDelivered, call.Error)
+			}
+			return Reply{}, call.Error
+		}
+		return reply, nil
+	case <-ctx.Done():
+		// Closing the connection abandons the call
+		conn.client.Close()
+		return Reply{}, ctx.Err()
+	}
+}
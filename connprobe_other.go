@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package ivy
+
+import "net"
+
+// The socket cannot be probed here. A connection the peer closed fails its next call instead.
+func peerClosed(conn net.Conn) bool {
+	return false
+}
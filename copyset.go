@@ -0,0 +1,72 @@
+package ivy
+
+import "context"
+
+// Copy sets kept by the owner of a page, in DYNAMIC and IMPROVED modes
+
+// Sends INVALIDATE_COPY for pageNo concurrently to every client in copySet other than this one
+func (c *Client) invalidateCopies(ctx context.Context, pageNo string, copySet []ClientPointer, requestID string) error {
+	others := []ClientPointer{}
+	for _, clientPointer := range copySet {
+		if clientPointer.ID != c.ID {
+			others = append(others, clientPointer)
+		}
+	}
+	invalidateCopy := Message{
+		Type: INVALIDATE_COPY,
+		Payload: Payload{
+			InvalidateCopy: InvalidateCopy{
+				WriteRequesterID: c.ID,
+				PageNumber:       pageNo,
+			},
+		},
+		FromID:    c.ID,
+		FromIP:    c.IP,
+		RequestID: requestID,
+	}
+	return fanOut(ctx, c.clock, INVALIDATE_COPY, pageNo, others, func(ctx context.Context, clientPointer ClientPointer) Reply {
+		return c.CallRPC(ctx, invalidateCopy, CLIENT, clientPointer.ID, clientPointer.IP)
+	})
+}
+
+// Clients other than clientID holding a copy of a page: the copy set and the owner
+func otherHolders(copySet []ClientPointer, owner ClientPointer, clientID int) []ClientPointer {
+	holders := []ClientPointer{}
+	for _, clientPointer := range addToCopySet(append([]ClientPointer{}, copySet...), owner) {
+		if clientPointer.ID != clientID {
+			holders = append(holders, clientPointer)
+		}
+	}
+	return holders
+}
+
+func addToCopySet(copySet []ClientPointer, client ClientPointer) []ClientPointer {
+	for _, clientPointer := range copySet {
+		if clientPointer.ID == client.ID {
+			return copySet
+		}
+	}
+	return append(copySet, client)
+}
+
+// Invalidates the copies of a page this client owns, except the write requester's, and clears its copy set.
+// The copy set is kept if an invalidation fails, so the write can be retried.
+func (c *Client) invalidateOwnCopies(ctx context.Context, pageNo string, writeRequesterID int, requestID string) error {
+	c.mu.Lock()
+	copySet := make([]ClientPointer, 0, len(c.copySets[pageNo]))
+	for _, clientPointer := range c.copySets[pageNo] {
+		// The write requester is fetching the page and ignores the invalidation anyway
+		if clientPointer.ID != writeRequesterID {
+			copySet = append(copySet, clientPointer)
+		}
+	}
+	c.mu.Unlock()
+
+	if err := c.invalidateCopies(ctx, pageNo, copySet, requestID); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	delete(c.copySets, pageNo)
+	c.mu.Unlock()
+	return nil
+}
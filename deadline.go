@@ -0,0 +1,98 @@
+package ivy
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Deadlines and retries of the messages a node sends. Every message is sent under a ctx, which is
+// bounded by the deadline of its message type, and the time left to that deadline travels with the
+// message in Message.Timeout. The receiving node handles the message under a ctx that ends at the same
+// time, and passes it on to the messages and page lock waits the handler makes, so once the sender has
+// stopped waiting, the whole chain gives up and the CM releases its hold on the page.
+//
+// A message that could not be delivered, e.g. while its target restarts, is sent again according to
+// the RetryPolicy. A message that was delivered is never sent again by this layer, as its handler may
+// already have run: lost replies are covered by the clients resending their requests, which the CM
+// deduplicates.
+
+const (
+	// Wait before the first retry of an undelivered message, unless RetryPolicy.Backoff is set
+	DEFAULT_RETRY_BACKOFF = 50 * time.Millisecond
+	// Longest wait between two retries, unless RetryPolicy.MaxBackoff is set
+	DEFAULT_MAX_RETRY_BACKOFF = 2 * time.Second
+)
+
+// Returned by a Transport, wrapped, when a message never reached the handler of its target, so
+// sending it again cannot apply it twice
+var ErrNotDelivered = errors.New("message not delivered")
+
+// Deadline for the reply to each message type, unless Config.Timeouts sets it. Requests to a manager
+// get REQUEST_TIMEOUT, and the messages answered by a single node, or by the chain of nodes handing
+// over a page, get FAN_OUT_TIMEOUT.
+// 0 waits as long as the sender's ctx allows: LOCK_ACQUIRE and BARRIER_WAIT are answered once the lock
+// is free or the barrier is full, and TXN_LOCK carries its own timeout. A retry of one of them rejoins
+// the original wait, as the CM identifies them by RequestID.
+func defaultTimeout(msgType string) time.Duration {
+	switch msgType {
+	case LOCK_ACQUIRE, BARRIER_WAIT, TXN_LOCK:
+		return 0
+	case READ_REQUEST, WRITE_REQUEST, UPGRADE_REQUEST, ALLOCATE_PAGE, FREE_PAGE, SET_COHERENCE:
+		// Queue for the page at its manager, then wait for the page to be handed over
+		return REQUEST_TIMEOUT
+	default:
+		return FAN_OUT_TIMEOUT
+	}
+}
+
+// How a node sends again the messages it could not deliver
+type RetryPolicy struct {
+	// Times an undelivered message is sent again. 0, the default, gives up after the first attempt.
+	Retries int
+	// Wait before the first retry, doubled for each further one. Defaults to DEFAULT_RETRY_BACKOFF.
+	Backoff time.Duration
+	// Longest wait between two retries. Defaults to DEFAULT_MAX_RETRY_BACKOFF.
+	MaxBackoff time.Duration
+}
+
+// Deadlines and retry policy of a node, on its clock
+type callPolicy struct {
+	timeouts map[string]time.Duration
+	retry    RetryPolicy
+	clock    clock
+}
+
+func newCallPolicy(cfg Config) callPolicy {
+	retry := cfg.Retry
+	if retry.Backoff <= 0 {
+		retry.Backoff = DEFAULT_RETRY_BACKOFF
+	}
+	if retry.MaxBackoff <= 0 {
+		retry.MaxBackoff = DEFAULT_MAX_RETRY_BACKOFF
+	}
+	return callPolicy{timeouts: cfg.Timeouts, retry: retry, clock: cfg.clock}
+}
+
+func (p callPolicy) timeout(msgType string) time.Duration {
+	if timeout, set := p.timeouts[msgType]; set {
+		return timeout
+	}
+	return defaultTimeout(msgType)
+}
+
+// Wait before the retry following one that waited backoff
+func (p callPolicy) nextBackoff(backoff time.Duration) time.Duration {
+	if backoff*2 > p.retry.MaxBackoff {
+		return p.retry.MaxBackoff
+	}
+	return backoff * 2
+}
+
+// Context for handling msg, done once its sender stops waiting for the reply
+func (p callPolicy) context(msg Message) (context.Context, context.CancelFunc) {
+	if msg.Timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return p.clock.WithTimeout(context.Background(), msg.Timeout)
+}
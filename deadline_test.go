@@ -0,0 +1,114 @@
+package ivy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Transport that fails the first sends with errs, then acknowledges, and records what it was sent
+type stubTransport struct {
+	clock *simClock
+	errs  []error
+
+	mu       sync.Mutex
+	sentAt   []time.Duration
+	timeouts []time.Duration
+}
+
+func (t *stubTransport) Send(ctx context.Context, target Target, msg Message) (Reply, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	attempt := len(t.sentAt)
+	t.sentAt = append(t.sentAt, t.clock.Now().Sub(simEpoch))
+	t.timeouts = append(t.timeouts, msg.Timeout)
+	if attempt < len(t.errs) {
+		return Reply{}, t.errs[attempt]
+	}
+	return Reply{Ack: true}, nil
+}
+
+func (t *stubTransport) Listen(addr string, handlers map[string]Handler) (io.Closer, error) {
+	return nil, errors.New("stub transport does not listen")
+}
+
+// Sends msg through transport on its clock, which the scheduler of a test network advances
+func sendOnSimClock(ctx context.Context, transport *stubTransport, cfg Config, msg Message) Reply {
+	cfg.clock = transport.clock
+	return sendMessage(ctx, transport, newMessageStats(), newCallPolicy(cfg), msg, Target{NodeType: CLIENT})
+}
+
+func TestSendMessageRetriesWithBackoff(t *testing.T) {
+	transport := &stubTransport{clock: newTestNetwork(t).scheduler.clock, errs: []error{ErrNotDelivered, ErrNotDelivered, ErrNotDelivered}}
+	cfg := Config{
+		Retry:    RetryPolicy{Retries: 3, Backoff: 10 * time.Millisecond, MaxBackoff: 25 * time.Millisecond},
+		Timeouts: map[string]time.Duration{PULSE: 0},
+	}
+	if reply := sendOnSimClock(context.Background(), transport, cfg, Message{Type: PULSE}); !reply.Ack {
+		t.Fatal("message not delivered on its last retry")
+	}
+	// The backoff doubles up to MaxBackoff
+	want := []time.Duration{0, 10 * time.Millisecond, 30 * time.Millisecond, 55 * time.Millisecond}
+	if !reflect.DeepEqual(transport.sentAt, want) {
+		t.Errorf("sent at %v, want %v", transport.sentAt, want)
+	}
+}
+
+func TestSendMessageGivesUp(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		errs     []error
+		retry    RetryPolicy
+		attempts int
+	}{
+		{"out of retries", []error{ErrNotDelivered, ErrNotDelivered, ErrNotDelivered}, RetryPolicy{Retries: 1}, 2},
+		// The handler may have run, so it is not sent again
+		{"delivered", []error{errors.New("reply lost")}, RetryPolicy{Retries: 3}, 1},
+		// The deadline of the message comes before the retry
+		{"deadline", []error{ErrNotDelivered}, RetryPolicy{Retries: 3, Backoff: 10 * time.Second}, 1},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			transport := &stubTransport{clock: newTestNetwork(t).scheduler.clock, errs: test.errs}
+			cfg := Config{Retry: test.retry, Timeouts: map[string]time.Duration{PULSE: time.Second}}
+			if reply := sendOnSimClock(context.Background(), transport, cfg, Message{Type: PULSE}); reply.Ack {
+				t.Fatal("message acknowledged")
+			}
+			if len(transport.sentAt) != test.attempts {
+				t.Errorf("sent %d times, want %d", len(transport.sentAt), test.attempts)
+			}
+		})
+	}
+}
+
+// The time left to the deadline travels with each attempt, and the handler's ctx ends with it
+func TestSendMessagePropagatesDeadline(t *testing.T) {
+	clock := newTestNetwork(t).scheduler.clock
+	transport := &stubTransport{clock: clock, errs: []error{ErrNotDelivered}}
+	cfg := Config{
+		Retry:    RetryPolicy{Retries: 1, Backoff: 100 * time.Millisecond},
+		Timeouts: map[string]time.Duration{PULSE: time.Second},
+	}
+	sendOnSimClock(context.Background(), transport, cfg, Message{Type: PULSE})
+	if want := []time.Duration{time.Second, 900 * time.Millisecond}; !reflect.DeepEqual(transport.timeouts, want) {
+		t.Errorf("sent with timeouts %v, want %v", transport.timeouts, want)
+	}
+
+	// The caller's deadline comes first
+	transport = &stubTransport{clock: clock}
+	ctx, cancel := clock.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	sendOnSimClock(ctx, transport, cfg, Message{Type: PULSE})
+	if want := []time.Duration{300 * time.Millisecond}; !reflect.DeepEqual(transport.timeouts, want) {
+		t.Errorf("sent with timeouts %v, want %v", transport.timeouts, want)
+	}
+
+	handlerCtx, cancelHandler := newCallPolicy(Config{clock: clock}).context(Message{Timeout: transport.timeouts[0]})
+	defer cancelHandler()
+	if deadline, ok := handlerCtx.Deadline(); !ok || !deadline.Equal(clock.Now().Add(300*time.Millisecond)) {
+		t.Errorf("handler deadline %v, want in 300ms", deadline)
+	}
+}
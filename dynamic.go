@@ -0,0 +1,334 @@
+package ivy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// DYNAMIC mode: Li & Hudak's dynamic distributed manager. There is no CM.
+// Every client keeps a probable owner for each page. READ_REQUEST/WRITE_REQUEST are sent to the
+// probable owner and forwarded along the probable owners until they reach the true owner, which
+// answers the requester directly with a PAGE_SEND. The owner keeps the copy set of its page, and on
+// a write both ownership and the copy set travel with the page to the requester, which then
+// invalidates the copies.
+//
+// Client DEFAULT_OWNER_ID initially owns every page. A page nobody has written yet is empty.
+//
+// Each client serializes the faults and requests for a page with its own pageLockTable. A client
+// waiting for a page holds the lock until the page has arrived. Requests forwarded to it in the
+// meantime are acknowledged and queued, and served or forwarded once the lock is free, so the
+// clients forwarding them are not kept waiting on its fault.
+//
+// A lost PAGE_SEND leaves the owner in place while the clients that forwarded the WRITE_REQUEST
+// already point at the requester, so the probable owners can form a cycle. A request that comes
+// back to its requester is rejected rather than forwarded around the cycle.
+
+const DEFAULT_OWNER_ID = 1
+
+// Must be called with c.mu held
+func (c *Client) probOwnerLocked(pageNo string) ClientPointer {
+	if owner, known := c.probOwners[pageNo]; known {
+		return owner
+	}
+	if c.ID == DEFAULT_OWNER_ID {
+		return ClientPointer{ID: c.ID, IP: c.IP}
+	}
+	// IP is resolved by probOwnerIP
+	return ClientPointer{ID: DEFAULT_OWNER_ID}
+}
+
+func (c *Client) probOwnerIP(owner ClientPointer) string {
+	if owner.IP != "" {
+		return owner.IP
+	}
+	ip, err := c.peerIP(owner.ID)
+	if err != nil {
+		logerror.Printf("Cannot reach probable owner Client %d: %v\n", owner.ID, err)
+		return NIL
+	}
+	return ip
+}
+
+func (c *Client) isOwner(pageNo string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.probOwnerLocked(pageNo).ID == c.ID
+}
+
+// The owner always holds a copy. A page nobody has written yet starts out empty at its owner.
+// Must be called with c.mu held.
+func (c *Client) ownedPageLocked(pageNo string) Page {
+	page, exists := c.PageStore[pageNo]
+	if !exists {
+		page = Page{Number: pageNo, Content: make([]byte, PAGE_SIZE), Access: READWRITE}
+		c.PageStore[pageNo] = page
+	}
+	return page
+}
+
+// Read fault in DYNAMIC mode
+func (c *Client) dynamicRead(ctx context.Context, pageNo string) (Page, error) {
+	readRequest := c.newReadRequest(pageNo)
+	if _, err := c.pageLocks.acquire(ctx, pageNo, readRequest.RequestID); err != nil {
+		return Page{}, err
+	}
+	defer c.pageLocks.release(pageNo, readRequest.RequestID)
+
+	// The page may have arrived while waiting for the lock
+	if page, exists := c.localPage(pageNo); exists {
+		return page, nil
+	}
+	if c.isOwner(pageNo) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return c.ownedPageLocked(pageNo), nil
+	}
+
+	c.beginFetch(pageNo, FETCHING_READ)
+	page, err := c.awaitRequest(ctx, readRequest)
+	if err != nil {
+		c.abortFetch(pageNo, FETCHING_READ)
+	}
+	return page, err
+}
+
+// Write fault in DYNAMIC mode
+func (c *Client) dynamicWrite(ctx context.Context, pageNo string, offset int, data []byte) error {
+	writeRequest := c.newWriteRequest(pageNo, offset, data)
+	if _, err := c.pageLocks.acquire(ctx, pageNo, writeRequest.RequestID); err != nil {
+		return err
+	}
+	defer c.pageLocks.release(pageNo, writeRequest.RequestID)
+
+	if c.isOwner(pageNo) {
+		// Owner with a READ copy: invalidate the readers, no page needs to move
+		c.mu.Lock()
+		page := c.ownedPageLocked(pageNo)
+		copySet := c.copySets[pageNo]
+		delete(c.copySets, pageNo)
+		c.mu.Unlock()
+
+		if err := c.invalidateCopies(ctx, pageNo, copySet, writeRequest.RequestID); err != nil {
+			// Some readers may still hold a copy, so the write is not applied and the copy set is kept
+			// for the next write to invalidate
+			c.mu.Lock()
+			c.copySets[pageNo] = copySet
+			c.mu.Unlock()
+			return err
+		}
+		c.installPage(Page{Number: pageNo, Content: applyWrite(page.Content, offset, data)}, READWRITE)
+		return nil
+	}
+
+	c.beginFetch(pageNo, FETCHING_WRITE)
+	_, err := c.awaitRequest(ctx, writeRequest)
+	if err != nil {
+		c.abortFetch(pageNo, FETCHING_WRITE)
+	}
+	return err
+}
+
+// Serves a READ_REQUEST/WRITE_REQUEST if this client owns the page, otherwise forwards it to the probable owner.
+// If the page is locked, the request is acknowledged and handled once the lock is free.
+func (c *Client) handleDynamicRequest(ctx context.Context, msg Message) error {
+	pageNo := requestPageNo(msg)
+	if msg.FromID == c.ID {
+		return fmt.Errorf("%s %s for page %s forwarded back to Client %d, the probable owners form a cycle", msg.Type, msg.RequestID, pageNo, c.ID)
+	}
+	holder := "serve-" + msg.RequestID
+	if !c.pageLocks.tryAcquire(pageNo, holder) {
+		logsystem.Printf("Client %d queueing Msg %s %s from Client %d until Page %s is unlocked\n", c.ID, msg.Type, msg.RequestID, msg.FromID, pageNo)
+		c.clock.Go(func() { c.handleQueuedDynamicRequest(msg, holder) })
+		return nil
+	}
+	return c.serveOrForward(ctx, msg, holder)
+}
+
+// Waits for the lock on the page, which the caller already acknowledged, then serves or forwards the request
+func (c *Client) handleQueuedDynamicRequest(msg Message, holder string) {
+	ctx, cancel := c.calls.context(msg)
+	defer cancel()
+	pageNo := requestPageNo(msg)
+	if _, err := c.pageLocks.acquire(ctx, pageNo, holder); err != nil {
+		logerror.Printf("Dropping Msg %s %s from Client %d: %v\n", msg.Type, msg.RequestID, msg.FromID, err)
+		return
+	}
+	if err := c.serveOrForward(ctx, msg, holder); err != nil {
+		logerror.Printf("Msg %s %s from Client %d failed: %v\n", msg.Type, msg.RequestID, msg.FromID, err)
+	}
+}
+
+// Must be called with the lock on the page held by holder, and releases it
+func (c *Client) serveOrForward(ctx context.Context, msg Message, holder string) error {
+	pageNo := requestPageNo(msg)
+	c.mu.Lock()
+	owner := c.probOwnerLocked(pageNo)
+	if owner.ID != c.ID {
+		c.mu.Unlock()
+		c.pageLocks.release(pageNo, holder)
+
+		// Forward the request as is, FromID/FromIP still name the requester
+		logoutgoing.Printf("Client %d forwarding Msg %s from Client %d to probable owner Client %d\n", c.ID, msg.Type, msg.FromID, owner.ID)
+		reply := c.CallRPC(ctx, msg, CLIENT, owner.ID, c.probOwnerIP(owner))
+		if !reply.Ack {
+			if reply.Err != "" {
+				return errors.New(reply.Err)
+			}
+			return fmt.Errorf("%s for page %s not acknowledged by Client %d", msg.Type, pageNo, owner.ID)
+		}
+		if msg.Type == WRITE_REQUEST {
+			// The requester is about to become the owner, unless a later request moved the hint on
+			c.mu.Lock()
+			if c.probOwnerLocked(pageNo) == owner {
+				c.probOwners[pageNo] = ClientPointer{ID: msg.FromID, IP: msg.FromIP}
+			}
+			c.mu.Unlock()
+		}
+		return nil
+	}
+	c.mu.Unlock()
+
+	defer c.pageLocks.release(pageNo, holder)
+	if msg.Type == READ_REQUEST {
+		return c.serveDynamicRead(ctx, msg)
+	}
+	return c.serveDynamicWrite(ctx, msg)
+}
+
+// The owner keeps a READ copy, adds the requester to its copy set and sends it the page
+func (c *Client) serveDynamicRead(ctx context.Context, msg Message) error {
+	pageNo := msg.Payload.ReadRequest.PageNo
+	requester := ClientPointer{ID: msg.FromID, IP: msg.FromIP}
+
+	c.mu.Lock()
+	c.ownedPageLocked(pageNo)
+	c.mu.Unlock()
+	page, _ := c.downgradePage(pageNo)
+
+	pageSend := Message{
+		Type: PAGE_SEND,
+		Payload: Payload{
+			PageSend: PageSend{
+				Purpose: READ,
+				Page:    page,
+			},
+		},
+		FromID:    c.ID,
+		FromIP:    c.IP,
+		RequestID: msg.RequestID,
+	}
+	reply := c.CallRPC(ctx, pageSend, CLIENT, requester.ID, requester.IP)
+	if !reply.Ack {
+		logerror.Printf("Msg [%s] from Client %d not acknowledged by Client %d\n", PAGE_SEND, c.ID, requester.ID)
+		return fmt.Errorf("%s for page %s not acknowledged by Client %d", PAGE_SEND, pageNo, requester.ID)
+	}
+
+	c.mu.Lock()
+	c.copySets[pageNo] = addToCopySet(c.copySets[pageNo], requester)
+	c.mu.Unlock()
+	return nil
+}
+
+// The owner writes the content, gives up the page and sends it, together with ownership and the copy set, to the requester
+func (c *Client) serveDynamicWrite(ctx context.Context, msg Message) error {
+	pageNo := msg.Payload.WriteRequest.PageNo
+	requester := ClientPointer{ID: msg.FromID, IP: msg.FromIP}
+
+	c.mu.Lock()
+	previousPage := c.ownedPageLocked(pageNo)
+	copySet := c.copySets[pageNo]
+	c.mu.Unlock()
+	page, _ := c.surrenderPage(pageNo, msg.Payload.WriteRequest.Offset, msg.Payload.WriteRequest.Content)
+
+	pageSend := Message{
+		Type: PAGE_SEND,
+		Payload: Payload{
+			PageSend: PageSend{
+				Purpose: WRITE,
+				Page:    page,
+				CopySet: copySet,
+			},
+		},
+		FromID:    c.ID,
+		FromIP:    c.IP,
+		RequestID: msg.RequestID,
+	}
+	reply := c.CallRPC(ctx, pageSend, CLIENT, requester.ID, requester.IP)
+	if !reply.Ack {
+		logerror.Printf("Msg [%s] from Client %d not acknowledged by Client %d\n", PAGE_SEND, c.ID, requester.ID)
+		// Still the owner
+		c.mu.Lock()
+		c.PageStore[pageNo] = previousPage
+		c.mu.Unlock()
+		return fmt.Errorf("%s for page %s not acknowledged by Client %d", PAGE_SEND, pageNo, requester.ID)
+	}
+
+	c.mu.Lock()
+	c.probOwners[pageNo] = requester
+	delete(c.copySets, pageNo)
+	c.mu.Unlock()
+	return nil
+}
+
+// PAGE_SEND in DYNAMIC mode. A READ copy remembers who sent it as the probable owner.
+// With a WRITE the page, ownership and copy set arrive together, and the copies are invalidated
+// before the page is installed with READWRITE access.
+func (c *Client) handleDynamicPageSend(ctx context.Context, msg Message) {
+	sentPage := msg.Payload.PageSend.Page
+	pageNo := sentPage.Number
+
+	if msg.Payload.PageSend.Purpose == READ {
+		sentPage = c.installPage(sentPage, READ)
+		c.mu.Lock()
+		c.probOwners[pageNo] = ClientPointer{ID: msg.FromID, IP: msg.FromIP}
+		c.mu.Unlock()
+		c.pending.complete(msg.RequestID, requestResult{page: sentPage})
+		return
+	}
+
+	copySet := msg.Payload.PageSend.CopySet
+	c.mu.Lock()
+	c.probOwners[pageNo] = ClientPointer{ID: c.ID, IP: c.IP}
+	c.mu.Unlock()
+	if err := c.invalidateCopies(ctx, pageNo, copySet, msg.RequestID); err != nil {
+		// Some readers may still hold a copy, so this owner only gets a READ copy and keeps the copy set
+		// for the next write to invalidate
+		c.installPage(sentPage, READ)
+		c.mu.Lock()
+		c.copySets[pageNo] = copySet
+		c.mu.Unlock()
+		c.pending.complete(msg.RequestID, requestResult{err: err})
+		return
+	}
+	sentPage = c.installPage(sentPage, READWRITE)
+	c.mu.Lock()
+	delete(c.copySets, pageNo)
+	c.mu.Unlock()
+	c.pending.complete(msg.RequestID, requestResult{page: sentPage})
+}
+
+// INVALIDATE_COPY in DYNAMIC mode comes from the new owner
+func (c *Client) handleDynamicInvalidateCopy(msg Message) {
+	pageNo := msg.Payload.InvalidateCopy.PageNumber
+	c.invalidatePage(pageNo)
+	c.mu.Lock()
+	c.probOwners[pageNo] = ClientPointer{ID: msg.FromID, IP: msg.FromIP}
+	c.mu.Unlock()
+}
+
+// Copies of the probable owners (DYNAMIC mode) and of the copy sets of owned pages (DYNAMIC and IMPROVED modes),
+// safe to print while RPCs are being handled
+func (c *Client) Ownership() (map[string]ClientPointer, map[string][]ClientPointer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	probOwners := make(map[string]ClientPointer, len(c.probOwners))
+	for pageNo, owner := range c.probOwners {
+		probOwners[pageNo] = owner
+	}
+	copySets := make(map[string][]ClientPointer, len(c.copySets))
+	for pageNo, copySet := range c.copySets {
+		copySets[pageNo] = append([]ClientPointer{}, copySet...)
+	}
+	return probOwners, copySets
+}
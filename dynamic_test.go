@@ -0,0 +1,146 @@
+package ivy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDynamicForwarding(t *testing.T) {
+	dsm := newTestDSM(t, Config{Mode: DYNAMIC}, 3)
+	c1, c2, c3 := dsm.clients[0], dsm.clients[1], dsm.clients[2]
+	if err := c2.Write("0", []byte("two")); err != nil {
+		t.Fatal(err)
+	}
+	// Forwarded by Client 1, which points at Client 2 since the write
+	if page, err := c3.Read("0"); err != nil || !bytes.HasPrefix(page.Content, []byte("two")) {
+		t.Fatalf("read %q, %v", page.Content, err)
+	}
+	if err := c1.Write("0", []byte("one")); err != nil {
+		t.Fatal(err)
+	}
+	if page, exists := c3.localPage("0"); exists {
+		t.Errorf("copy of Client 3 not invalidated: %+v", page)
+	}
+	probOwners, copySets := c1.Ownership()
+	if owner := probOwners["0"]; owner.ID != c1.ID {
+		t.Errorf("Client 1 points at Client %d, want itself", owner.ID)
+	}
+	if len(copySets["0"]) != 0 {
+		t.Errorf("copy set %v left after the write", copySets["0"])
+	}
+}
+
+// A request that reaches a client whose own fault holds the page lock is acknowledged and served
+// once the lock is free
+func TestDynamicRequestQueuedBehindFault(t *testing.T) {
+	// On the wall clock, so the lease of the lock does not expire while the test waits
+	dsm := newTCPTestDSM(t, Config{Mode: DYNAMIC}, 2)
+	c1, c2 := dsm.clients[0], dsm.clients[1]
+	if _, err := c1.pageLocks.acquire(context.Background(), "0", "fault"); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() {
+		_, err := c2.Read("0")
+		done <- err
+	}()
+	waitFor(t, func() bool {
+		c1.pageLocks.mu.Lock()
+		defer c1.pageLocks.mu.Unlock()
+		lock, exists := c1.pageLocks.pages["0"]
+		return exists && len(lock.queue) == 1
+	})
+	c1.pageLocks.release("0", "fault")
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	// The owner adds the reader to its copy set once the PAGE_SEND is acknowledged
+	waitFor(t, func() bool {
+		_, copySets := c1.Ownership()
+		return reflect.DeepEqual(copySets["0"], []ClientPointer{{ID: c2.ID, IP: c2.IP}})
+	})
+}
+
+// Probable owners left pointing at each other fail the request instead of deadlocking on the page lock
+// of the requester
+func TestDynamicRequestForwardedBackRejected(t *testing.T) {
+	dsm := newTestDSM(t, Config{Mode: DYNAMIC}, 3)
+	c2, c3 := dsm.clients[1], dsm.clients[2]
+	c2.probOwners["0"] = ClientPointer{ID: c3.ID, IP: c3.IP}
+	c3.probOwners["0"] = ClientPointer{ID: c2.ID, IP: c2.IP}
+
+	_, err := c2.Read("0")
+	if err == nil || errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("got %v, want the cycle reported", err)
+	}
+	if page := c2.PageStore["0"]; page.Access != NIL {
+		t.Errorf("page left %s", page.Access)
+	}
+}
+
+// A client only points at a write requester once it has forwarded the request
+func TestDynamicFailedForwardKeepsProbableOwner(t *testing.T) {
+	dsm := newTestDSM(t, Config{Mode: DYNAMIC}, 3)
+	c1, c2, c3 := dsm.clients[0], dsm.clients[1], dsm.clients[2]
+	c3.probOwners["0"] = ClientPointer{ID: c2.ID, IP: c2.IP}
+	dsm.network.crash(c1.IP)
+
+	if err := c3.Write("0", []byte("x")); err == nil {
+		t.Fatal("write forwarded to a crashed owner succeeded")
+	}
+	probOwners, _ := c2.Ownership()
+	if owner, known := probOwners["0"]; known {
+		t.Errorf("Client 2 points at Client %d, want the owner it could not reach", owner.ID)
+	}
+}
+
+// The owner keeps the page and its copy set if a reader cannot be invalidated
+func TestDynamicOwnerWriteAbortedOnFailedInvalidation(t *testing.T) {
+	dsm := newTestDSM(t, Config{Mode: DYNAMIC}, 2)
+	c1, c2 := dsm.clients[0], dsm.clients[1]
+	if err := c1.Write("0", []byte("old")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c2.Read("0"); err != nil {
+		t.Fatal(err)
+	}
+	dsm.network.crash(c2.IP)
+
+	if err := c1.Write("0", []byte("new")); err == nil {
+		t.Fatal("write applied with a copy left valid")
+	}
+	page := c1.PageStore["0"]
+	if page.Access != READ || !bytes.HasPrefix(page.Content, []byte("old")) {
+		t.Errorf("page left %s with %q", page.Access, page.Content[:3])
+	}
+	if _, copySets := c1.Ownership(); len(copySets["0"]) != 1 || copySets["0"][0].ID != c2.ID {
+		t.Errorf("copy set %v, want Client 2", copySets["0"])
+	}
+}
+
+// The new owner of a page only takes write access once the copies that came with it are invalidated
+func TestDynamicWriteFaultAbortedOnFailedInvalidation(t *testing.T) {
+	dsm := newTestDSM(t, Config{Mode: DYNAMIC}, 3)
+	c1, c2, c3 := dsm.clients[0], dsm.clients[1], dsm.clients[2]
+	if err := c1.Write("0", []byte("old")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c2.Read("0"); err != nil {
+		t.Fatal(err)
+	}
+	dsm.network.crash(c2.IP)
+
+	if _, err := c3.FetchAndAdd("0", 1); err == nil {
+		t.Fatal("atomic operation applied with a copy left valid")
+	}
+	if page := c3.PageStore["0"]; page.Access == READWRITE {
+		t.Error("page installed with write access")
+	}
+	if owners, copySets := c3.Ownership(); owners["0"].ID != c3.ID || len(copySets["0"]) != 1 || copySets["0"][0].ID != c2.ID {
+		t.Errorf("owner %v with copy set %v, want Client 3 with Client 2", owners["0"], copySets["0"])
+	}
+}
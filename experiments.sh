@@ -1,7 +1,7 @@
 #!/bin/bash
 
 # Build and run the main program in the background
-go build
+go build ./cmd/ivy
 ./ivy &
 
 # Wait for the program to initialize
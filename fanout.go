@@ -0,0 +1,75 @@
+package ivy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Reports the copy-set members that did not acknowledge a fanned-out INVALIDATE_COPY/UPDATE_COPY
+type fanOutError struct {
+	msgType string
+	pageNo  string
+	// Clients that refused the message or could not be reached
+	failed []int
+	// Clients that had not answered by the deadline
+	timedOut []int
+}
+
+func (e *fanOutError) Error() string {
+	s := fmt.Sprintf("%s for page %s", e.msgType, e.pageNo)
+	if len(e.failed) > 0 {
+		s += fmt.Sprintf(" not acknowledged by Clients %v", e.failed)
+	}
+	if len(e.failed) > 0 && len(e.timedOut) > 0 {
+		s += " and"
+	}
+	if len(e.timedOut) > 0 {
+		s += fmt.Sprintf(" not answered in time by Clients %v", e.timedOut)
+	}
+	return s
+}
+
+// Calls send for every target concurrently and waits until all of them have acknowledged, or
+// FAN_OUT_TIMEOUT elapses or ctx is done. Returns a *fanOutError naming every target that did not
+// acknowledge. The sends still in progress at the deadline are abandoned through their ctx.
+func fanOut(ctx context.Context, clock clock, msgType string, pageNo string, targets []ClientPointer, send func(context.Context, ClientPointer) Reply) error {
+	ctx, cancel := clock.WithTimeout(ctx, FAN_OUT_TIMEOUT)
+	defer cancel()
+
+	type ack struct {
+		id int
+		ok bool
+	}
+	acks := make(chan ack, len(targets))
+	waiting := make(map[int]bool, len(targets))
+	for _, target := range targets {
+		waiting[target.ID] = true
+		clock.Go(func() {
+			acks <- ack{id: target.ID, ok: send(ctx, target).Ack}
+		})
+	}
+
+	fanOutErr := &fanOutError{msgType: msgType, pageNo: pageNo}
+	for len(waiting) > 0 {
+		a, err := receiveContext(clock, ctx, acks)
+		if err != nil {
+			for id := range waiting {
+				fanOutErr.timedOut = append(fanOutErr.timedOut, id)
+			}
+			break
+		}
+		delete(waiting, a.id)
+		if !a.ok {
+			fanOutErr.failed = append(fanOutErr.failed, a.id)
+		}
+	}
+
+	if len(fanOutErr.failed) == 0 && len(fanOutErr.timedOut) == 0 {
+		return nil
+	}
+	sort.Ints(fanOutErr.failed)
+	sort.Ints(fanOutErr.timedOut)
+	logerror.Println(fanOutErr)
+	return fanOutErr
+}
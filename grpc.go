@@ -0,0 +1,172 @@
+//go:build grpc
+
+package ivy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/s4nat/ivy/ivypb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/peer"
+)
+
+// gRPC over HTTP/2, with the messages of ivypb/ivy.proto. Messages are sent to the Client or
+// CentralManager service of the target, over one connection per peer that carries any number of
+// messages at once. Only built with the grpc build tag. The zero value is ready to use, and must not
+// be copied once used.
+type GRPCTransport struct {
+	// Options of the connections to the other nodes. Defaults to connections without TLS, which
+	// reconnect to a restarted node within DEFAULT_MAX_RETRY_BACKOFF.
+	DialOptions []grpc.DialOption
+	// Options of the servers of the nodes listening through this transport
+	ServerOptions []grpc.ServerOption
+
+	mu    sync.Mutex
+	conns map[string]*grpcConn
+}
+
+type grpcConn struct {
+	conn *grpc.ClientConn
+	// Messages being sent over the connection
+	inFlight int
+}
+
+func (t *GRPCTransport) Send(ctx context.Context, target Target, msg Message) (Reply, error) {
+	conn, err := t.acquire(target.Addr)
+	if err != nil {
+		return Reply{}, fmt.Errorf("%w: dialing gRPC: %w", ErrNotDelivered, err)
+	}
+	defer t.release(conn)
+
+	var reply *ivypb.Reply
+	// The peer is only known once the message has been handed to a connection
+	var to peer.Peer
+	switch target.NodeType {
+	case CLIENT:
+		reply, err = ivypb.NewClientClient(conn.conn).HandleIncomingMessage(ctx, messageToProto(msg), grpc.Peer(&to))
+	case CENTRALMANAGER:
+		reply, err = ivypb.NewCentralManagerClient(conn.conn).HandleIncomingMessage(ctx, messageToProto(msg), grpc.Peer(&to))
+	default:
+		return Reply{}, fmt.Errorf("%w: unknown node type %s", ErrNotDelivered, target.NodeType)
+	}
+	if err != nil {
+		if to.Addr == nil {
+			return Reply{}, fmt.Errorf("%w: %w", ErrNotDelivered, err)
+		}
+		return Reply{}, err
+	}
+	return replyFromProto(reply), nil
+}
+
+// The connection to addr, dialed on first use. gRPC connects lazily and reconnects on its own.
+func (t *GRPCTransport) acquire(addr string) (*grpcConn, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.conns == nil {
+		t.conns = make(map[string]*grpcConn)
+	}
+	conn, exists := t.conns[addr]
+	if !exists {
+		options := t.DialOptions
+		if len(options) == 0 {
+			reconnect := backoff.DefaultConfig
+			reconnect.BaseDelay = DEFAULT_RETRY_BACKOFF
+			reconnect.MaxDelay = DEFAULT_MAX_RETRY_BACKOFF
+			options = []grpc.DialOption{
+				grpc.WithTransportCredentials(insecure.NewCredentials()),
+				grpc.WithConnectParams(grpc.ConnectParams{Backoff: reconnect}),
+			}
+		}
+		client, err := grpc.NewClient(addr, options...)
+		if err != nil {
+			return nil, err
+		}
+		conn = &grpcConn{conn: client}
+		t.conns[addr] = conn
+	}
+	conn.inFlight++
+	return conn, nil
+}
+
+func (t *GRPCTransport) release(conn *grpcConn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	conn.inFlight--
+}
+
+// Closes the connections no message is being sent over
+func (t *GRPCTransport) CloseIdleConnections() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for addr, conn := range t.conns {
+		if conn.inFlight == 0 {
+			conn.conn.Close()
+			delete(t.conns, addr)
+		}
+	}
+}
+
+// Closing the returned closer stops the server and closes its connections, failing the messages being handled
+func (t *GRPCTransport) Listen(addr string, handlers map[string]Handler) (io.Closer, error) {
+	server := grpc.NewServer(t.ServerOptions...)
+	for nodeType, handle := range handlers {
+		switch nodeType {
+		case CLIENT:
+			ivypb.RegisterClientServer(server, &grpcClientService{handle: handle})
+		case CENTRALMANAGER:
+			ivypb.RegisterCentralManagerServer(server, &grpcCMService{handle: handle})
+		default:
+			return nil, fmt.Errorf("registering %s's gRPC service: unknown node type", nodeType)
+		}
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listening on %s: %w", addr, err)
+	}
+	go server.Serve(listener)
+	return closerFunc(func() error {
+		server.Stop()
+		return nil
+	}), nil
+}
+
+var _ Transport = (*GRPCTransport)(nil)
+
+// Handles a message received over gRPC. Senders that leave Message.timeout unset, such as non-Go
+// tooling, can give the message a deadline with the gRPC deadline of the call instead.
+func handleGRPC(ctx context.Context, handle Handler, pb *ivypb.Message) (*ivypb.Reply, error) {
+	msg := messageFromProto(pb)
+	if deadline, ok := ctx.Deadline(); ok && msg.Timeout == 0 {
+		msg.Timeout = time.Until(deadline)
+	}
+	var reply Reply
+	if err := handle(msg, &reply); err != nil {
+		return nil, err
+	}
+	return replyToProto(reply), nil
+}
+
+type grpcClientService struct {
+	ivypb.UnimplementedClientServer
+	handle Handler
+}
+
+func (s *grpcClientService) HandleIncomingMessage(ctx context.Context, msg *ivypb.Message) (*ivypb.Reply, error) {
+	return handleGRPC(ctx, s.handle, msg)
+}
+
+type grpcCMService struct {
+	ivypb.UnimplementedCentralManagerServer
+	handle Handler
+}
+
+func (s *grpcCMService) HandleIncomingMessage(ctx context.Context, msg *ivypb.Message) (*ivypb.Reply, error) {
+	return handleGRPC(ctx, s.handle, msg)
+}
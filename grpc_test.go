@@ -0,0 +1,67 @@
+//go:build grpc
+
+package ivy
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGRPCTransportSend(t *testing.T) {
+	addr := freeAddr(t)
+	received := make(chan Message, 1)
+	server := &GRPCTransport{}
+	closer, err := server.Listen(addr, map[string]Handler{
+		CENTRALMANAGER: func(msg Message, reply *Reply) error {
+			received <- msg
+			reply.Ack = true
+			reply.Notices = map[string]int{"P0": 2}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer.Close()
+
+	client := &GRPCTransport{}
+	defer client.CloseIdleConnections()
+	msg := Message{
+		Type:      WRITE_REQUEST,
+		Payload:   Payload{WriteRequest: WriteRequest{PageNo: "P0", Offset: 3, Content: []byte("abc")}},
+		FromID:    1,
+		FromIP:    "127.0.0.1:1",
+		RequestID: "1-a-1",
+		Timeout:   time.Second,
+	}
+	reply, err := client.Send(context.Background(), Target{NodeType: CENTRALMANAGER, ID: -1, Addr: addr}, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Reply{Ack: true, Notices: map[string]int{"P0": 2}}); !reflect.DeepEqual(reply, want) {
+		t.Errorf("reply %+v, want %+v", reply, want)
+	}
+	if got := <-received; !reflect.DeepEqual(got, msg) {
+		t.Errorf("handler received %+v, want %+v", got, msg)
+	}
+
+	// The server only serves the CentralManager service
+	if _, err := client.Send(context.Background(), Target{NodeType: CLIENT, ID: 1, Addr: addr}, msg); err == nil {
+		t.Error("message to an unregistered Client service was answered")
+	}
+}
+
+// A message to a node that is not listening never reaches a handler, so it can be retried
+func TestGRPCTransportNotDelivered(t *testing.T) {
+	client := &GRPCTransport{}
+	defer client.CloseIdleConnections()
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err := client.Send(ctx, Target{NodeType: CENTRALMANAGER, ID: -1, Addr: freeAddr(t)}, Message{Type: PULSE})
+	if !errors.Is(err, ErrNotDelivered) {
+		t.Errorf("got %v, want an error wrapping ErrNotDelivered", err)
+	}
+}
@@ -0,0 +1,411 @@
+//go:build grpc
+
+package ivy
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/s4nat/ivy/ivypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Conversions between the messages of message.go and those of ivypb/ivy.proto. Every field is
+// carried, so a message survives the round trip as it would through gob. Only the fields of the
+// Payload that are set go on the wire.
+
+func messageToProto(msg Message) *ivypb.Message {
+	return &ivypb.Message{
+		Type:      msg.Type,
+		Payload:   payloadToProto(msg.Payload),
+		FromId:    int64(msg.FromID),
+		FromIp:    msg.FromIP,
+		RequestId: msg.RequestID,
+		TxnId:     msg.TxnID,
+		Timeout:   durationToProto(msg.Timeout),
+	}
+}
+
+func messageFromProto(pb *ivypb.Message) Message {
+	return Message{
+		Type:      pb.GetType(),
+		Payload:   payloadFromProto(pb.GetPayload()),
+		FromID:    int(pb.GetFromId()),
+		FromIP:    pb.GetFromIp(),
+		RequestID: pb.GetRequestId(),
+		TxnID:     pb.GetTxnId(),
+		Timeout:   durationFromProto(pb.GetTimeout()),
+	}
+}
+
+func replyToProto(reply Reply) *ivypb.Reply {
+	pb := &ivypb.Reply{
+		Ack:     reply.Ack,
+		Done:    reply.Done,
+		Err:     reply.Err,
+		Notices: noticesToProto(reply.Notices),
+	}
+	if reply.Payload != nil {
+		pb.Payload = make(map[string]*ivypb.PageInfo, len(reply.Payload))
+		for pageNo, pageInfo := range reply.Payload {
+			pb.Payload[pageNo] = &ivypb.PageInfo{
+				Owner:     clientPointerToProto(pageInfo.Owner),
+				CopySet:   copySetToProto(pageInfo.CopySet),
+				Coherence: pageInfo.Coherence,
+			}
+		}
+	}
+	if reply.Requests != nil {
+		pb.Requests = make(map[string]*ivypb.RequestRecord, len(reply.Requests))
+		for requestID, record := range reply.Requests {
+			pb.Requests[requestID] = &ivypb.RequestRecord{Status: record.Status, Started: timeToProto(record.Started)}
+		}
+	}
+	if set(reply.Home) {
+		pb.Home = homePageToProto(reply.Home)
+	}
+	if reply.Homes != nil {
+		pb.Homes = make(map[string]*ivypb.HomePage, len(reply.Homes))
+		for pageNo, home := range reply.Homes {
+			pb.Homes[pageNo] = homePageToProto(home)
+		}
+	}
+	return pb
+}
+
+func replyFromProto(pb *ivypb.Reply) Reply {
+	reply := Reply{
+		Ack:     pb.GetAck(),
+		Done:    pb.GetDone(),
+		Err:     pb.GetErr(),
+		Home:    homePageFromProto(pb.GetHome()),
+		Notices: noticesFromProto(pb.GetNotices()),
+	}
+	if pb.GetPayload() != nil {
+		reply.Payload = make(map[string]PageInfo, len(pb.GetPayload()))
+		for pageNo, pageInfo := range pb.GetPayload() {
+			reply.Payload[pageNo] = PageInfo{
+				Owner:     clientPointerFromProto(pageInfo.GetOwner()),
+				CopySet:   copySetFromProto(pageInfo.GetCopySet()),
+				Coherence: pageInfo.GetCoherence(),
+			}
+		}
+	}
+	if pb.GetRequests() != nil {
+		reply.Requests = make(map[string]RequestRecord, len(pb.GetRequests()))
+		for requestID, record := range pb.GetRequests() {
+			reply.Requests[requestID] = RequestRecord{Status: record.GetStatus(), Started: timeFromProto(record.GetStarted())}
+		}
+	}
+	if pb.GetHomes() != nil {
+		reply.Homes = make(map[string]HomePage, len(pb.GetHomes()))
+		for pageNo, home := range pb.GetHomes() {
+			reply.Homes[pageNo] = homePageFromProto(home)
+		}
+	}
+	return reply
+}
+
+func payloadToProto(p Payload) *ivypb.Payload {
+	pb := &ivypb.Payload{}
+	if set(p.ReadRequest) {
+		pb.ReadRequest = &ivypb.ReadRequest{PageNo: p.ReadRequest.PageNo}
+	}
+	if set(p.ReadForward) {
+		pb.ReadForward = &ivypb.ReadForward{
+			ReadRequesterId: int64(p.ReadForward.ReadRequesterID),
+			ReadRequesterIp: p.ReadForward.ReadRequesterIP,
+			PageNo:          p.ReadForward.PageNo,
+		}
+	}
+	if set(p.PageSend) {
+		pb.PageSend = &ivypb.PageSend{
+			Purpose: p.PageSend.Purpose,
+			Page:    &ivypb.Page{Number: p.PageSend.Page.Number, Content: p.PageSend.Page.Content, Access: p.PageSend.Page.Access},
+			CopySet: copySetToProto(p.PageSend.CopySet),
+			Shared:  p.PageSend.Shared,
+		}
+	}
+	if set(p.ReadConfirmation) {
+		pb.ReadConfirmation = &ivypb.ReadConfirmation{
+			PageNumber:      p.ReadConfirmation.PageNumber,
+			ReadRequesterId: int64(p.ReadConfirmation.ReadRequesterID),
+			ReadRequesterIp: p.ReadConfirmation.ReadRequesterIP,
+			SenderId:        int64(p.ReadConfirmation.SenderID),
+			SenderIp:        p.ReadConfirmation.SenderIP,
+		}
+	}
+	if set(p.WriteRequest) {
+		pb.WriteRequest = &ivypb.WriteRequest{
+			PageNo:  p.WriteRequest.PageNo,
+			Offset:  int64(p.WriteRequest.Offset),
+			Content: p.WriteRequest.Content,
+		}
+	}
+	if set(p.InvalidateCopy) {
+		pb.InvalidateCopy = &ivypb.InvalidateCopy{
+			WriteRequesterId: int64(p.InvalidateCopy.WriteRequesterID),
+			PageNumber:       p.InvalidateCopy.PageNumber,
+		}
+	}
+	if set(p.InvalidateConfirmation) {
+		pb.InvalidateConfirmation = &ivypb.InvalidateConfirmation{
+			WriteRequesterId: int64(p.InvalidateConfirmation.WriteRequesterID),
+			PageNumber:       p.InvalidateConfirmation.PageNumber,
+		}
+	}
+	if set(p.WriteForward) {
+		pb.WriteForward = &ivypb.WriteForward{
+			WriteRequesterId: int64(p.WriteForward.WriteRequesterID),
+			WriteRequesterIp: p.WriteForward.WriteRequesterIP,
+			PageNumber:       p.WriteForward.PageNumber,
+			Offset:           int64(p.WriteForward.Offset),
+			Content:          p.WriteForward.Content,
+			Coherence:        p.WriteForward.Coherence,
+			CopySet:          copySetToProto(p.WriteForward.CopySet),
+		}
+	}
+	if set(p.WriteConfirmation) {
+		pb.WriteConfirmation = &ivypb.WriteConfirmation{
+			WriterId:   int64(p.WriteConfirmation.WriterID),
+			WriterIp:   p.WriteConfirmation.WriterIP,
+			PageNumber: p.WriteConfirmation.PageNumber,
+		}
+	}
+	if set(p.Pulse) {
+		pb.Pulse = &ivypb.Pulse{FromIp: p.Pulse.FromIP}
+	}
+	if set(p.ChangeCM) {
+		pb.ChangeCm = &ivypb.ChangeCM{NewCmIp: p.ChangeCM.NewCMIP}
+	}
+	if set(p.ImBack) {
+		pb.ImBack = &ivypb.ImBack{CmIp: p.ImBack.CMIP}
+	}
+	if set(p.UpdateCopy) {
+		pb.UpdateCopy = &ivypb.UpdateCopy{
+			PageNumber: p.UpdateCopy.PageNumber,
+			Offset:     int64(p.UpdateCopy.Offset),
+			Content:    p.UpdateCopy.Content,
+		}
+	}
+	if set(p.SetCoherence) {
+		pb.SetCoherence = &ivypb.SetCoherence{PageNo: p.SetCoherence.PageNo, Policy: p.SetCoherence.Policy}
+	}
+	if set(p.UpgradeGrant) {
+		pb.UpgradeGrant = &ivypb.UpgradeGrant{
+			PageNumber: p.UpgradeGrant.PageNumber,
+			Offset:     int64(p.UpgradeGrant.Offset),
+			Content:    p.UpgradeGrant.Content,
+		}
+	}
+	if set(p.AllocatePage) {
+		pb.AllocatePage = &ivypb.AllocatePage{PageNo: p.AllocatePage.PageNo}
+	}
+	if set(p.FreePage) {
+		pb.FreePage = &ivypb.FreePage{PageNo: p.FreePage.PageNo}
+	}
+	if set(p.DropPage) {
+		pb.DropPage = &ivypb.DropPage{PageNumber: p.DropPage.PageNumber}
+	}
+	if set(p.Lock) {
+		pb.Lock = &ivypb.Lock{Name: p.Lock.Name, Shared: p.Lock.Shared, Notices: noticesToProto(p.Lock.Notices)}
+	}
+	if set(p.Barrier) {
+		pb.Barrier = &ivypb.Barrier{Name: p.Barrier.Name, N: int64(p.Barrier.N)}
+	}
+	if set(p.TxnLock) {
+		pb.TxnLock = &ivypb.TxnLock{PageNo: p.TxnLock.PageNo, Timeout: durationToProto(p.TxnLock.Timeout)}
+	}
+	if set(p.HomeFetch) {
+		pb.HomeFetch = &ivypb.HomeFetch{PageNo: p.HomeFetch.PageNo}
+	}
+	if set(p.HomeDiff) {
+		diffs := make([]*ivypb.Diff, len(p.HomeDiff.Diffs))
+		for i, diff := range p.HomeDiff.Diffs {
+			diffs[i] = &ivypb.Diff{Offset: int64(diff.Offset), Content: diff.Content}
+		}
+		pb.HomeDiff = &ivypb.HomeDiff{PageNo: p.HomeDiff.PageNo, Diffs: diffs}
+	}
+	return pb
+}
+
+// The getters of ivypb return zero values for the fields that are not set
+func payloadFromProto(pb *ivypb.Payload) Payload {
+	p := Payload{
+		ReadRequest: ReadRequest{PageNo: pb.GetReadRequest().GetPageNo()},
+		ReadForward: ReadForward{
+			ReadRequesterID: int(pb.GetReadForward().GetReadRequesterId()),
+			ReadRequesterIP: pb.GetReadForward().GetReadRequesterIp(),
+			PageNo:          pb.GetReadForward().GetPageNo(),
+		},
+		PageSend: PageSend{
+			Purpose: pb.GetPageSend().GetPurpose(),
+			Page: Page{
+				Number:  pb.GetPageSend().GetPage().GetNumber(),
+				Content: pb.GetPageSend().GetPage().GetContent(),
+				Access:  pb.GetPageSend().GetPage().GetAccess(),
+			},
+			CopySet: copySetFromProto(pb.GetPageSend().GetCopySet()),
+			Shared:  pb.GetPageSend().GetShared(),
+		},
+		ReadConfirmation: ReadConfirmation{
+			PageNumber:      pb.GetReadConfirmation().GetPageNumber(),
+			ReadRequesterID: int(pb.GetReadConfirmation().GetReadRequesterId()),
+			ReadRequesterIP: pb.GetReadConfirmation().GetReadRequesterIp(),
+			SenderID:        int(pb.GetReadConfirmation().GetSenderId()),
+			SenderIP:        pb.GetReadConfirmation().GetSenderIp(),
+		},
+		WriteRequest: WriteRequest{
+			PageNo:  pb.GetWriteRequest().GetPageNo(),
+			Offset:  int(pb.GetWriteRequest().GetOffset()),
+			Content: pb.GetWriteRequest().GetContent(),
+		},
+		InvalidateCopy: InvalidateCopy{
+			WriteRequesterID: int(pb.GetInvalidateCopy().GetWriteRequesterId()),
+			PageNumber:       pb.GetInvalidateCopy().GetPageNumber(),
+		},
+		InvalidateConfirmation: InvalidateConfirmation{
+			WriteRequesterID: int(pb.GetInvalidateConfirmation().GetWriteRequesterId()),
+			PageNumber:       pb.GetInvalidateConfirmation().GetPageNumber(),
+		},
+		WriteForward: WriteForward{
+			WriteRequesterID: int(pb.GetWriteForward().GetWriteRequesterId()),
+			WriteRequesterIP: pb.GetWriteForward().GetWriteRequesterIp(),
+			PageNumber:       pb.GetWriteForward().GetPageNumber(),
+			Offset:           int(pb.GetWriteForward().GetOffset()),
+			Content:          pb.GetWriteForward().GetContent(),
+			Coherence:        pb.GetWriteForward().GetCoherence(),
+			CopySet:          copySetFromProto(pb.GetWriteForward().GetCopySet()),
+		},
+		WriteConfirmation: WriteConfirmation{
+			WriterID:   int(pb.GetWriteConfirmation().GetWriterId()),
+			WriterIP:   pb.GetWriteConfirmation().GetWriterIp(),
+			PageNumber: pb.GetWriteConfirmation().GetPageNumber(),
+		},
+		Pulse:    Pulse{FromIP: pb.GetPulse().GetFromIp()},
+		ChangeCM: ChangeCM{NewCMIP: pb.GetChangeCm().GetNewCmIp()},
+		ImBack:   ImBack{CMIP: pb.GetImBack().GetCmIp()},
+		UpdateCopy: UpdateCopy{
+			PageNumber: pb.GetUpdateCopy().GetPageNumber(),
+			Offset:     int(pb.GetUpdateCopy().GetOffset()),
+			Content:    pb.GetUpdateCopy().GetContent(),
+		},
+		SetCoherence: SetCoherence{PageNo: pb.GetSetCoherence().GetPageNo(), Policy: pb.GetSetCoherence().GetPolicy()},
+		UpgradeGrant: UpgradeGrant{
+			PageNumber: pb.GetUpgradeGrant().GetPageNumber(),
+			Offset:     int(pb.GetUpgradeGrant().GetOffset()),
+			Content:    pb.GetUpgradeGrant().GetContent(),
+		},
+		AllocatePage: AllocatePage{PageNo: pb.GetAllocatePage().GetPageNo()},
+		FreePage:     FreePage{PageNo: pb.GetFreePage().GetPageNo()},
+		DropPage:     DropPage{PageNumber: pb.GetDropPage().GetPageNumber()},
+		Lock: Lock{
+			Name:    pb.GetLock().GetName(),
+			Shared:  pb.GetLock().GetShared(),
+			Notices: noticesFromProto(pb.GetLock().GetNotices()),
+		},
+		Barrier:   Barrier{Name: pb.GetBarrier().GetName(), N: int(pb.GetBarrier().GetN())},
+		TxnLock:   TxnLock{PageNo: pb.GetTxnLock().GetPageNo(), Timeout: durationFromProto(pb.GetTxnLock().GetTimeout())},
+		HomeFetch: HomeFetch{PageNo: pb.GetHomeFetch().GetPageNo()},
+		HomeDiff:  HomeDiff{PageNo: pb.GetHomeDiff().GetPageNo()},
+	}
+	for _, diff := range pb.GetHomeDiff().GetDiffs() {
+		p.HomeDiff.Diffs = append(p.HomeDiff.Diffs, Diff{Offset: int(diff.GetOffset()), Content: diff.GetContent()})
+	}
+	return p
+}
+
+// Whether a payload field or other struct has anything to send
+func set(v any) bool {
+	return !reflect.ValueOf(v).IsZero()
+}
+
+func clientPointerToProto(clientPointer ClientPointer) *ivypb.ClientPointer {
+	return &ivypb.ClientPointer{Id: int64(clientPointer.ID), Ip: clientPointer.IP}
+}
+
+func clientPointerFromProto(pb *ivypb.ClientPointer) ClientPointer {
+	return ClientPointer{ID: int(pb.GetId()), IP: pb.GetIp()}
+}
+
+func copySetToProto(copySet []ClientPointer) []*ivypb.ClientPointer {
+	if copySet == nil {
+		return nil
+	}
+	pb := make([]*ivypb.ClientPointer, len(copySet))
+	for i, clientPointer := range copySet {
+		pb[i] = clientPointerToProto(clientPointer)
+	}
+	return pb
+}
+
+func copySetFromProto(pb []*ivypb.ClientPointer) []ClientPointer {
+	if pb == nil {
+		return nil
+	}
+	copySet := make([]ClientPointer, len(pb))
+	for i, clientPointer := range pb {
+		copySet[i] = clientPointerFromProto(clientPointer)
+	}
+	return copySet
+}
+
+func homePageToProto(home HomePage) *ivypb.HomePage {
+	return &ivypb.HomePage{Content: home.Content, Version: int64(home.Version)}
+}
+
+func homePageFromProto(pb *ivypb.HomePage) HomePage {
+	return HomePage{Content: pb.GetContent(), Version: int(pb.GetVersion())}
+}
+
+func noticesToProto(notices map[string]int) map[string]int64 {
+	if notices == nil {
+		return nil
+	}
+	pb := make(map[string]int64, len(notices))
+	for pageNo, version := range notices {
+		pb[pageNo] = int64(version)
+	}
+	return pb
+}
+
+func noticesFromProto(pb map[string]int64) map[string]int {
+	if pb == nil {
+		return nil
+	}
+	notices := make(map[string]int, len(pb))
+	for pageNo, version := range pb {
+		notices[pageNo] = int(version)
+	}
+	return notices
+}
+
+// Unset for 0, which waits indefinitely
+func durationToProto(d time.Duration) *durationpb.Duration {
+	if d == 0 {
+		return nil
+	}
+	return durationpb.New(d)
+}
+
+func durationFromProto(pb *durationpb.Duration) time.Duration {
+	if pb == nil {
+		return 0
+	}
+	return pb.AsDuration()
+}
+
+func timeToProto(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func timeFromProto(pb *timestamppb.Timestamp) time.Time {
+	if pb == nil {
+		return time.Time{}
+	}
+	return pb.AsTime()
+}
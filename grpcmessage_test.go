@@ -0,0 +1,174 @@
+//go:build grpc
+
+package ivy
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/s4nat/ivy/ivypb"
+	"google.golang.org/protobuf/proto"
+)
+
+// Payload field carried by each message type
+var payloadFields = map[string]string{
+	READ_REQUEST:            "ReadRequest",
+	READ_FORWARD:            "ReadForward",
+	PAGE_SEND:               "PageSend",
+	READ_CONFIRMATION:       "ReadConfirmation",
+	WRITE_REQUEST:           "WriteRequest",
+	INVALIDATE_COPY:         "InvalidateCopy",
+	INVALIDATE_CONFIRMATION: "InvalidateConfirmation",
+	WRITE_FORWARD:           "WriteForward",
+	WRITE_CONFIRMATION:      "WriteConfirmation",
+	PULSE:                   "Pulse",
+	CHANGE_CM:               "ChangeCM",
+	IM_BACK:                 "ImBack",
+	UPDATE_COPY:             "UpdateCopy",
+	SET_COHERENCE:           "SetCoherence",
+	UPGRADE_REQUEST:         "WriteRequest",
+	UPGRADE_GRANT:           "UpgradeGrant",
+	ALLOCATE_PAGE:           "AllocatePage",
+	FREE_PAGE:               "FreePage",
+	DROP_PAGE:               "DropPage",
+	LOCK_ACQUIRE:            "Lock",
+	LOCK_RELEASE:            "Lock",
+	LOCK_RENEW:              "Lock",
+	BARRIER_WAIT:            "Barrier",
+	TXN_LOCK:                "TxnLock",
+	TXN_UNLOCK:              "TxnLock",
+	HOME_FETCH:              "HomeFetch",
+	HOME_DIFF:               "HomeDiff",
+}
+
+// Sets every field reachable from v to a non-zero value, distinct from the others, so a field the
+// conversion drops comes back different
+func fill(v reflect.Value, next *int) {
+	*next++
+	n := *next
+	if v.Type() == reflect.TypeOf(time.Time{}) {
+		v.Set(reflect.ValueOf(time.Unix(int64(n)*1000, int64(n)).UTC()))
+		return
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(fmt.Sprintf("s%d", n))
+	case reflect.Int, reflect.Int64:
+		v.SetInt(int64(n))
+	case reflect.Uint8:
+		v.SetUint(uint64(n % 256))
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			fill(v.Field(i), next)
+		}
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 2, 2))
+		for i := 0; i < v.Len(); i++ {
+			fill(v.Index(i), next)
+		}
+	case reflect.Map:
+		v.Set(reflect.MakeMap(v.Type()))
+		for i := 0; i < 2; i++ {
+			key := reflect.New(v.Type().Key()).Elem()
+			fill(key, next)
+			value := reflect.New(v.Type().Elem()).Elem()
+			fill(value, next)
+			v.SetMapIndex(key, value)
+		}
+	default:
+		panic("fill: unsupported kind " + v.Kind().String())
+	}
+}
+
+// Through the conversions and the wire format
+func roundTripMessage(t *testing.T, msg Message) Message {
+	t.Helper()
+	wire, err := proto.Marshal(messageToProto(msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pb ivypb.Message
+	if err := proto.Unmarshal(wire, &pb); err != nil {
+		t.Fatal(err)
+	}
+	return messageFromProto(&pb)
+}
+
+func TestMessageProtoRoundTrip(t *testing.T) {
+	covered := make(map[string]bool)
+	for msgType, field := range payloadFields {
+		covered[field] = true
+		t.Run(msgType, func(t *testing.T) {
+			next := 0
+			msg := Message{Type: msgType}
+			fill(reflect.ValueOf(&msg.FromID).Elem(), &next)
+			fill(reflect.ValueOf(&msg.FromIP).Elem(), &next)
+			fill(reflect.ValueOf(&msg.RequestID).Elem(), &next)
+			fill(reflect.ValueOf(&msg.TxnID).Elem(), &next)
+			fill(reflect.ValueOf(&msg.Timeout).Elem(), &next)
+			fill(reflect.ValueOf(&msg.Payload).Elem().FieldByName(field), &next)
+
+			if got := roundTripMessage(t, msg); !reflect.DeepEqual(got, msg) {
+				t.Errorf("round trip changed the message\n got: %+v\nwant: %+v", got, msg)
+			}
+		})
+	}
+
+	// A Payload field no message type carries would not be checked above
+	payloadType := reflect.TypeOf(Payload{})
+	for i := 0; i < payloadType.NumField(); i++ {
+		if name := payloadType.Field(i).Name; !covered[name] {
+			t.Errorf("Payload.%s is carried by no message type of payloadFields", name)
+		}
+	}
+}
+
+// Every field of Message and Payload set at once
+func TestMessageProtoRoundTripAllFields(t *testing.T) {
+	next := 0
+	var msg Message
+	fill(reflect.ValueOf(&msg).Elem(), &next)
+	if got := roundTripMessage(t, msg); !reflect.DeepEqual(got, msg) {
+		t.Errorf("round trip changed the message\n got: %+v\nwant: %+v", got, msg)
+	}
+}
+
+// A message with no payload comes back with the zero Payload, not with empty slices or maps
+func TestMessageProtoRoundTripEmpty(t *testing.T) {
+	msg := Message{Type: PULSE}
+	if got := roundTripMessage(t, msg); !reflect.DeepEqual(got, msg) {
+		t.Errorf("round trip changed the message\n got: %+v\nwant: %+v", got, msg)
+	}
+}
+
+func TestReplyProtoRoundTrip(t *testing.T) {
+	tests := map[string]func() Reply{
+		"empty": func() Reply { return Reply{} },
+		"all fields": func() Reply {
+			next := 0
+			var reply Reply
+			fill(reflect.ValueOf(&reply).Elem(), &next)
+			return reply
+		},
+	}
+	for name, build := range tests {
+		t.Run(name, func(t *testing.T) {
+			reply := build()
+			wire, err := proto.Marshal(replyToProto(reply))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var pb ivypb.Reply
+			if err := proto.Unmarshal(wire, &pb); err != nil {
+				t.Fatal(err)
+			}
+			if got := replyFromProto(&pb); !reflect.DeepEqual(got, reply) {
+				t.Errorf("round trip changed the reply\n got: %+v\nwant: %+v", got, reply)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package ivy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Histories of page reads and writes. A Client with Config.HistoryDir set appends every Read and Write
+// it makes to <HistoryDir>/client-<ID>.jsonl, one HistoryOp per line. Reads and writes of byte ranges
+// (ReadAt, WriteAt, ...) are not recorded, as they do not replace the value of a page.
+
+// Kinds of HistoryOp
+const (
+	HISTORY_READ  = "read"
+	HISTORY_WRITE = "write"
+)
+
+// A read or write of a whole page, with the times of its invocation and response in nanoseconds, on
+// the wall clock or, in a simulation, the simulated clock. Value is the content of the page without its trailing zero bytes, so a page nobody has
+// written reads as "". Response is 0 for a write that failed: it may or may not have taken effect.
+type HistoryOp struct {
+	Client   int
+	Kind     string
+	PageNo   string
+	Value    string
+	Invoke   int64
+	Response int64
+}
+
+// Appends the operations of one client to its history file
+type historyRecorder struct {
+	mu      sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+}
+
+func openHistory(dir string, clientID int) (*historyRecorder, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating history directory: %w", err)
+	}
+	file, err := os.OpenFile(filepath.Join(dir, fmt.Sprintf("client-%d.jsonl", clientID)), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("opening history file: %w", err)
+	}
+	return &historyRecorder{file: file, encoder: json.NewEncoder(file)}, nil
+}
+
+func (h *historyRecorder) record(op HistoryOp) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.file == nil {
+		return
+	}
+	if err := h.encoder.Encode(op); err != nil {
+		logerror.Println("Error recording history: ", err)
+	}
+}
+
+func (h *historyRecorder) close() error {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.file == nil {
+		return nil
+	}
+	err := h.file.Close()
+	h.file = nil
+	return err
+}
+
+func (c *Client) recordRead(pageNo string, invoke time.Time, page Page, err error) {
+	if c.history == nil {
+		return
+	}
+	if err != nil && !errors.Is(err, errNoSuchPage) {
+		// A failed read observed nothing
+		return
+	}
+	c.history.record(HistoryOp{
+		Client:   c.ID,
+		Kind:     HISTORY_READ,
+		PageNo:   pageNo,
+		Value:    historyValue(page.Content),
+		Invoke:   invoke.UnixNano(),
+		Response: c.clock.Now().UnixNano(),
+	})
+}
+
+func (c *Client) recordWrite(pageNo string, content []byte, invoke time.Time, err error) {
+	if c.history == nil {
+		return
+	}
+	op := HistoryOp{
+		Client: c.ID,
+		Kind:   HISTORY_WRITE,
+		PageNo: pageNo,
+		Value:  historyValue(content),
+		Invoke: invoke.UnixNano(),
+	}
+	if err == nil {
+		op.Response = c.clock.Now().UnixNano()
+	}
+	c.history.record(op)
+}
+
+func historyValue(content []byte) string {
+	return string(bytes.TrimRight(content, "\x00"))
+}
+
+// Reads the operations recorded in the given history files, and in the .jsonl files of the given directories
+func LoadHistory(paths ...string) ([]HistoryOp, error) {
+	ops := []HistoryOp{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		files := []string{path}
+		if info.IsDir() {
+			files, err = filepath.Glob(filepath.Join(path, "*.jsonl"))
+			if err != nil {
+				return nil, err
+			}
+			sort.Strings(files)
+		}
+		for _, name := range files {
+			fileOps, err := loadHistoryFile(name)
+			if err != nil {
+				return nil, err
+			}
+			ops = append(ops, fileOps...)
+		}
+	}
+	return ops, nil
+}
+
+func loadHistoryFile(name string) ([]HistoryOp, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	ops := []HistoryOp{}
+	scanner := bufio.NewScanner(file)
+	// A value escaped by JSON takes up to 6 bytes per byte of the page
+	scanner.Buffer(make([]byte, 0, 64*1024), 8*PAGE_SIZE)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var op HistoryOp
+		if err := json.Unmarshal(scanner.Bytes(), &op); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", name, line, err)
+		}
+		if op.Kind != HISTORY_READ && op.Kind != HISTORY_WRITE {
+			return nil, fmt.Errorf("%s:%d: unknown operation %q", name, line, op.Kind)
+		}
+		if op.Kind == HISTORY_READ && op.Response == 0 {
+			return nil, fmt.Errorf("%s:%d: read without a response", name, line)
+		}
+		ops = append(ops, op)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return ops, nil
+}
+
+// Prints the operation with its times relative to start
+func (op HistoryOp) Format(start int64) string {
+	response := "no response"
+	if op.Response != 0 {
+		response = fmt.Sprintf("%.3fs", time.Duration(op.Response-start).Seconds())
+	}
+	return fmt.Sprintf("Client %d %-5s %s %q [%.3fs, %s]", op.Client, op.Kind, op.PageNo, op.Value, time.Duration(op.Invoke-start).Seconds(), response)
+}
@@ -0,0 +1,280 @@
+package ivy
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Checks recorded histories against the memory models the protocol promises. Every page is a register
+// holding the value of its last write, "" at first.
+//
+// A history is sequentially consistent if its operations can be put in one order that keeps the order
+// of each client's operations and in which every read returns the value of the last write before it.
+// It is linearizable if that order also keeps the real time order: an operation that got its response
+// before another was invoked comes first. A write without a response may be placed anywhere after its
+// invocation, or left out.
+//
+// Both are checked with a depth first search over the operations that can come next, remembering the
+// states already visited. Linearizability is checked page by page, as a history is linearizable if the
+// history of each page is. Sequential consistency is checked on the whole history.
+
+// Number of search states after which the checker gives up on a history
+const HISTORY_SEARCH_LIMIT = 1000000
+
+// Returned when a history is too large or too concurrent for the search to decide it
+var errHistoryTooLarge = errors.New("history too large to check")
+
+type HistoryResult struct {
+	SequentiallyConsistent bool
+	Linearizable           bool
+	// Reduced set of operations that violates the first property that fails: no read, no write without
+	// a response, and no group of operations with the same page and value can be left out without the
+	// rest satisfying it. Completed writes are only left out with their group, so the counterexample
+	// is not always minimal.
+	Counterexample []HistoryOp
+}
+
+// Checks a history for sequential consistency and linearizability
+func CheckHistory(ops []HistoryOp) (HistoryResult, error) {
+	result := HistoryResult{}
+
+	consistent, err := checkOrder(ops, sameClientBefore)
+	if err != nil {
+		return result, fmt.Errorf("checking sequential consistency: %w", err)
+	}
+	if !consistent {
+		result.Counterexample, err = reduceHistory(ops, sameClientBefore)
+		return result, err
+	}
+	result.SequentiallyConsistent = true
+
+	byPage := map[string][]HistoryOp{}
+	for _, op := range ops {
+		byPage[op.PageNo] = append(byPage[op.PageNo], op)
+	}
+	pageNos := make([]string, 0, len(byPage))
+	for pageNo := range byPage {
+		pageNos = append(pageNos, pageNo)
+	}
+	sort.Strings(pageNos)
+	for _, pageNo := range pageNos {
+		linearizable, err := checkOrder(byPage[pageNo], realTimeBefore)
+		if err != nil {
+			return result, fmt.Errorf("checking linearizability of page %s: %w", pageNo, err)
+		}
+		if !linearizable {
+			result.Counterexample, err = reduceHistory(byPage[pageNo], realTimeBefore)
+			return result, err
+		}
+	}
+	result.Linearizable = true
+	return result, nil
+}
+
+// Whether a must come before b in the order of a history
+type beforeFunc func(a HistoryOp, b HistoryOp) bool
+
+// Program order. A write without a response may have taken effect at any time.
+func sameClientBefore(a HistoryOp, b HistoryOp) bool {
+	return a.Client == b.Client && a.Response != 0 && a.Response < b.Invoke
+}
+
+// Order of the operations of a client that took no time between them, as in a simulation, whose clock
+// only moves between messages. Such operations are recorded in the order they were made.
+func recordedBefore(a HistoryOp, b HistoryOp) bool {
+	return a.Client == b.Client && a.Response != 0 && a.Response == b.Invoke
+}
+
+// Real time order
+func realTimeBefore(a HistoryOp, b HistoryOp) bool {
+	return a.Response != 0 && a.Response < b.Invoke
+}
+
+// Reports whether the operations can be ordered so that every read returns the last value written,
+// keeping every pair ordered by before
+func checkOrder(ops []HistoryOp, before beforeFunc) (bool, error) {
+	search := &orderSearch{
+		ops:     ops,
+		preds:   make([][]int, len(ops)),
+		done:    make([]bool, len(ops)),
+		memory:  map[string]string{},
+		visited: map[string]bool{},
+	}
+	for i := range ops {
+		for j := range ops {
+			if i != j && (before(ops[j], ops[i]) || (j < i && recordedBefore(ops[j], ops[i]))) {
+				search.preds[i] = append(search.preds[i], j)
+			}
+		}
+	}
+	return search.run()
+}
+
+type orderSearch struct {
+	ops []HistoryOp
+	// Indexes of the operations that must come before each operation
+	preds  [][]int
+	done   []bool
+	memory map[string]string
+	// States already found to lead nowhere
+	visited map[string]bool
+}
+
+func (s *orderSearch) run() (bool, error) {
+	// A read that returns the current value can be taken right away: it changes nothing, and only
+	// lets more operations come next
+	taken := []int{}
+	for progress := true; progress; {
+		progress = false
+		for i, op := range s.ops {
+			if op.Kind == HISTORY_READ && s.enabled(i) && s.memory[op.PageNo] == op.Value {
+				s.done[i] = true
+				taken = append(taken, i)
+				progress = true
+			}
+		}
+	}
+	defer func() {
+		for _, i := range taken {
+			s.done[i] = false
+		}
+	}()
+
+	complete := true
+	for i, op := range s.ops {
+		if !s.done[i] && op.Response != 0 {
+			complete = false
+			break
+		}
+	}
+	if complete {
+		return true, nil
+	}
+
+	state := s.state()
+	if s.visited[state] {
+		return false, nil
+	}
+	if len(s.visited) >= HISTORY_SEARCH_LIMIT {
+		return false, errHistoryTooLarge
+	}
+	s.visited[state] = true
+
+	for i, op := range s.ops {
+		if op.Kind != HISTORY_WRITE || !s.enabled(i) {
+			continue
+		}
+		previous, written := s.memory[op.PageNo]
+		s.done[i] = true
+		s.memory[op.PageNo] = op.Value
+		ok, err := s.run()
+		s.done[i] = false
+		if written {
+			s.memory[op.PageNo] = previous
+		} else {
+			delete(s.memory, op.PageNo)
+		}
+		if ok || err != nil {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// Whether operation i can come next
+func (s *orderSearch) enabled(i int) bool {
+	if s.done[i] {
+		return false
+	}
+	for _, j := range s.preds[i] {
+		if !s.done[j] {
+			return false
+		}
+	}
+	return true
+}
+
+// Key of the operations done and the values of the pages
+func (s *orderSearch) state() string {
+	var key strings.Builder
+	for _, done := range s.done {
+		if done {
+			key.WriteByte('1')
+		} else {
+			key.WriteByte('0')
+		}
+	}
+	pageNos := make([]string, 0, len(s.memory))
+	for pageNo := range s.memory {
+		pageNos = append(pageNos, pageNo)
+	}
+	sort.Strings(pageNos)
+	for _, pageNo := range pageNos {
+		fmt.Fprintf(&key, "|%q=%q", pageNo, s.memory[pageNo])
+	}
+	return key.String()
+}
+
+// Shrinks a history that fails checkOrder to a subset that still fails, and that no longer fails if
+// any further read, write without a response, or group of operations with the same page and value is
+// removed. A completed write is not removed on its own, as its reads would then fail by returning a
+// value nobody wrote. Removing a read, a write without a response, or every operation
+// on a page with a given value cannot make a valid history fail, so the subset only holds violations
+// that were in the history.
+func reduceHistory(ops []HistoryOp, before beforeFunc) ([]HistoryOp, error) {
+	fails := func(subset []HistoryOp) (bool, error) {
+		ok, err := checkOrder(subset, before)
+		return !ok, err
+	}
+
+	// Drop whole (page, value) groups first, then single operations
+	type group struct{ pageNo, value string }
+	for shrunk := true; shrunk; {
+		shrunk = false
+		groups := []group{}
+		seen := map[group]bool{}
+		for _, op := range ops {
+			g := group{op.PageNo, op.Value}
+			if !seen[g] {
+				seen[g] = true
+				groups = append(groups, g)
+			}
+		}
+		for _, g := range groups {
+			subset := []HistoryOp{}
+			for _, op := range ops {
+				if op.PageNo != g.pageNo || op.Value != g.value {
+					subset = append(subset, op)
+				}
+			}
+			failing, err := fails(subset)
+			if err != nil {
+				return nil, err
+			}
+			if failing {
+				ops = subset
+				shrunk = true
+			}
+		}
+	}
+	for i := len(ops) - 1; i >= 0; i-- {
+		if ops[i].Kind == HISTORY_WRITE && ops[i].Response != 0 {
+			continue
+		}
+		subset := append(append([]HistoryOp{}, ops[:i]...), ops[i+1:]...)
+		failing, err := fails(subset)
+		if err != nil {
+			return nil, err
+		}
+		if failing {
+			ops = subset
+		}
+	}
+
+	sort.SliceStable(ops, func(i, j int) bool {
+		return ops[i].Invoke < ops[j].Invoke
+	})
+	return ops, nil
+}
@@ -0,0 +1,184 @@
+package ivy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func w(client int, pageNo string, value string, invoke int64, response int64) HistoryOp {
+	return HistoryOp{Client: client, Kind: HISTORY_WRITE, PageNo: pageNo, Value: value, Invoke: invoke, Response: response}
+}
+
+func r(client int, pageNo string, value string, invoke int64, response int64) HistoryOp {
+	return HistoryOp{Client: client, Kind: HISTORY_READ, PageNo: pageNo, Value: value, Invoke: invoke, Response: response}
+}
+
+var historyTests = []struct {
+	name         string
+	ops          []HistoryOp
+	consistent   bool
+	linearizable bool
+	// Expected when the history fails a check
+	counterexample []HistoryOp
+}{
+	{
+		name:         "empty",
+		consistent:   true,
+		linearizable: true,
+	},
+	{
+		name:         "read own write",
+		ops:          []HistoryOp{r(1, "x", "", 1, 2), w(1, "x", "a", 3, 4), r(1, "x", "a", 5, 6)},
+		consistent:   true,
+		linearizable: true,
+	},
+	{
+		name: "read overlapping a write returns either value",
+		ops: []HistoryOp{
+			w(1, "x", "a", 1, 10),
+			r(2, "x", "", 2, 3), r(2, "x", "a", 4, 5),
+			r(3, "x", "a", 2, 3),
+		},
+		consistent:   true,
+		linearizable: true,
+	},
+	{
+		name: "write without a response may have taken effect",
+		ops: []HistoryOp{
+			w(1, "x", "a", 1, 0),
+			r(2, "x", "a", 5, 6),
+		},
+		consistent:   true,
+		linearizable: true,
+	},
+	{
+		name: "write without a response may not have taken effect",
+		ops: []HistoryOp{
+			w(1, "x", "a", 1, 0),
+			r(2, "x", "", 5, 6),
+		},
+		consistent:   true,
+		linearizable: true,
+	},
+	{
+		name: "pages are linearizable independently",
+		ops: []HistoryOp{
+			w(1, "x", "a", 1, 2), w(1, "y", "b", 3, 4),
+			r(2, "y", "b", 5, 6), r(2, "x", "a", 7, 8),
+		},
+		consistent:   true,
+		linearizable: true,
+	},
+	{
+		// Ordering the read first keeps each client's order, but not real time
+		name: "stale read after a completed write",
+		ops: []HistoryOp{
+			w(1, "x", "a", 1, 2),
+			r(2, "x", "", 3, 4),
+			w(3, "y", "c", 1, 2),
+		},
+		consistent:     true,
+		counterexample: []HistoryOp{w(1, "x", "a", 1, 2), r(2, "x", "", 3, 4)},
+	},
+	{
+		name: "writes seen in different orders",
+		ops: []HistoryOp{
+			w(1, "x", "a", 1, 2), w(1, "x", "b", 3, 4),
+			r(2, "x", "b", 5, 6), r(2, "x", "a", 7, 8),
+			w(3, "y", "c", 1, 2), r(3, "y", "c", 3, 4),
+		},
+		counterexample: []HistoryOp{
+			w(1, "x", "a", 1, 2), w(1, "x", "b", 3, 4),
+			r(2, "x", "b", 5, 6), r(2, "x", "a", 7, 8),
+		},
+	},
+	{
+		name: "each client misses the other's write",
+		ops: []HistoryOp{
+			w(1, "x", "a", 1, 2), r(1, "y", "", 3, 4),
+			w(2, "y", "b", 1, 2), r(2, "x", "", 3, 4),
+		},
+		counterexample: []HistoryOp{
+			w(1, "x", "a", 1, 2), w(2, "y", "b", 1, 2),
+			r(1, "y", "", 3, 4), r(2, "x", "", 3, 4),
+		},
+	},
+	{
+		name: "read of a value never written",
+		ops: []HistoryOp{
+			w(1, "x", "a", 1, 2),
+			r(2, "x", "z", 3, 4),
+		},
+		counterexample: []HistoryOp{r(2, "x", "z", 3, 4)},
+	},
+	{
+		// On the simulated clock, operations of a client can take no time at all
+		name: "same timestamps keep the recorded order",
+		ops: []HistoryOp{
+			w(1, "x", "a", 1, 1), r(1, "x", "a", 1, 1), w(1, "x", "b", 1, 1), r(1, "x", "b", 1, 1),
+			r(2, "x", "b", 1, 2),
+		},
+		consistent:   true,
+		linearizable: true,
+	},
+	{
+		// Would pass if the read could be ordered before the write that was recorded first
+		name:           "same timestamps do not reorder a client's operations",
+		ops:            []HistoryOp{w(1, "x", "a", 1, 1), r(1, "x", "", 1, 1)},
+		counterexample: []HistoryOp{w(1, "x", "a", 1, 1), r(1, "x", "", 1, 1)},
+	},
+}
+
+func TestCheckHistory(t *testing.T) {
+	for _, test := range historyTests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := CheckHistory(test.ops)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.SequentiallyConsistent != test.consistent || result.Linearizable != test.linearizable {
+				t.Fatalf("sequentially consistent %v, linearizable %v, want %v, %v",
+					result.SequentiallyConsistent, result.Linearizable, test.consistent, test.linearizable)
+			}
+			if !reflect.DeepEqual(result.Counterexample, test.counterexample) {
+				t.Errorf("counterexample %v, want %v", result.Counterexample, test.counterexample)
+			}
+		})
+	}
+}
+
+// A counterexample passes once any read, write without a response, or all the operations on a page
+// with one value are removed from it
+func TestCounterexampleReduced(t *testing.T) {
+	without := func(ops []HistoryOp, drop func(op HistoryOp) bool) []HistoryOp {
+		subset := []HistoryOp{}
+		for _, op := range ops {
+			if !drop(op) {
+				subset = append(subset, op)
+			}
+		}
+		return subset
+	}
+	for _, test := range historyTests {
+		if test.counterexample == nil {
+			continue
+		}
+		before := realTimeBefore
+		if !test.consistent {
+			before = sameClientBefore
+		}
+		for _, removed := range test.counterexample {
+			subsets := [][]HistoryOp{without(test.counterexample, func(op HistoryOp) bool {
+				return op.PageNo == removed.PageNo && op.Value == removed.Value
+			})}
+			if removed.Kind == HISTORY_READ || removed.Response == 0 {
+				subsets = append(subsets, without(test.counterexample, func(op HistoryOp) bool { return op == removed }))
+			}
+			for _, subset := range subsets {
+				if ok, err := checkOrder(subset, before); err != nil || !ok {
+					t.Errorf("%s: counterexample still fails as %v", test.name, subset)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,6 @@
+// Package ivypb holds the protobuf schema of the messages exchanged by ivy nodes, and the code
+// generated from it, which GRPCTransport sends. Other languages can generate their own from ivy.proto
+// to talk to ivy nodes over gRPC.
+package ivypb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative ivy.proto
@@ -0,0 +1,2372 @@
+// Messages exchanged by the nodes of an ivy DSM, as sent by GRPCTransport. Each message mirrors the
+// Go struct of the same name in message.go, field by field. Enumerated values, such as Message.type,
+// are carried as the strings the Go code uses (e.g. "READ_REQUEST"), so new values need no schema change.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: ivy.proto
+
+package ivypb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Message struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// READ_REQUEST, READ_FORWARD, PAGE_SEND, ... : the constants of message.go
+	Type    string   `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Payload *Payload `protobuf:"bytes,2,opt,name=payload,proto3" json:"payload,omitempty"`
+	FromId  int64    `protobuf:"varint,3,opt,name=from_id,json=fromId,proto3" json:"from_id,omitempty"`
+	FromIp  string   `protobuf:"bytes,4,opt,name=from_ip,json=fromIp,proto3" json:"from_ip,omitempty"`
+	// Generated by the client that issued the READ_REQUEST/WRITE_REQUEST and carried by every
+	// message of that request, so the CM can deduplicate retries
+	RequestId string `protobuf:"bytes,5,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	// Set on the WRITE_REQUESTs of a transaction
+	TxnId string `protobuf:"bytes,6,opt,name=txn_id,json=txnId,proto3" json:"txn_id,omitempty"`
+	// Time the sender waits for the reply, from when the message was sent. Unset waits indefinitely.
+	Timeout       *durationpb.Duration `protobuf:"bytes,7,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Message) Reset() {
+	*x = Message{}
+	mi := &file_ivy_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Message) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Message) ProtoMessage() {}
+
+func (x *Message) ProtoReflect() protoreflect.Message {
+	mi := &file_ivy_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Message.ProtoReflect.Descriptor instead.
+func (*Message) Descriptor() ([]byte, []int) {
+	return file_ivy_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Message) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Message) GetPayload() *Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *Message) GetFromId() int64 {
+	if x != nil {
+		return x.FromId
+	}
+	return 0
+}
+
+func (x *Message) GetFromIp() string {
+	if x != nil {
+		return x.FromIp
+	}
+	return ""
+}
+
+func (x *Message) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *Message) GetTxnId() string {
+	if x != nil {
+		return x.TxnId
+	}
+	return ""
+}
+
+func (x *Message) GetTimeout() *durationpb.Duration {
+	if x != nil {
+		return x.Timeout
+	}
+	return nil
+}
+
+type Reply struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Ack   bool                   `protobuf:"varint,1,opt,name=ack,proto3" json:"ack,omitempty"`
+	// MetaData of the CM, sent to the other CM
+	Payload  map[string]*PageInfo      `protobuf:"bytes,2,rep,name=payload,proto3" json:"payload,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Requests map[string]*RequestRecord `protobuf:"bytes,3,rep,name=requests,proto3" json:"requests,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Set when a retried request had already completed
+	Done bool `protobuf:"varint,4,opt,name=done,proto3" json:"done,omitempty"`
+	// Set when the request was refused and retrying will not help
+	Err string `protobuf:"bytes,5,opt,name=err,proto3" json:"err,omitempty"`
+	// Home copy returned by HOME_FETCH, or its new version after a HOME_DIFF
+	Home *HomePage `protobuf:"bytes,6,opt,name=home,proto3" json:"home,omitempty"`
+	// Home copies, sent to the backup CM with the MetaData
+	Homes map[string]*HomePage `protobuf:"bytes,7,rep,name=homes,proto3" json:"homes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Write notices of the lock granted by LOCK_ACQUIRE
+	Notices       map[string]int64 `protobuf:"bytes,8,rep,name=notices,proto3" json:"notices,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Reply) Reset() {
+	*x = Reply{}
+	mi := &file_ivy_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Reply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Reply) ProtoMessage() {}
+
+func (x *Reply) ProtoReflect() protoreflect.Message {
+	mi := &file_ivy_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Reply.ProtoReflect.Descriptor instead.
+func (*Reply) Descriptor() ([]byte, []int) {
+	return file_ivy_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Reply) GetAck() bool {
+	if x != nil {
+		return x.Ack
+	}
+	return false
+}
+
+func (x *Reply) GetPayload() map[string]*PageInfo {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *Reply) GetRequests() map[string]*RequestRecord {
+	if x != nil {
+		return x.Requests
+	}
+	return nil
+}
+
+func (x *Reply) GetDone() bool {
+	if x != nil {
+		return x.Done
+	}
+	return false
+}
+
+func (x *Reply) GetErr() string {
+	if x != nil {
+		return x.Err
+	}
+	return ""
+}
+
+func (x *Reply) GetHome() *HomePage {
+	if x != nil {
+		return x.Home
+	}
+	return nil
+}
+
+func (x *Reply) GetHomes() map[string]*HomePage {
+	if x != nil {
+		return x.Homes
+	}
+	return nil
+}
+
+func (x *Reply) GetNotices() map[string]int64 {
+	if x != nil {
+		return x.Notices
+	}
+	return nil
+}
+
+// Only the field of the message's type is set
+type Payload struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	ReadRequest      *ReadRequest           `protobuf:"bytes,1,opt,name=read_request,json=readRequest,proto3" json:"read_request,omitempty"`
+	ReadForward      *ReadForward           `protobuf:"bytes,2,opt,name=read_forward,json=readForward,proto3" json:"read_forward,omitempty"`
+	PageSend         *PageSend              `protobuf:"bytes,3,opt,name=page_send,json=pageSend,proto3" json:"page_send,omitempty"`
+	ReadConfirmation *ReadConfirmation      `protobuf:"bytes,4,opt,name=read_confirmation,json=readConfirmation,proto3" json:"read_confirmation,omitempty"`
+	// Also carried by UPGRADE_REQUEST
+	WriteRequest           *WriteRequest           `protobuf:"bytes,5,opt,name=write_request,json=writeRequest,proto3" json:"write_request,omitempty"`
+	InvalidateCopy         *InvalidateCopy         `protobuf:"bytes,6,opt,name=invalidate_copy,json=invalidateCopy,proto3" json:"invalidate_copy,omitempty"`
+	InvalidateConfirmation *InvalidateConfirmation `protobuf:"bytes,7,opt,name=invalidate_confirmation,json=invalidateConfirmation,proto3" json:"invalidate_confirmation,omitempty"`
+	WriteForward           *WriteForward           `protobuf:"bytes,8,opt,name=write_forward,json=writeForward,proto3" json:"write_forward,omitempty"`
+	WriteConfirmation      *WriteConfirmation      `protobuf:"bytes,9,opt,name=write_confirmation,json=writeConfirmation,proto3" json:"write_confirmation,omitempty"`
+	Pulse                  *Pulse                  `protobuf:"bytes,10,opt,name=pulse,proto3" json:"pulse,omitempty"`
+	ChangeCm               *ChangeCM               `protobuf:"bytes,11,opt,name=change_cm,json=changeCm,proto3" json:"change_cm,omitempty"`
+	ImBack                 *ImBack                 `protobuf:"bytes,12,opt,name=im_back,json=imBack,proto3" json:"im_back,omitempty"`
+	UpdateCopy             *UpdateCopy             `protobuf:"bytes,13,opt,name=update_copy,json=updateCopy,proto3" json:"update_copy,omitempty"`
+	SetCoherence           *SetCoherence           `protobuf:"bytes,14,opt,name=set_coherence,json=setCoherence,proto3" json:"set_coherence,omitempty"`
+	UpgradeGrant           *UpgradeGrant           `protobuf:"bytes,15,opt,name=upgrade_grant,json=upgradeGrant,proto3" json:"upgrade_grant,omitempty"`
+	AllocatePage           *AllocatePage           `protobuf:"bytes,16,opt,name=allocate_page,json=allocatePage,proto3" json:"allocate_page,omitempty"`
+	FreePage               *FreePage               `protobuf:"bytes,17,opt,name=free_page,json=freePage,proto3" json:"free_page,omitempty"`
+	DropPage               *DropPage               `protobuf:"bytes,18,opt,name=drop_page,json=dropPage,proto3" json:"drop_page,omitempty"`
+	// Carried by LOCK_ACQUIRE, LOCK_RELEASE and LOCK_RENEW
+	Lock    *Lock    `protobuf:"bytes,19,opt,name=lock,proto3" json:"lock,omitempty"`
+	Barrier *Barrier `protobuf:"bytes,20,opt,name=barrier,proto3" json:"barrier,omitempty"`
+	// Carried by TXN_LOCK and TXN_UNLOCK
+	TxnLock       *TxnLock   `protobuf:"bytes,21,opt,name=txn_lock,json=txnLock,proto3" json:"txn_lock,omitempty"`
+	HomeFetch     *HomeFetch `protobuf:"bytes,22,opt,name=home_fetch,json=homeFetch,proto3" json:"home_fetch,omitempty"`
+	HomeDiff      *HomeDiff  `protobuf:"bytes,23,opt,name=home_diff,json=homeDiff,proto3" json:"home_diff,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Payload) Reset() {
+	*x = Payload{}
+	mi := &file_ivy_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Payload) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Payload) ProtoMessage() {}
+
+func (x *Payload) ProtoReflect() protoreflect.Message {
+	mi := &file_ivy_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Payload.ProtoReflect.Descriptor instead.
+func (*Payload) Descriptor() ([]byte, []int) {
+	return file_ivy_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Payload) GetReadRequest() *ReadRequest {
+	if x != nil {
+		return x.ReadRequest
+	}
+	return nil
+}
+
+func (x *Payload) GetReadForward() *ReadForward {
+	if x != nil {
+		return x.ReadForward
+	}
+	return nil
+}
+
+func (x *Payload) GetPageSend() *PageSend {
+	if x != nil {
+		return x.PageSend
+	}
+	return nil
+}
+
+func (x *Payload) GetReadConfirmation() *ReadConfirmation {
+	if x != nil {
+		return x.ReadConfirmation
+	}
+	return nil
+}
+
+func (x *Payload) GetWriteRequest() *WriteRequest {
+	if x != nil {
+		return x.WriteRequest
+	}
+	return nil
+}
+
+func (x *Payload) GetInvalidateCopy() *InvalidateCopy {
+	if x != nil {
+		return x.InvalidateCopy
+	}
+	return nil
+}
+
+func (x *Payload) GetInvalidateConfirmation() *InvalidateConfirmation {
+	if x != nil {
+		return x.InvalidateConfirmation
+	}
+	return nil
+}
+
+func (x *Payload) GetWriteForward() *WriteForward {
+	if x != nil {
+		return x.WriteForward
+	}
+	return nil
+}
+
+func (x *Payload) GetWriteConfirmation() *WriteConfirmation {
+	if x != nil {
+		return x.WriteConfirmation
+	}
+	return nil
+}
+
+func (x *Payload) GetPulse() *Pulse {
+	if x != nil {
+		return x.Pulse
+	}
+	return nil
+}
+
+func (x *Payload) GetChangeCm() *ChangeCM {
+	if x != nil {
+		return x.ChangeCm
+	}
+	return nil
+}
+
+func (x *Payload) GetImBack() *ImBack {
+	if x != nil {
+		return x.ImBack
+	}
+	return nil
+}
+
+func (x *Payload) GetUpdateCopy() *UpdateCopy {
+	if x != nil {
+		return x.UpdateCopy
+	}
+	return nil
+}
+
+func (x *Payload) GetSetCoherence() *SetCoherence {
+	if x != nil {
+		return x.SetCoherence
+	}
+	return nil
+}
+
+func (x *Payload) GetUpgradeGrant() *UpgradeGrant {
+	if x != nil {
+		return x.UpgradeGrant
+	}
+	return nil
+}
+
+func (x *Payload) GetAllocatePage() *AllocatePage {
+	if x != nil {
+		return x.AllocatePage
+	}
+	return nil
+}
+
+func (x *Payload) GetFreePage() *FreePage {
+	if x != nil {
+		return x.FreePage
+	}
+	return nil
+}
+
+func (x *Payload) GetDropPage() *DropPage {
+	if x != nil {
+		return x.DropPage
+	}
+	return nil
+}
+
+func (x *Payload) GetLock() *Lock {
+	if x != nil {
+		return x.Lock
+	}
+	return nil
+}
+
+func (x *Payload) GetBarrier() *Barrier {
+	if x != nil {
+		return x.Barrier
+	}
+	return nil
+}
+
+func (x *Payload) GetTxnLock() *TxnLock {
+	if x != nil {
+		return x.TxnLock
+	}
+	return nil
+}
+
+func (x *Payload) GetHomeFetch() *HomeFetch {
+	if x != nil {
+		return x.HomeFetch
+	}
+	return nil
+}
+
+func (x *Payload) GetHomeDiff() *HomeDiff {
+	if x != nil {
+		return x.HomeDiff
+	}
+	return nil
+}
+
+type Page struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Number string                 `protobuf:"bytes,1,opt,name=number,proto3" json:"number,omitempty"`
+	// PAGE_SIZE bytes once the page has been written
+	Content []byte `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	// READ, READWRITE or NIL
+	Access        string `protobuf:"bytes,3,opt,name=access,proto3" json:"access,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Page) Reset() {
+	*x = Page{}
+	mi := &file_ivy_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Page) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Page) ProtoMessage() {}
+
+func (x *Page) ProtoReflect() protoreflect.Message {
+	mi := &file_ivy_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Page.ProtoReflect.Descriptor instead.
+func (*Page) Descriptor() ([]byte, []int) {
+	return file_ivy_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Page) GetNumber() string {
+	if x != nil {
+		return x.Number
+	}
+	return ""
+}
+
+func (x *Page) GetContent() []byte {
+	if x != nil {
+		return x.Content
+	}
+	return nil
+}
+
+func (x *Page) GetAccess() string {
+	if x != nil {
+		return x.Access
+	}
+	return ""
+}
+
+type ClientPointer struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Ip            string                 `protobuf:"bytes,2,opt,name=ip,proto3" json:"ip,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ClientPointer) Reset() {
+	*x = ClientPointer{}
+	mi := &file_ivy_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClientPointer) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClientPointer) ProtoMessage() {}
+
+func (x *ClientPointer) ProtoReflect() protoreflect.Message {
+	mi := &file_ivy_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClientPointer.ProtoReflect.Descriptor instead.
+func (*ClientPointer) Descriptor() ([]byte, []int) {
+	return file_ivy_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ClientPointer) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *ClientPointer) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+type PageInfo struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Owner   *ClientPointer         `protobuf:"bytes,1,opt,name=owner,proto3" json:"owner,omitempty"`
+	CopySet []*ClientPointer       `protobuf:"bytes,2,rep,name=copy_set,json=copySet,proto3" json:"copy_set,omitempty"`
+	// "invalidate" (also when empty) or "update"
+	Coherence     string `protobuf:"bytes,3,opt,name=coherence,proto3" json:"coherence,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PageInfo) Reset() {
+	*x = PageInfo{}
+	mi := &file_ivy_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PageInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PageInfo) ProtoMessage() {}
+
+func (x *PageInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_ivy_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PageInfo.ProtoReflect.Descriptor instead.
+func (*PageInfo) Descriptor() ([]byte, []int) {
+	return file_ivy_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *PageInfo) GetOwner() *ClientPointer {
+	if x != nil {
+		return x.Owner
+	}
+	return nil
+}
+
+func (x *PageInfo) GetCopySet() []*ClientPointer {
+	if x != nil {
+		return x.CopySet
+	}
+	return nil
+}
+
+func (x *PageInfo) GetCoherence() string {
+	if x != nil {
+		return x.Coherence
+	}
+	return ""
+}
+
+type RequestRecord struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// IN_PROGRESS or DONE
+	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	Started       *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=started,proto3" json:"started,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RequestRecord) Reset() {
+	*x = RequestRecord{}
+	mi := &file_ivy_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RequestRecord) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RequestRecord) ProtoMessage() {}
+
+func (x *RequestRecord) ProtoReflect() protoreflect.Message {
+	mi := &file_ivy_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RequestRecord.ProtoReflect.Descriptor instead.
+func (*RequestRecord) Descriptor() ([]byte, []int) {
+	return file_ivy_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *RequestRecord) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *RequestRecord) GetStarted() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Started
+	}
+	return nil
+}
+
+type HomePage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Content       []byte                 `protobuf:"bytes,1,opt,name=content,proto3" json:"content,omitempty"`
+	Version       int64                  `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HomePage) Reset() {
+	*x = HomePage{}
+	mi := &file_ivy_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HomePage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HomePage) ProtoMessage() {}
+
+func (x *HomePage) ProtoReflect() protoreflect.Message {
+	mi := &file_ivy_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HomePage.ProtoReflect.Descriptor instead.
+func (*HomePage) Descriptor() ([]byte, []int) {
+	return file_ivy_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *HomePage) GetContent() []byte {
+	if x != nil {
+		return x.Content
+	}
+	return nil
+}
+
+func (x *HomePage) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type Diff struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Offset        int64                  `protobuf:"varint,1,opt,name=offset,proto3" json:"offset,omitempty"`
+	Content       []byte                 `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Diff) Reset() {
+	*x = Diff{}
+	mi := &file_ivy_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Diff) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Diff) ProtoMessage() {}
+
+func (x *Diff) ProtoReflect() protoreflect.Message {
+	mi := &file_ivy_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Diff.ProtoReflect.Descriptor instead.
+func (*Diff) Descriptor() ([]byte, []int) {
+	return file_ivy_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Diff) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *Diff) GetContent() []byte {
+	if x != nil {
+		return x.Content
+	}
+	return nil
+}
+
+type ReadRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PageNo        string                 `protobuf:"bytes,1,opt,name=page_no,json=pageNo,proto3" json:"page_no,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReadRequest) Reset() {
+	*x = ReadRequest{}
+	mi := &file_ivy_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadRequest) ProtoMessage() {}
+
+func (x *ReadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ivy_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadRequest.ProtoReflect.Descriptor instead.
+func (*ReadRequest) Descriptor() ([]byte, []int) {
+	return file_ivy_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ReadRequest) GetPageNo() string {
+	if x != nil {
+		return x.PageNo
+	}
+	return ""
+}
+
+type ReadForward struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	ReadRequesterId int64                  `protobuf:"varint,1,opt,name=read_requester_id,json=readRequesterId,proto3" json:"read_requester_id,omitempty"`
+	ReadRequesterIp string                 `protobuf:"bytes,2,opt,name=read_requester_ip,json=readRequesterIp,proto3" json:"read_requester_ip,omitempty"`
+	PageNo          string                 `protobuf:"bytes,3,opt,name=page_no,json=pageNo,proto3" json:"page_no,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ReadForward) Reset() {
+	*x = ReadForward{}
+	mi := &file_ivy_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReadForward) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadForward) ProtoMessage() {}
+
+func (x *ReadForward) ProtoReflect() protoreflect.Message {
+	mi := &file_ivy_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadForward.ProtoReflect.Descriptor instead.
+func (*ReadForward) Descriptor() ([]byte, []int) {
+	return file_ivy_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ReadForward) GetReadRequesterId() int64 {
+	if x != nil {
+		return x.ReadRequesterId
+	}
+	return 0
+}
+
+func (x *ReadForward) GetReadRequesterIp() string {
+	if x != nil {
+		return x.ReadRequesterIp
+	}
+	return ""
+}
+
+func (x *ReadForward) GetPageNo() string {
+	if x != nil {
+		return x.PageNo
+	}
+	return ""
+}
+
+type PageSend struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// READ or WRITE
+	Purpose string `protobuf:"bytes,1,opt,name=purpose,proto3" json:"purpose,omitempty"`
+	Page    *Page  `protobuf:"bytes,2,opt,name=page,proto3" json:"page,omitempty"`
+	// Travels with the page when ownership moves in DYNAMIC and IMPROVED modes
+	CopySet []*ClientPointer `protobuf:"bytes,3,rep,name=copy_set,json=copySet,proto3" json:"copy_set,omitempty"`
+	// Set on a WRITE under write-update when other clients still hold copies
+	Shared        bool `protobuf:"varint,4,opt,name=shared,proto3" json:"shared,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PageSend) Reset() {
+	*x = PageSend{}
+	mi := &file_ivy_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PageSend) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PageSend) ProtoMessage() {}
+
+func (x *PageSend) ProtoReflect() protoreflect.Message {
+	mi := &file_ivy_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PageSend.ProtoReflect.Descriptor instead.
+func (*PageSend) Descriptor() ([]byte, []int) {
+	return file_ivy_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *PageSend) GetPurpose() string {
+	if x != nil {
+		return x.Purpose
+	}
+	return ""
+}
+
+func (x *PageSend) GetPage() *Page {
+	if x != nil {
+		return x.Page
+	}
+	return nil
+}
+
+func (x *PageSend) GetCopySet() []*ClientPointer {
+	if x != nil {
+		return x.CopySet
+	}
+	return nil
+}
+
+func (x *PageSend) GetShared() bool {
+	if x != nil {
+		return x.Shared
+	}
+	return false
+}
+
+type ReadConfirmation struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	PageNumber      string                 `protobuf:"bytes,1,opt,name=page_number,json=pageNumber,proto3" json:"page_number,omitempty"`
+	ReadRequesterId int64                  `protobuf:"varint,2,opt,name=read_requester_id,json=readRequesterId,proto3" json:"read_requester_id,omitempty"`
+	ReadRequesterIp string                 `protobuf:"bytes,3,opt,name=read_requester_ip,json=readRequesterIp,proto3" json:"read_requester_ip,omitempty"`
+	SenderId        int64                  `protobuf:"varint,4,opt,name=sender_id,json=senderId,proto3" json:"sender_id,omitempty"`
+	SenderIp        string                 `protobuf:"bytes,5,opt,name=sender_ip,json=senderIp,proto3" json:"sender_ip,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ReadConfirmation) Reset() {
+	*x = ReadConfirmation{}
+	mi := &file_ivy_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReadConfirmation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadConfirmation) ProtoMessage() {}
+
+func (x *ReadConfirmation) ProtoReflect() protoreflect.Message {
+	mi := &file_ivy_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadConfirmation.ProtoReflect.Descriptor instead.
+func (*ReadConfirmation) Descriptor() ([]byte, []int) {
+	return file_ivy_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ReadConfirmation) GetPageNumber() string {
+	if x != nil {
+		return x.PageNumber
+	}
+	return ""
+}
+
+func (x *ReadConfirmation) GetReadRequesterId() int64 {
+	if x != nil {
+		return x.ReadRequesterId
+	}
+	return 0
+}
+
+func (x *ReadConfirmation) GetReadRequesterIp() string {
+	if x != nil {
+		return x.ReadRequesterIp
+	}
+	return ""
+}
+
+func (x *ReadConfirmation) GetSenderId() int64 {
+	if x != nil {
+		return x.SenderId
+	}
+	return 0
+}
+
+func (x *ReadConfirmation) GetSenderIp() string {
+	if x != nil {
+		return x.SenderIp
+	}
+	return ""
+}
+
+// Writes content at offset in the page
+type WriteRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PageNo        string                 `protobuf:"bytes,1,opt,name=page_no,json=pageNo,proto3" json:"page_no,omitempty"`
+	Offset        int64                  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	Content       []byte                 `protobuf:"bytes,3,opt,name=content,proto3" json:"content,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WriteRequest) Reset() {
+	*x = WriteRequest{}
+	mi := &file_ivy_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WriteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WriteRequest) ProtoMessage() {}
+
+func (x *WriteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ivy_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WriteRequest.ProtoReflect.Descriptor instead.
+func (*WriteRequest) Descriptor() ([]byte, []int) {
+	return file_ivy_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *WriteRequest) GetPageNo() string {
+	if x != nil {
+		return x.PageNo
+	}
+	return ""
+}
+
+func (x *WriteRequest) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *WriteRequest) GetContent() []byte {
+	if x != nil {
+		return x.Content
+	}
+	return nil
+}
+
+type InvalidateCopy struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	WriteRequesterId int64                  `protobuf:"varint,1,opt,name=write_requester_id,json=writeRequesterId,proto3" json:"write_requester_id,omitempty"`
+	PageNumber       string                 `protobuf:"bytes,2,opt,name=page_number,json=pageNumber,proto3" json:"page_number,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *InvalidateCopy) Reset() {
+	*x = InvalidateCopy{}
+	mi := &file_ivy_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InvalidateCopy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InvalidateCopy) ProtoMessage() {}
+
+func (x *InvalidateCopy) ProtoReflect() protoreflect.Message {
+	mi := &file_ivy_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InvalidateCopy.ProtoReflect.Descriptor instead.
+func (*InvalidateCopy) Descriptor() ([]byte, []int) {
+	return file_ivy_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *InvalidateCopy) GetWriteRequesterId() int64 {
+	if x != nil {
+		return x.WriteRequesterId
+	}
+	return 0
+}
+
+func (x *InvalidateCopy) GetPageNumber() string {
+	if x != nil {
+		return x.PageNumber
+	}
+	return ""
+}
+
+type InvalidateConfirmation struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	WriteRequesterId int64                  `protobuf:"varint,1,opt,name=write_requester_id,json=writeRequesterId,proto3" json:"write_requester_id,omitempty"`
+	PageNumber       string                 `protobuf:"bytes,2,opt,name=page_number,json=pageNumber,proto3" json:"page_number,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *InvalidateConfirmation) Reset() {
+	*x = InvalidateConfirmation{}
+	mi := &file_ivy_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InvalidateConfirmation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InvalidateConfirmation) ProtoMessage() {}
+
+func (x *InvalidateConfirmation) ProtoReflect() protoreflect.Message {
+	mi := &file_ivy_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InvalidateConfirmation.ProtoReflect.Descriptor instead.
+func (*InvalidateConfirmation) Descriptor() ([]byte, []int) {
+	return file_ivy_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *InvalidateConfirmation) GetWriteRequesterId() int64 {
+	if x != nil {
+		return x.WriteRequesterId
+	}
+	return 0
+}
+
+func (x *InvalidateConfirmation) GetPageNumber() string {
+	if x != nil {
+		return x.PageNumber
+	}
+	return ""
+}
+
+type WriteForward struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	WriteRequesterId int64                  `protobuf:"varint,1,opt,name=write_requester_id,json=writeRequesterId,proto3" json:"write_requester_id,omitempty"`
+	WriteRequesterIp string                 `protobuf:"bytes,2,opt,name=write_requester_ip,json=writeRequesterIp,proto3" json:"write_requester_ip,omitempty"`
+	PageNumber       string                 `protobuf:"bytes,3,opt,name=page_number,json=pageNumber,proto3" json:"page_number,omitempty"`
+	Offset           int64                  `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
+	Content          []byte                 `protobuf:"bytes,5,opt,name=content,proto3" json:"content,omitempty"`
+	Coherence        string                 `protobuf:"bytes,6,opt,name=coherence,proto3" json:"coherence,omitempty"`
+	CopySet          []*ClientPointer       `protobuf:"bytes,7,rep,name=copy_set,json=copySet,proto3" json:"copy_set,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *WriteForward) Reset() {
+	*x = WriteForward{}
+	mi := &file_ivy_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WriteForward) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WriteForward) ProtoMessage() {}
+
+func (x *WriteForward) ProtoReflect() protoreflect.Message {
+	mi := &file_ivy_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WriteForward.ProtoReflect.Descriptor instead.
+func (*WriteForward) Descriptor() ([]byte, []int) {
+	return file_ivy_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *WriteForward) GetWriteRequesterId() int64 {
+	if x != nil {
+		return x.WriteRequesterId
+	}
+	return 0
+}
+
+func (x *WriteForward) GetWriteRequesterIp() string {
+	if x != nil {
+		return x.WriteRequesterIp
+	}
+	return ""
+}
+
+func (x *WriteForward) GetPageNumber() string {
+	if x != nil {
+		return x.PageNumber
+	}
+	return ""
+}
+
+func (x *WriteForward) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *WriteForward) GetContent() []byte {
+	if x != nil {
+		return x.Content
+	}
+	return nil
+}
+
+func (x *WriteForward) GetCoherence() string {
+	if x != nil {
+		return x.Coherence
+	}
+	return ""
+}
+
+func (x *WriteForward) GetCopySet() []*ClientPointer {
+	if x != nil {
+		return x.CopySet
+	}
+	return nil
+}
+
+type WriteConfirmation struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	WriterId      int64                  `protobuf:"varint,1,opt,name=writer_id,json=writerId,proto3" json:"writer_id,omitempty"`
+	WriterIp      string                 `protobuf:"bytes,2,opt,name=writer_ip,json=writerIp,proto3" json:"writer_ip,omitempty"`
+	PageNumber    string                 `protobuf:"bytes,3,opt,name=page_number,json=pageNumber,proto3" json:"page_number,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WriteConfirmation) Reset() {
+	*x = WriteConfirmation{}
+	mi := &file_ivy_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WriteConfirmation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WriteConfirmation) ProtoMessage() {}
+
+func (x *WriteConfirmation) ProtoReflect() protoreflect.Message {
+	mi := &file_ivy_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WriteConfirmation.ProtoReflect.Descriptor instead.
+func (*WriteConfirmation) Descriptor() ([]byte, []int) {
+	return file_ivy_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *WriteConfirmation) GetWriterId() int64 {
+	if x != nil {
+		return x.WriterId
+	}
+	return 0
+}
+
+func (x *WriteConfirmation) GetWriterIp() string {
+	if x != nil {
+		return x.WriterIp
+	}
+	return ""
+}
+
+func (x *WriteConfirmation) GetPageNumber() string {
+	if x != nil {
+		return x.PageNumber
+	}
+	return ""
+}
+
+type Pulse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FromIp        string                 `protobuf:"bytes,1,opt,name=from_ip,json=fromIp,proto3" json:"from_ip,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Pulse) Reset() {
+	*x = Pulse{}
+	mi := &file_ivy_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Pulse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Pulse) ProtoMessage() {}
+
+func (x *Pulse) ProtoReflect() protoreflect.Message {
+	mi := &file_ivy_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Pulse.ProtoReflect.Descriptor instead.
+func (*Pulse) Descriptor() ([]byte, []int) {
+	return file_ivy_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *Pulse) GetFromIp() string {
+	if x != nil {
+		return x.FromIp
+	}
+	return ""
+}
+
+type ChangeCM struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NewCmIp       string                 `protobuf:"bytes,1,opt,name=new_cm_ip,json=newCmIp,proto3" json:"new_cm_ip,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChangeCM) Reset() {
+	*x = ChangeCM{}
+	mi := &file_ivy_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChangeCM) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChangeCM) ProtoMessage() {}
+
+func (x *ChangeCM) ProtoReflect() protoreflect.Message {
+	mi := &file_ivy_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChangeCM.ProtoReflect.Descriptor instead.
+func (*ChangeCM) Descriptor() ([]byte, []int) {
+	return file_ivy_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ChangeCM) GetNewCmIp() string {
+	if x != nil {
+		return x.NewCmIp
+	}
+	return ""
+}
+
+type ImBack struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CmIp          string                 `protobuf:"bytes,1,opt,name=cm_ip,json=cmIp,proto3" json:"cm_ip,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImBack) Reset() {
+	*x = ImBack{}
+	mi := &file_ivy_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImBack) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImBack) ProtoMessage() {}
+
+func (x *ImBack) ProtoReflect() protoreflect.Message {
+	mi := &file_ivy_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImBack.ProtoReflect.Descriptor instead.
+func (*ImBack) Descriptor() ([]byte, []int) {
+	return file_ivy_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *ImBack) GetCmIp() string {
+	if x != nil {
+		return x.CmIp
+	}
+	return ""
+}
+
+// Write to apply to a copy under write-update
+type UpdateCopy struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PageNumber    string                 `protobuf:"bytes,1,opt,name=page_number,json=pageNumber,proto3" json:"page_number,omitempty"`
+	Offset        int64                  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	Content       []byte                 `protobuf:"bytes,3,opt,name=content,proto3" json:"content,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateCopy) Reset() {
+	*x = UpdateCopy{}
+	mi := &file_ivy_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateCopy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateCopy) ProtoMessage() {}
+
+func (x *UpdateCopy) ProtoReflect() protoreflect.Message {
+	mi := &file_ivy_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateCopy.ProtoReflect.Descriptor instead.
+func (*UpdateCopy) Descriptor() ([]byte, []int) {
+	return file_ivy_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *UpdateCopy) GetPageNumber() string {
+	if x != nil {
+		return x.PageNumber
+	}
+	return ""
+}
+
+func (x *UpdateCopy) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *UpdateCopy) GetContent() []byte {
+	if x != nil {
+		return x.Content
+	}
+	return nil
+}
+
+type SetCoherence struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PageNo        string                 `protobuf:"bytes,1,opt,name=page_no,json=pageNo,proto3" json:"page_no,omitempty"`
+	Policy        string                 `protobuf:"bytes,2,opt,name=policy,proto3" json:"policy,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetCoherence) Reset() {
+	*x = SetCoherence{}
+	mi := &file_ivy_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetCoherence) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetCoherence) ProtoMessage() {}
+
+func (x *SetCoherence) ProtoReflect() protoreflect.Message {
+	mi := &file_ivy_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetCoherence.ProtoReflect.Descriptor instead.
+func (*SetCoherence) Descriptor() ([]byte, []int) {
+	return file_ivy_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *SetCoherence) GetPageNo() string {
+	if x != nil {
+		return x.PageNo
+	}
+	return ""
+}
+
+func (x *SetCoherence) GetPolicy() string {
+	if x != nil {
+		return x.Policy
+	}
+	return ""
+}
+
+// Grants ownership to a client upgrading its READ copy. Offset/content is the requester's own write.
+type UpgradeGrant struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PageNumber    string                 `protobuf:"bytes,1,opt,name=page_number,json=pageNumber,proto3" json:"page_number,omitempty"`
+	Offset        int64                  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	Content       []byte                 `protobuf:"bytes,3,opt,name=content,proto3" json:"content,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpgradeGrant) Reset() {
+	*x = UpgradeGrant{}
+	mi := &file_ivy_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpgradeGrant) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpgradeGrant) ProtoMessage() {}
+
+func (x *UpgradeGrant) ProtoReflect() protoreflect.Message {
+	mi := &file_ivy_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpgradeGrant.ProtoReflect.Descriptor instead.
+func (*UpgradeGrant) Descriptor() ([]byte, []int) {
+	return file_ivy_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *UpgradeGrant) GetPageNumber() string {
+	if x != nil {
+		return x.PageNumber
+	}
+	return ""
+}
+
+func (x *UpgradeGrant) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *UpgradeGrant) GetContent() []byte {
+	if x != nil {
+		return x.Content
+	}
+	return nil
+}
+
+type AllocatePage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PageNo        string                 `protobuf:"bytes,1,opt,name=page_no,json=pageNo,proto3" json:"page_no,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AllocatePage) Reset() {
+	*x = AllocatePage{}
+	mi := &file_ivy_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AllocatePage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AllocatePage) ProtoMessage() {}
+
+func (x *AllocatePage) ProtoReflect() protoreflect.Message {
+	mi := &file_ivy_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AllocatePage.ProtoReflect.Descriptor instead.
+func (*AllocatePage) Descriptor() ([]byte, []int) {
+	return file_ivy_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *AllocatePage) GetPageNo() string {
+	if x != nil {
+		return x.PageNo
+	}
+	return ""
+}
+
+type FreePage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PageNo        string                 `protobuf:"bytes,1,opt,name=page_no,json=pageNo,proto3" json:"page_no,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FreePage) Reset() {
+	*x = FreePage{}
+	mi := &file_ivy_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FreePage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FreePage) ProtoMessage() {}
+
+func (x *FreePage) ProtoReflect() protoreflect.Message {
+	mi := &file_ivy_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FreePage.ProtoReflect.Descriptor instead.
+func (*FreePage) Descriptor() ([]byte, []int) {
+	return file_ivy_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *FreePage) GetPageNo() string {
+	if x != nil {
+		return x.PageNo
+	}
+	return ""
+}
+
+// Sent by the CM to the owner of a page being freed
+type DropPage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PageNumber    string                 `protobuf:"bytes,1,opt,name=page_number,json=pageNumber,proto3" json:"page_number,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DropPage) Reset() {
+	*x = DropPage{}
+	mi := &file_ivy_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DropPage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DropPage) ProtoMessage() {}
+
+func (x *DropPage) ProtoReflect() protoreflect.Message {
+	mi := &file_ivy_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DropPage.ProtoReflect.Descriptor instead.
+func (*DropPage) Descriptor() ([]byte, []int) {
+	return file_ivy_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *DropPage) GetPageNumber() string {
+	if x != nil {
+		return x.PageNumber
+	}
+	return ""
+}
+
+// The hold is identified by the message's request_id
+type Lock struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Name   string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Shared bool                   `protobuf:"varint,2,opt,name=shared,proto3" json:"shared,omitempty"`
+	// Write notices left by a LOCK_RELEASE under release consistency: version of each page written
+	Notices       map[string]int64 `protobuf:"bytes,3,rep,name=notices,proto3" json:"notices,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Lock) Reset() {
+	*x = Lock{}
+	mi := &file_ivy_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Lock) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Lock) ProtoMessage() {}
+
+func (x *Lock) ProtoReflect() protoreflect.Message {
+	mi := &file_ivy_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Lock.ProtoReflect.Descriptor instead.
+func (*Lock) Descriptor() ([]byte, []int) {
+	return file_ivy_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *Lock) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Lock) GetShared() bool {
+	if x != nil {
+		return x.Shared
+	}
+	return false
+}
+
+func (x *Lock) GetNotices() map[string]int64 {
+	if x != nil {
+		return x.Notices
+	}
+	return nil
+}
+
+// The transaction is identified by the message's request_id
+type TxnLock struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	PageNo string                 `protobuf:"bytes,1,opt,name=page_no,json=pageNo,proto3" json:"page_no,omitempty"`
+	// How long the manager waits for the page lock before the transaction aborts
+	Timeout       *durationpb.Duration `protobuf:"bytes,2,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TxnLock) Reset() {
+	*x = TxnLock{}
+	mi := &file_ivy_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TxnLock) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TxnLock) ProtoMessage() {}
+
+func (x *TxnLock) ProtoReflect() protoreflect.Message {
+	mi := &file_ivy_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TxnLock.ProtoReflect.Descriptor instead.
+func (*TxnLock) Descriptor() ([]byte, []int) {
+	return file_ivy_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *TxnLock) GetPageNo() string {
+	if x != nil {
+		return x.PageNo
+	}
+	return ""
+}
+
+func (x *TxnLock) GetTimeout() *durationpb.Duration {
+	if x != nil {
+		return x.Timeout
+	}
+	return nil
+}
+
+type HomeFetch struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PageNo        string                 `protobuf:"bytes,1,opt,name=page_no,json=pageNo,proto3" json:"page_no,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HomeFetch) Reset() {
+	*x = HomeFetch{}
+	mi := &file_ivy_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HomeFetch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HomeFetch) ProtoMessage() {}
+
+func (x *HomeFetch) ProtoReflect() protoreflect.Message {
+	mi := &file_ivy_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HomeFetch.ProtoReflect.Descriptor instead.
+func (*HomeFetch) Descriptor() ([]byte, []int) {
+	return file_ivy_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *HomeFetch) GetPageNo() string {
+	if x != nil {
+		return x.PageNo
+	}
+	return ""
+}
+
+// Writes made to a page since the last release, in order
+type HomeDiff struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PageNo        string                 `protobuf:"bytes,1,opt,name=page_no,json=pageNo,proto3" json:"page_no,omitempty"`
+	Diffs         []*Diff                `protobuf:"bytes,2,rep,name=diffs,proto3" json:"diffs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HomeDiff) Reset() {
+	*x = HomeDiff{}
+	mi := &file_ivy_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HomeDiff) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HomeDiff) ProtoMessage() {}
+
+func (x *HomeDiff) ProtoReflect() protoreflect.Message {
+	mi := &file_ivy_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HomeDiff.ProtoReflect.Descriptor instead.
+func (*HomeDiff) Descriptor() ([]byte, []int) {
+	return file_ivy_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *HomeDiff) GetPageNo() string {
+	if x != nil {
+		return x.PageNo
+	}
+	return ""
+}
+
+func (x *HomeDiff) GetDiffs() []*Diff {
+	if x != nil {
+		return x.Diffs
+	}
+	return nil
+}
+
+// Arrival at barrier name, which is released once n clients have arrived
+type Barrier struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	N             int64                  `protobuf:"varint,2,opt,name=n,proto3" json:"n,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Barrier) Reset() {
+	*x = Barrier{}
+	mi := &file_ivy_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Barrier) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Barrier) ProtoMessage() {}
+
+func (x *Barrier) ProtoReflect() protoreflect.Message {
+	mi := &file_ivy_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Barrier.ProtoReflect.Descriptor instead.
+func (*Barrier) Descriptor() ([]byte, []int) {
+	return file_ivy_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *Barrier) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Barrier) GetN() int64 {
+	if x != nil {
+		return x.N
+	}
+	return 0
+}
+
+var File_ivy_proto protoreflect.FileDescriptor
+
+const file_ivy_proto_rawDesc = "" +
+	"\n" +
+	"\tivy.proto\x12\x03ivy\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xe2\x01\n" +
+	"\aMessage\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12&\n" +
+	"\apayload\x18\x02 \x01(\v2\f.ivy.PayloadR\apayload\x12\x17\n" +
+	"\afrom_id\x18\x03 \x01(\x03R\x06fromId\x12\x17\n" +
+	"\afrom_ip\x18\x04 \x01(\tR\x06fromIp\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x05 \x01(\tR\trequestId\x12\x15\n" +
+	"\x06txn_id\x18\x06 \x01(\tR\x05txnId\x123\n" +
+	"\atimeout\x18\a \x01(\v2\x19.google.protobuf.DurationR\atimeout\"\xcc\x04\n" +
+	"\x05Reply\x12\x10\n" +
+	"\x03ack\x18\x01 \x01(\bR\x03ack\x121\n" +
+	"\apayload\x18\x02 \x03(\v2\x17.ivy.Reply.PayloadEntryR\apayload\x124\n" +
+	"\brequests\x18\x03 \x03(\v2\x18.ivy.Reply.RequestsEntryR\brequests\x12\x12\n" +
+	"\x04done\x18\x04 \x01(\bR\x04done\x12\x10\n" +
+	"\x03err\x18\x05 \x01(\tR\x03err\x12!\n" +
+	"\x04home\x18\x06 \x01(\v2\r.ivy.HomePageR\x04home\x12+\n" +
+	"\x05homes\x18\a \x03(\v2\x15.ivy.Reply.HomesEntryR\x05homes\x121\n" +
+	"\anotices\x18\b \x03(\v2\x17.ivy.Reply.NoticesEntryR\anotices\x1aI\n" +
+	"\fPayloadEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12#\n" +
+	"\x05value\x18\x02 \x01(\v2\r.ivy.PageInfoR\x05value:\x028\x01\x1aO\n" +
+	"\rRequestsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12(\n" +
+	"\x05value\x18\x02 \x01(\v2\x12.ivy.RequestRecordR\x05value:\x028\x01\x1aG\n" +
+	"\n" +
+	"HomesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12#\n" +
+	"\x05value\x18\x02 \x01(\v2\r.ivy.HomePageR\x05value:\x028\x01\x1a:\n" +
+	"\fNoticesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\"\x9f\t\n" +
+	"\aPayload\x123\n" +
+	"\fread_request\x18\x01 \x01(\v2\x10.ivy.ReadRequestR\vreadRequest\x123\n" +
+	"\fread_forward\x18\x02 \x01(\v2\x10.ivy.ReadForwardR\vreadForward\x12*\n" +
+	"\tpage_send\x18\x03 \x01(\v2\r.ivy.PageSendR\bpageSend\x12B\n" +
+	"\x11read_confirmation\x18\x04 \x01(\v2\x15.ivy.ReadConfirmationR\x10readConfirmation\x126\n" +
+	"\rwrite_request\x18\x05 \x01(\v2\x11.ivy.WriteRequestR\fwriteRequest\x12<\n" +
+	"\x0finvalidate_copy\x18\x06 \x01(\v2\x13.ivy.InvalidateCopyR\x0einvalidateCopy\x12T\n" +
+	"\x17invalidate_confirmation\x18\a \x01(\v2\x1b.ivy.InvalidateConfirmationR\x16invalidateConfirmation\x126\n" +
+	"\rwrite_forward\x18\b \x01(\v2\x11.ivy.WriteForwardR\fwriteForward\x12E\n" +
+	"\x12write_confirmation\x18\t \x01(\v2\x16.ivy.WriteConfirmationR\x11writeConfirmation\x12 \n" +
+	"\x05pulse\x18\n" +
+	" \x01(\v2\n" +
+	".ivy.PulseR\x05pulse\x12*\n" +
+	"\tchange_cm\x18\v \x01(\v2\r.ivy.ChangeCMR\bchangeCm\x12$\n" +
+	"\aim_back\x18\f \x01(\v2\v.ivy.ImBackR\x06imBack\x120\n" +
+	"\vupdate_copy\x18\r \x01(\v2\x0f.ivy.UpdateCopyR\n" +
+	"updateCopy\x126\n" +
+	"\rset_coherence\x18\x0e \x01(\v2\x11.ivy.SetCoherenceR\fsetCoherence\x126\n" +
+	"\rupgrade_grant\x18\x0f \x01(\v2\x11.ivy.UpgradeGrantR\fupgradeGrant\x126\n" +
+	"\rallocate_page\x18\x10 \x01(\v2\x11.ivy.AllocatePageR\fallocatePage\x12*\n" +
+	"\tfree_page\x18\x11 \x01(\v2\r.ivy.FreePageR\bfreePage\x12*\n" +
+	"\tdrop_page\x18\x12 \x01(\v2\r.ivy.DropPageR\bdropPage\x12\x1d\n" +
+	"\x04lock\x18\x13 \x01(\v2\t.ivy.LockR\x04lock\x12&\n" +
+	"\abarrier\x18\x14 \x01(\v2\f.ivy.BarrierR\abarrier\x12'\n" +
+	"\btxn_lock\x18\x15 \x01(\v2\f.ivy.TxnLockR\atxnLock\x12-\n" +
+	"\n" +
+	"home_fetch\x18\x16 \x01(\v2\x0e.ivy.HomeFetchR\thomeFetch\x12*\n" +
+	"\thome_diff\x18\x17 \x01(\v2\r.ivy.HomeDiffR\bhomeDiff\"P\n" +
+	"\x04Page\x12\x16\n" +
+	"\x06number\x18\x01 \x01(\tR\x06number\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\fR\acontent\x12\x16\n" +
+	"\x06access\x18\x03 \x01(\tR\x06access\"/\n" +
+	"\rClientPointer\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x0e\n" +
+	"\x02ip\x18\x02 \x01(\tR\x02ip\"\x81\x01\n" +
+	"\bPageInfo\x12(\n" +
+	"\x05owner\x18\x01 \x01(\v2\x12.ivy.ClientPointerR\x05owner\x12-\n" +
+	"\bcopy_set\x18\x02 \x03(\v2\x12.ivy.ClientPointerR\acopySet\x12\x1c\n" +
+	"\tcoherence\x18\x03 \x01(\tR\tcoherence\"]\n" +
+	"\rRequestRecord\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\x124\n" +
+	"\astarted\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\astarted\">\n" +
+	"\bHomePage\x12\x18\n" +
+	"\acontent\x18\x01 \x01(\fR\acontent\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\x03R\aversion\"8\n" +
+	"\x04Diff\x12\x16\n" +
+	"\x06offset\x18\x01 \x01(\x03R\x06offset\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\fR\acontent\"&\n" +
+	"\vReadRequest\x12\x17\n" +
+	"\apage_no\x18\x01 \x01(\tR\x06pageNo\"~\n" +
+	"\vReadForward\x12*\n" +
+	"\x11read_requester_id\x18\x01 \x01(\x03R\x0freadRequesterId\x12*\n" +
+	"\x11read_requester_ip\x18\x02 \x01(\tR\x0freadRequesterIp\x12\x17\n" +
+	"\apage_no\x18\x03 \x01(\tR\x06pageNo\"\x8a\x01\n" +
+	"\bPageSend\x12\x18\n" +
+	"\apurpose\x18\x01 \x01(\tR\apurpose\x12\x1d\n" +
+	"\x04page\x18\x02 \x01(\v2\t.ivy.PageR\x04page\x12-\n" +
+	"\bcopy_set\x18\x03 \x03(\v2\x12.ivy.ClientPointerR\acopySet\x12\x16\n" +
+	"\x06shared\x18\x04 \x01(\bR\x06shared\"\xc5\x01\n" +
+	"\x10ReadConfirmation\x12\x1f\n" +
+	"\vpage_number\x18\x01 \x01(\tR\n" +
+	"pageNumber\x12*\n" +
+	"\x11read_requester_id\x18\x02 \x01(\x03R\x0freadRequesterId\x12*\n" +
+	"\x11read_requester_ip\x18\x03 \x01(\tR\x0freadRequesterIp\x12\x1b\n" +
+	"\tsender_id\x18\x04 \x01(\x03R\bsenderId\x12\x1b\n" +
+	"\tsender_ip\x18\x05 \x01(\tR\bsenderIp\"Y\n" +
+	"\fWriteRequest\x12\x17\n" +
+	"\apage_no\x18\x01 \x01(\tR\x06pageNo\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x03R\x06offset\x12\x18\n" +
+	"\acontent\x18\x03 \x01(\fR\acontent\"_\n" +
+	"\x0eInvalidateCopy\x12,\n" +
+	"\x12write_requester_id\x18\x01 \x01(\x03R\x10writeRequesterId\x12\x1f\n" +
+	"\vpage_number\x18\x02 \x01(\tR\n" +
+	"pageNumber\"g\n" +
+	"\x16InvalidateConfirmation\x12,\n" +
+	"\x12write_requester_id\x18\x01 \x01(\x03R\x10writeRequesterId\x12\x1f\n" +
+	"\vpage_number\x18\x02 \x01(\tR\n" +
+	"pageNumber\"\x8a\x02\n" +
+	"\fWriteForward\x12,\n" +
+	"\x12write_requester_id\x18\x01 \x01(\x03R\x10writeRequesterId\x12,\n" +
+	"\x12write_requester_ip\x18\x02 \x01(\tR\x10writeRequesterIp\x12\x1f\n" +
+	"\vpage_number\x18\x03 \x01(\tR\n" +
+	"pageNumber\x12\x16\n" +
+	"\x06offset\x18\x04 \x01(\x03R\x06offset\x12\x18\n" +
+	"\acontent\x18\x05 \x01(\fR\acontent\x12\x1c\n" +
+	"\tcoherence\x18\x06 \x01(\tR\tcoherence\x12-\n" +
+	"\bcopy_set\x18\a \x03(\v2\x12.ivy.ClientPointerR\acopySet\"n\n" +
+	"\x11WriteConfirmation\x12\x1b\n" +
+	"\twriter_id\x18\x01 \x01(\x03R\bwriterId\x12\x1b\n" +
+	"\twriter_ip\x18\x02 \x01(\tR\bwriterIp\x12\x1f\n" +
+	"\vpage_number\x18\x03 \x01(\tR\n" +
+	"pageNumber\" \n" +
+	"\x05Pulse\x12\x17\n" +
+	"\afrom_ip\x18\x01 \x01(\tR\x06fromIp\"&\n" +
+	"\bChangeCM\x12\x1a\n" +
+	"\tnew_cm_ip\x18\x01 \x01(\tR\anewCmIp\"\x1d\n" +
+	"\x06ImBack\x12\x13\n" +
+	"\x05cm_ip\x18\x01 \x01(\tR\x04cmIp\"_\n" +
+	"\n" +
+	"UpdateCopy\x12\x1f\n" +
+	"\vpage_number\x18\x01 \x01(\tR\n" +
+	"pageNumber\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x03R\x06offset\x12\x18\n" +
+	"\acontent\x18\x03 \x01(\fR\acontent\"?\n" +
+	"\fSetCoherence\x12\x17\n" +
+	"\apage_no\x18\x01 \x01(\tR\x06pageNo\x12\x16\n" +
+	"\x06policy\x18\x02 \x01(\tR\x06policy\"a\n" +
+	"\fUpgradeGrant\x12\x1f\n" +
+	"\vpage_number\x18\x01 \x01(\tR\n" +
+	"pageNumber\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x03R\x06offset\x12\x18\n" +
+	"\acontent\x18\x03 \x01(\fR\acontent\"'\n" +
+	"\fAllocatePage\x12\x17\n" +
+	"\apage_no\x18\x01 \x01(\tR\x06pageNo\"#\n" +
+	"\bFreePage\x12\x17\n" +
+	"\apage_no\x18\x01 \x01(\tR\x06pageNo\"+\n" +
+	"\bDropPage\x12\x1f\n" +
+	"\vpage_number\x18\x01 \x01(\tR\n" +
+	"pageNumber\"\xa0\x01\n" +
+	"\x04Lock\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x16\n" +
+	"\x06shared\x18\x02 \x01(\bR\x06shared\x120\n" +
+	"\anotices\x18\x03 \x03(\v2\x16.ivy.Lock.NoticesEntryR\anotices\x1a:\n" +
+	"\fNoticesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\"W\n" +
+	"\aTxnLock\x12\x17\n" +
+	"\apage_no\x18\x01 \x01(\tR\x06pageNo\x123\n" +
+	"\atimeout\x18\x02 \x01(\v2\x19.google.protobuf.DurationR\atimeout\"$\n" +
+	"\tHomeFetch\x12\x17\n" +
+	"\apage_no\x18\x01 \x01(\tR\x06pageNo\"D\n" +
+	"\bHomeDiff\x12\x17\n" +
+	"\apage_no\x18\x01 \x01(\tR\x06pageNo\x12\x1f\n" +
+	"\x05diffs\x18\x02 \x03(\v2\t.ivy.DiffR\x05diffs\"+\n" +
+	"\aBarrier\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\f\n" +
+	"\x01n\x18\x02 \x01(\x03R\x01n2;\n" +
+	"\x06Client\x121\n" +
+	"\x15HandleIncomingMessage\x12\f.ivy.Message\x1a\n" +
+	".ivy.Reply2C\n" +
+	"\x0eCentralManager\x121\n" +
+	"\x15HandleIncomingMessage\x12\f.ivy.Message\x1a\n" +
+	".ivy.ReplyB\x1cZ\x1agithub.com/s4nat/ivy/ivypbb\x06proto3"
+
+var (
+	file_ivy_proto_rawDescOnce sync.Once
+	file_ivy_proto_rawDescData []byte
+)
+
+func file_ivy_proto_rawDescGZIP() []byte {
+	file_ivy_proto_rawDescOnce.Do(func() {
+		file_ivy_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_ivy_proto_rawDesc), len(file_ivy_proto_rawDesc)))
+	})
+	return file_ivy_proto_rawDescData
+}
+
+var file_ivy_proto_msgTypes = make([]protoimpl.MessageInfo, 37)
+var file_ivy_proto_goTypes = []any{
+	(*Message)(nil),                // 0: ivy.Message
+	(*Reply)(nil),                  // 1: ivy.Reply
+	(*Payload)(nil),                // 2: ivy.Payload
+	(*Page)(nil),                   // 3: ivy.Page
+	(*ClientPointer)(nil),          // 4: ivy.ClientPointer
+	(*PageInfo)(nil),               // 5: ivy.PageInfo
+	(*RequestRecord)(nil),          // 6: ivy.RequestRecord
+	(*HomePage)(nil),               // 7: ivy.HomePage
+	(*Diff)(nil),                   // 8: ivy.Diff
+	(*ReadRequest)(nil),            // 9: ivy.ReadRequest
+	(*ReadForward)(nil),            // 10: ivy.ReadForward
+	(*PageSend)(nil),               // 11: ivy.PageSend
+	(*ReadConfirmation)(nil),       // 12: ivy.ReadConfirmation
+	(*WriteRequest)(nil),           // 13: ivy.WriteRequest
+	(*InvalidateCopy)(nil),         // 14: ivy.InvalidateCopy
+	(*InvalidateConfirmation)(nil), // 15: ivy.InvalidateConfirmation
+	(*WriteForward)(nil),           // 16: ivy.WriteForward
+	(*WriteConfirmation)(nil),      // 17: ivy.WriteConfirmation
+	(*Pulse)(nil),                  // 18: ivy.Pulse
+	(*ChangeCM)(nil),               // 19: ivy.ChangeCM
+	(*ImBack)(nil),                 // 20: ivy.ImBack
+	(*UpdateCopy)(nil),             // 21: ivy.UpdateCopy
+	(*SetCoherence)(nil),           // 22: ivy.SetCoherence
+	(*UpgradeGrant)(nil),           // 23: ivy.UpgradeGrant
+	(*AllocatePage)(nil),           // 24: ivy.AllocatePage
+	(*FreePage)(nil),               // 25: ivy.FreePage
+	(*DropPage)(nil),               // 26: ivy.DropPage
+	(*Lock)(nil),                   // 27: ivy.Lock
+	(*TxnLock)(nil),                // 28: ivy.TxnLock
+	(*HomeFetch)(nil),              // 29: ivy.HomeFetch
+	(*HomeDiff)(nil),               // 30: ivy.HomeDiff
+	(*Barrier)(nil),                // 31: ivy.Barrier
+	nil,                            // 32: ivy.Reply.PayloadEntry
+	nil,                            // 33: ivy.Reply.RequestsEntry
+	nil,                            // 34: ivy.Reply.HomesEntry
+	nil,                            // 35: ivy.Reply.NoticesEntry
+	nil,                            // 36: ivy.Lock.NoticesEntry
+	(*durationpb.Duration)(nil),    // 37: google.protobuf.Duration
+	(*timestamppb.Timestamp)(nil),  // 38: google.protobuf.Timestamp
+}
+var file_ivy_proto_depIdxs = []int32{
+	2,  // 0: ivy.Message.payload:type_name -> ivy.Payload
+	37, // 1: ivy.Message.timeout:type_name -> google.protobuf.Duration
+	32, // 2: ivy.Reply.payload:type_name -> ivy.Reply.PayloadEntry
+	33, // 3: ivy.Reply.requests:type_name -> ivy.Reply.RequestsEntry
+	7,  // 4: ivy.Reply.home:type_name -> ivy.HomePage
+	34, // 5: ivy.Reply.homes:type_name -> ivy.Reply.HomesEntry
+	35, // 6: ivy.Reply.notices:type_name -> ivy.Reply.NoticesEntry
+	9,  // 7: ivy.Payload.read_request:type_name -> ivy.ReadRequest
+	10, // 8: ivy.Payload.read_forward:type_name -> ivy.ReadForward
+	11, // 9: ivy.Payload.page_send:type_name -> ivy.PageSend
+	12, // 10: ivy.Payload.read_confirmation:type_name -> ivy.ReadConfirmation
+	13, // 11: ivy.Payload.write_request:type_name -> ivy.WriteRequest
+	14, // 12: ivy.Payload.invalidate_copy:type_name -> ivy.InvalidateCopy
+	15, // 13: ivy.Payload.invalidate_confirmation:type_name -> ivy.InvalidateConfirmation
+	16, // 14: ivy.Payload.write_forward:type_name -> ivy.WriteForward
+	17, // 15: ivy.Payload.write_confirmation:type_name -> ivy.WriteConfirmation
+	18, // 16: ivy.Payload.pulse:type_name -> ivy.Pulse
+	19, // 17: ivy.Payload.change_cm:type_name -> ivy.ChangeCM
+	20, // 18: ivy.Payload.im_back:type_name -> ivy.ImBack
+	21, // 19: ivy.Payload.update_copy:type_name -> ivy.UpdateCopy
+	22, // 20: ivy.Payload.set_coherence:type_name -> ivy.SetCoherence
+	23, // 21: ivy.Payload.upgrade_grant:type_name -> ivy.UpgradeGrant
+	24, // 22: ivy.Payload.allocate_page:type_name -> ivy.AllocatePage
+	25, // 23: ivy.Payload.free_page:type_name -> ivy.FreePage
+	26, // 24: ivy.Payload.drop_page:type_name -> ivy.DropPage
+	27, // 25: ivy.Payload.lock:type_name -> ivy.Lock
+	31, // 26: ivy.Payload.barrier:type_name -> ivy.Barrier
+	28, // 27: ivy.Payload.txn_lock:type_name -> ivy.TxnLock
+	29, // 28: ivy.Payload.home_fetch:type_name -> ivy.HomeFetch
+	30, // 29: ivy.Payload.home_diff:type_name -> ivy.HomeDiff
+	4,  // 30: ivy.PageInfo.owner:type_name -> ivy.ClientPointer
+	4,  // 31: ivy.PageInfo.copy_set:type_name -> ivy.ClientPointer
+	38, // 32: ivy.RequestRecord.started:type_name -> google.protobuf.Timestamp
+	3,  // 33: ivy.PageSend.page:type_name -> ivy.Page
+	4,  // 34: ivy.PageSend.copy_set:type_name -> ivy.ClientPointer
+	4,  // 35: ivy.WriteForward.copy_set:type_name -> ivy.ClientPointer
+	36, // 36: ivy.Lock.notices:type_name -> ivy.Lock.NoticesEntry
+	37, // 37: ivy.TxnLock.timeout:type_name -> google.protobuf.Duration
+	8,  // 38: ivy.HomeDiff.diffs:type_name -> ivy.Diff
+	5,  // 39: ivy.Reply.PayloadEntry.value:type_name -> ivy.PageInfo
+	6,  // 40: ivy.Reply.RequestsEntry.value:type_name -> ivy.RequestRecord
+	7,  // 41: ivy.Reply.HomesEntry.value:type_name -> ivy.HomePage
+	0,  // 42: ivy.Client.HandleIncomingMessage:input_type -> ivy.Message
+	0,  // 43: ivy.CentralManager.HandleIncomingMessage:input_type -> ivy.Message
+	1,  // 44: ivy.Client.HandleIncomingMessage:output_type -> ivy.Reply
+	1,  // 45: ivy.CentralManager.HandleIncomingMessage:output_type -> ivy.Reply
+	44, // [44:46] is the sub-list for method output_type
+	42, // [42:44] is the sub-list for method input_type
+	42, // [42:42] is the sub-list for extension type_name
+	42, // [42:42] is the sub-list for extension extendee
+	0,  // [0:42] is the sub-list for field type_name
+}
+
+func init() { file_ivy_proto_init() }
+func file_ivy_proto_init() {
+	if File_ivy_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_ivy_proto_rawDesc), len(file_ivy_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   37,
+			NumExtensions: 0,
+			NumServices:   2,
+		},
+		GoTypes:           file_ivy_proto_goTypes,
+		DependencyIndexes: file_ivy_proto_depIdxs,
+		MessageInfos:      file_ivy_proto_msgTypes,
+	}.Build()
+	File_ivy_proto = out.File
+	file_ivy_proto_goTypes = nil
+	file_ivy_proto_depIdxs = nil
+}
@@ -0,0 +1,235 @@
+// Messages exchanged by the nodes of an ivy DSM, as sent by GRPCTransport. Each message mirrors the
+// Go struct of the same name in message.go, field by field. Enumerated values, such as Message.type,
+// are carried as the strings the Go code uses (e.g. "READ_REQUEST"), so new values need no schema change.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: ivy.proto
+
+package ivypb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Client_HandleIncomingMessage_FullMethodName = "/ivy.Client/HandleIncomingMessage"
+)
+
+// ClientClient is the client API for Client service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Messages to a Client
+type ClientClient interface {
+	HandleIncomingMessage(ctx context.Context, in *Message, opts ...grpc.CallOption) (*Reply, error)
+}
+
+type clientClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewClientClient(cc grpc.ClientConnInterface) ClientClient {
+	return &clientClient{cc}
+}
+
+func (c *clientClient) HandleIncomingMessage(ctx context.Context, in *Message, opts ...grpc.CallOption) (*Reply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Reply)
+	err := c.cc.Invoke(ctx, Client_HandleIncomingMessage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ClientServer is the server API for Client service.
+// All implementations must embed UnimplementedClientServer
+// for forward compatibility.
+//
+// Messages to a Client
+type ClientServer interface {
+	HandleIncomingMessage(context.Context, *Message) (*Reply, error)
+	mustEmbedUnimplementedClientServer()
+}
+
+// UnimplementedClientServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedClientServer struct{}
+
+func (UnimplementedClientServer) HandleIncomingMessage(context.Context, *Message) (*Reply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HandleIncomingMessage not implemented")
+}
+func (UnimplementedClientServer) mustEmbedUnimplementedClientServer() {}
+func (UnimplementedClientServer) testEmbeddedByValue()                {}
+
+// UnsafeClientServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ClientServer will
+// result in compilation errors.
+type UnsafeClientServer interface {
+	mustEmbedUnimplementedClientServer()
+}
+
+func RegisterClientServer(s grpc.ServiceRegistrar, srv ClientServer) {
+	// If the following call pancis, it indicates UnimplementedClientServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Client_ServiceDesc, srv)
+}
+
+func _Client_HandleIncomingMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Message)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClientServer).HandleIncomingMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Client_HandleIncomingMessage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClientServer).HandleIncomingMessage(ctx, req.(*Message))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Client_ServiceDesc is the grpc.ServiceDesc for Client service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Client_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ivy.Client",
+	HandlerType: (*ClientServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "HandleIncomingMessage",
+			Handler:    _Client_HandleIncomingMessage_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "ivy.proto",
+}
+
+const (
+	CentralManager_HandleIncomingMessage_FullMethodName = "/ivy.CentralManager/HandleIncomingMessage"
+)
+
+// CentralManagerClient is the client API for CentralManager service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Messages to a CM. In FIXED mode a Client serves both services on the same address.
+type CentralManagerClient interface {
+	HandleIncomingMessage(ctx context.Context, in *Message, opts ...grpc.CallOption) (*Reply, error)
+}
+
+type centralManagerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCentralManagerClient(cc grpc.ClientConnInterface) CentralManagerClient {
+	return &centralManagerClient{cc}
+}
+
+func (c *centralManagerClient) HandleIncomingMessage(ctx context.Context, in *Message, opts ...grpc.CallOption) (*Reply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Reply)
+	err := c.cc.Invoke(ctx, CentralManager_HandleIncomingMessage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CentralManagerServer is the server API for CentralManager service.
+// All implementations must embed UnimplementedCentralManagerServer
+// for forward compatibility.
+//
+// Messages to a CM. In FIXED mode a Client serves both services on the same address.
+type CentralManagerServer interface {
+	HandleIncomingMessage(context.Context, *Message) (*Reply, error)
+	mustEmbedUnimplementedCentralManagerServer()
+}
+
+// UnimplementedCentralManagerServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCentralManagerServer struct{}
+
+func (UnimplementedCentralManagerServer) HandleIncomingMessage(context.Context, *Message) (*Reply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HandleIncomingMessage not implemented")
+}
+func (UnimplementedCentralManagerServer) mustEmbedUnimplementedCentralManagerServer() {}
+func (UnimplementedCentralManagerServer) testEmbeddedByValue()                        {}
+
+// UnsafeCentralManagerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CentralManagerServer will
+// result in compilation errors.
+type UnsafeCentralManagerServer interface {
+	mustEmbedUnimplementedCentralManagerServer()
+}
+
+func RegisterCentralManagerServer(s grpc.ServiceRegistrar, srv CentralManagerServer) {
+	// If the following call pancis, it indicates UnimplementedCentralManagerServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&CentralManager_ServiceDesc, srv)
+}
+
+func _CentralManager_HandleIncomingMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Message)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CentralManagerServer).HandleIncomingMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CentralManager_HandleIncomingMessage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CentralManagerServer).HandleIncomingMessage(ctx, req.(*Message))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// CentralManager_ServiceDesc is the grpc.ServiceDesc for CentralManager service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CentralManager_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ivy.CentralManager",
+	HandlerType: (*CentralManagerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "HandleIncomingMessage",
+			Handler:    _CentralManager_HandleIncomingMessage_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "ivy.proto",
+}
@@ -0,0 +1,512 @@
+package ivy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Named reader-writer locks, hosted by the manager a page of the same name would have. There is none
+// in DYNAMIC mode, and the locks are not synced to the backup CM.
+
+// Returned, wrapped, by UnlockContext and RUnlockContext when the manager no longer knew the hold
+// being released, e.g. because its lease expired or the backup CM took over. Another client may have
+// held the lock in the meantime.
+var ErrLockLost = errors.New("lock lost")
+
+// Returned, wrapped, by UnlockContext and RUnlockContext under release consistency when the writes
+// made under the lock could not all be sent to their homes. The lock is still held, and the unlock
+// can be retried.
+var ErrWritesNotReleased = errors.New("writes not released")
+
+// Returned to an acquire withdrawn before it was granted
+var errLockWithdrawn = errors.New("lock request withdrawn")
+
+// Named reader-writer locks with leases, kept by the CM
+type lockTable struct {
+	mu    sync.Mutex
+	locks map[string]*rwLock
+	// Latest version of each page written under each lock, kept after the lock is deleted
+	notices map[string]map[string]int
+	clock   clock
+}
+
+type rwLock struct {
+	holders map[string]*lockHolder
+	queue   []*lockWaiter
+}
+
+type lockHolder struct {
+	shared bool
+	lease  clockTimer
+}
+
+type lockWaiter struct {
+	holder  string
+	shared  bool
+	granted chan struct{}
+	// Set before granted is closed if the waiter left the queue without the lock
+	withdrawn bool
+}
+
+func newLockTable(clock clock) *lockTable {
+	return &lockTable{locks: make(map[string]*rwLock), notices: make(map[string]map[string]int), clock: clock}
+}
+
+// Blocks until holder holds the lock, shared or exclusive, and returns the write notices left on it.
+// Waiters are granted the lock in arrival order. A retried acquire returns once the original one is
+// granted. Once ctx is done the hold is withdrawn, or released if it was granted in the meantime.
+func (lt *lockTable) acquire(ctx context.Context, name string, holder string, shared bool) (map[string]int, error) {
+	lt.mu.Lock()
+	lock := lt.lock(name)
+	if _, held := lock.holders[holder]; held {
+		defer lt.mu.Unlock()
+		return lt.noticesOf(name), nil
+	}
+	var waiter *lockWaiter
+	for _, queued := range lock.queue {
+		if queued.holder == holder {
+			waiter = queued
+			break
+		}
+	}
+	if waiter == nil {
+		if len(lock.queue) == 0 && lock.compatible(shared) {
+			lt.grant(name, lock, holder, shared)
+			defer lt.mu.Unlock()
+			return lt.noticesOf(name), nil
+		}
+		waiter = &lockWaiter{holder: holder, shared: shared, granted: make(chan struct{})}
+		lock.queue = append(lock.queue, waiter)
+		logsystem.Printf("Hold %s waiting for lock %s\n", holder, name)
+	}
+	lt.mu.Unlock()
+
+	if _, err := receiveContext(lt.clock, ctx, waiter.granted); err != nil {
+		lt.release(name, holder)
+		return nil, err
+	}
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	if waiter.withdrawn {
+		return nil, errLockWithdrawn
+	}
+	return lt.noticesOf(name), nil
+}
+
+// Adds the write notices of a release to the lock, keeping the latest version of each page
+func (lt *lockTable) addNotices(name string, notices map[string]int) {
+	if len(notices) == 0 {
+		return
+	}
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	kept, exists := lt.notices[name]
+	if !exists {
+		kept = make(map[string]int)
+		lt.notices[name] = kept
+	}
+	for pageNo, version := range notices {
+		if version > kept[pageNo] {
+			kept[pageNo] = version
+		}
+	}
+}
+
+// Copy of the write notices left on the lock. Must be called with lt.mu held.
+func (lt *lockTable) noticesOf(name string) map[string]int {
+	notices := make(map[string]int, len(lt.notices[name]))
+	for pageNo, version := range lt.notices[name] {
+		notices[pageNo] = version
+	}
+	return notices
+}
+
+// Releases holder's hold on the lock, or withdraws holder from the queue, and grants the lock to
+// the waiters that can now have it. Returns false if holder neither held nor waited for the lock, e.g.
+// because its lease expired.
+func (lt *lockTable) release(name string, holder string) bool {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	lock, exists := lt.locks[name]
+	if !exists {
+		return false
+	}
+	if hold, held := lock.holders[holder]; held {
+		hold.lease.Stop()
+		delete(lock.holders, holder)
+	} else if !lock.withdraw(holder) {
+		return false
+	}
+
+	for len(lock.queue) > 0 && lock.compatible(lock.queue[0].shared) {
+		next := lock.queue[0]
+		lock.queue = lock.queue[1:]
+		lt.grant(name, lock, next.holder, next.shared)
+		close(next.granted)
+	}
+	if len(lock.holders) == 0 && len(lock.queue) == 0 {
+		delete(lt.locks, name)
+	}
+	return true
+}
+
+// Extends holder's lease. Returns false if the CM does not know the hold, e.g. because its lease
+// expired or it was granted by the CM before a failover: the lock may have been granted to someone
+// else since.
+func (lt *lockTable) renew(name string, holder string) bool {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	lock, exists := lt.locks[name]
+	if !exists {
+		return false
+	}
+	hold, held := lock.holders[holder]
+	if !held {
+		return false
+	}
+	hold.lease.Reset(LOCK_LEASE)
+	return true
+}
+
+// Must be called with lt.mu held
+func (lt *lockTable) lock(name string) *rwLock {
+	lock, exists := lt.locks[name]
+	if !exists {
+		lock = &rwLock{holders: make(map[string]*lockHolder)}
+		lt.locks[name] = lock
+	}
+	return lock
+}
+
+// Must be called with lt.mu held
+func (lt *lockTable) grant(name string, lock *rwLock, holder string, shared bool) {
+	lock.holders[holder] = &lockHolder{
+		shared: shared,
+		lease: lt.clock.AfterFunc(LOCK_LEASE, func() {
+			if lt.release(name, holder) {
+				logwarning.Printf("Lease of hold %s on lock %s expired\n", holder, name)
+			}
+		}),
+	}
+}
+
+// Removes holder from the queue. Reports whether it was waiting.
+func (lock *rwLock) withdraw(holder string) bool {
+	for i, queued := range lock.queue {
+		if queued.holder == holder {
+			lock.queue = append(lock.queue[:i:i], lock.queue[i+1:]...)
+			queued.withdrawn = true
+			close(queued.granted)
+			return true
+		}
+	}
+	return false
+}
+
+// Whether a new hold can be granted next to the current holders
+func (lock *rwLock) compatible(shared bool) bool {
+	for _, hold := range lock.holders {
+		if !shared || !hold.shared {
+			return false
+		}
+	}
+	return true
+}
+
+func (cm *CentralManager) handleLockAcquire(ctx context.Context, msg Message, reply *Reply) {
+	lock := msg.Payload.Lock
+	notices, err := cm.locks.acquire(ctx, lock.Name, msg.RequestID, lock.Shared)
+	if err != nil {
+		logwarning.Printf("Hold %s on lock %s not granted: %v\n", msg.RequestID, lock.Name, err)
+		reply.Err = err.Error()
+		return
+	}
+	reply.Notices = notices
+	reply.Ack = true
+	logsystem.Printf("Lock %s granted to Client %d (shared: %v)\n", lock.Name, msg.FromID, lock.Shared)
+}
+
+func (cm *CentralManager) handleLockRelease(msg Message, reply *Reply) {
+	lock := msg.Payload.Lock
+	// The diffs behind the notices are at their homes, so they stand even if the hold was lost
+	cm.locks.addNotices(lock.Name, lock.Notices)
+	if !cm.locks.release(lock.Name, msg.RequestID) {
+		logwarning.Printf("Client %d released lock %s, which it did not hold\n", msg.FromID, lock.Name)
+		reply.Err = ErrLockLost.Error()
+		return
+	}
+	reply.Ack = true
+	logsystem.Printf("Lock %s released by Client %d\n", lock.Name, msg.FromID)
+}
+
+func (cm *CentralManager) handleLockRenew(msg Message, reply *Reply) {
+	lock := msg.Payload.Lock
+	if !cm.locks.renew(lock.Name, msg.RequestID) {
+		logwarning.Printf("Client %d renewed lock %s, which it did not hold\n", msg.FromID, lock.Name)
+		reply.Err = ErrLockLost.Error()
+		return
+	}
+	reply.Ack = true
+}
+
+// A single acquisition of a lock by this client
+type lockHold struct {
+	name   string
+	holder string
+	shared bool
+	// Closed on release, stops the lease renewals
+	stop chan struct{}
+	// Write notices of the diffs sent by earlier attempts to release the hold
+	notices map[string]int
+}
+
+// Blocks until the lock is granted, retrying while the manager cannot be reached. Once ctx is done,
+// the request is withdrawn from the manager.
+func (c *Client) acquireLock(ctx context.Context, name string, shared bool) (*lockHold, error) {
+	hold := &lockHold{name: name, holder: c.pending.newRequestID(c.ID), shared: shared, stop: make(chan struct{})}
+	msg := c.lockMessage(LOCK_ACQUIRE, hold)
+	for {
+		reply := c.CallRPC(ctx, msg, CENTRALMANAGER, -1, c.managerIP(name))
+		if reply.Ack {
+			if c.consistency == RELEASE {
+				c.applyNotices(reply.Notices)
+			}
+			break
+		}
+		// The manager gives up at the same deadline
+		if reply.Err != "" && ctx.Err() == nil {
+			return nil, fmt.Errorf("%s for lock %s: %s", LOCK_ACQUIRE, name, reply.Err)
+		}
+		if ctx.Err() == nil {
+			logwarning.Printf("Msg [%s] for lock %s not acknowledged, retrying...\n", LOCK_ACQUIRE, name)
+			if _, err := receiveContext(c.clock, ctx, c.clock.After(RETRY_INTERVAL)); err == nil {
+				continue
+			}
+		}
+		// The manager may still have the request queued
+		c.CallRPC(context.Background(), c.lockMessage(LOCK_RELEASE, hold), CENTRALMANAGER, -1, c.managerIP(name))
+		return nil, fmt.Errorf("%s for lock %s: %w", LOCK_ACQUIRE, name, ctx.Err())
+	}
+
+	c.clock.Go(func() { c.renewLock(hold) })
+	return hold, nil
+}
+
+// Renews the lease of hold until it is released, or until the manager no longer knows it
+func (c *Client) renewLock(hold *lockHold) {
+	ticker := c.clock.NewTicker(LOCK_LEASE / 3)
+	defer ticker.Stop()
+	for {
+		if i, _ := c.clock.Select(hold.stop, ticker.Ticks()); i == 0 {
+			return
+		}
+		reply := c.CallRPC(context.Background(), c.lockMessage(LOCK_RENEW, hold), CENTRALMANAGER, -1, c.managerIP(hold.name))
+		if reply.Err == ErrLockLost.Error() {
+			logerror.Printf("Lost lock %s, it may have been granted to someone else\n", hold.name)
+			return
+		}
+		if !reply.Ack {
+			logerror.Printf("Could not renew the lease on lock %s\n", hold.name)
+		}
+	}
+}
+
+func (c *Client) releaseLock(ctx context.Context, hold *lockHold) error {
+	msg := c.lockMessage(LOCK_RELEASE, hold)
+	if c.consistency == RELEASE {
+		notices, err := c.flushDiffs(ctx)
+		if hold.notices == nil {
+			hold.notices = make(map[string]int)
+		}
+		for pageNo, version := range notices {
+			if version > hold.notices[pageNo] {
+				hold.notices[pageNo] = version
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("%w under lock %s, which is still held: %w", ErrWritesNotReleased, hold.name, err)
+		}
+		msg.Payload.Lock.Notices = hold.notices
+	}
+	close(hold.stop)
+	reply := c.CallRPC(ctx, msg, CENTRALMANAGER, -1, c.managerIP(hold.name))
+	if reply.Err == ErrLockLost.Error() {
+		return fmt.Errorf("%w: %s", ErrLockLost, hold.name)
+	}
+	if !reply.Ack {
+		return fmt.Errorf("release of lock %s not acknowledged, it will be released when its lease expires", hold.name)
+	}
+	return nil
+}
+
+func (c *Client) lockMessage(msgType string, hold *lockHold) Message {
+	return Message{
+		Type: msgType,
+		Payload: Payload{
+			Lock: Lock{
+				Name:   hold.name,
+				Shared: hold.shared,
+			},
+		},
+		FromID:    c.ID,
+		FromIP:    c.IP,
+		RequestID: hold.holder,
+	}
+}
+
+// Distributed reader-writer lock shared by every client that uses the same name
+type RWMutex struct {
+	c    *Client
+	name string
+
+	// Guards writer and readers
+	mu      sync.Mutex
+	writer  *lockHold
+	readers []*lockHold
+}
+
+// Distributed mutual exclusion lock shared by every client that uses the same name.
+// Uses the same lock as an RWMutex of that name.
+type Mutex struct {
+	rw *RWMutex
+}
+
+func (c *Client) NewRWMutex(name string) (*RWMutex, error) {
+	if c.mode == DYNAMIC {
+		return nil, fmt.Errorf("locks are hosted by the manager, there is none in %s mode", DYNAMIC)
+	}
+	return &RWMutex{c: c, name: name}, nil
+}
+
+func (c *Client) NewMutex(name string) (*Mutex, error) {
+	rw, err := c.NewRWMutex(name)
+	if err != nil {
+		return nil, err
+	}
+	return &Mutex{rw: rw}, nil
+}
+
+// Blocks until this client holds the lock exclusively. Panics if the manager refuses the lock, as
+// sync.Locker has no way to report it: LockContext returns the error instead.
+func (rw *RWMutex) Lock() {
+	if err := rw.LockContext(context.Background()); err != nil {
+		panic(fmt.Sprintf("ivy: could not take lock %s: %v", rw.name, err))
+	}
+}
+
+// Blocks until this client holds the lock exclusively or ctx is done
+func (rw *RWMutex) LockContext(ctx context.Context) error {
+	hold, err := rw.c.acquireLock(ctx, rw.name, false)
+	if err != nil {
+		return err
+	}
+	rw.mu.Lock()
+	rw.writer = hold
+	rw.mu.Unlock()
+	return nil
+}
+
+// Releases the exclusive hold of this client, retrying until the writes made under it are released
+func (rw *RWMutex) Unlock() {
+	if err := rw.c.retryUnlock(rw.UnlockContext); err != nil {
+		logerror.Printf("Unlock of lock %s: %v\n", rw.name, err)
+	}
+}
+
+// Releases the exclusive hold of this client. Returns an error wrapping ErrLockLost if the manager no
+// longer knew the hold, in which case the writes made under it may have interleaved with another holder's.
+func (rw *RWMutex) UnlockContext(ctx context.Context) error {
+	rw.mu.Lock()
+	hold := rw.writer
+	rw.writer = nil
+	rw.mu.Unlock()
+	if hold == nil {
+		return fmt.Errorf("lock %s is not locked", rw.name)
+	}
+	err := rw.c.releaseLock(ctx, hold)
+	if errors.Is(err, ErrWritesNotReleased) {
+		rw.mu.Lock()
+		rw.writer = hold
+		rw.mu.Unlock()
+	}
+	return err
+}
+
+// Blocks until this client holds the lock shared with other readers. Panics as Lock does.
+func (rw *RWMutex) RLock() {
+	if err := rw.RLockContext(context.Background()); err != nil {
+		panic(fmt.Sprintf("ivy: could not take lock %s: %v", rw.name, err))
+	}
+}
+
+// Blocks until this client holds the lock shared with other readers or ctx is done
+func (rw *RWMutex) RLockContext(ctx context.Context) error {
+	hold, err := rw.c.acquireLock(ctx, rw.name, true)
+	if err != nil {
+		return err
+	}
+	rw.mu.Lock()
+	rw.readers = append(rw.readers, hold)
+	rw.mu.Unlock()
+	return nil
+}
+
+func (rw *RWMutex) RUnlock() {
+	if err := rw.c.retryUnlock(rw.RUnlockContext); err != nil {
+		logerror.Printf("RUnlock of lock %s: %v\n", rw.name, err)
+	}
+}
+
+// Releases a shared hold of this client, as UnlockContext
+func (rw *RWMutex) RUnlockContext(ctx context.Context) error {
+	rw.mu.Lock()
+	if len(rw.readers) == 0 {
+		rw.mu.Unlock()
+		return fmt.Errorf("lock %s is not read-locked", rw.name)
+	}
+	hold := rw.readers[len(rw.readers)-1]
+	rw.readers = rw.readers[:len(rw.readers)-1]
+	rw.mu.Unlock()
+	err := rw.c.releaseLock(ctx, hold)
+	if errors.Is(err, ErrWritesNotReleased) {
+		rw.mu.Lock()
+		rw.readers = append(rw.readers, hold)
+		rw.mu.Unlock()
+	}
+	return err
+}
+
+// Calls unlock every RETRY_INTERVAL until the writes made under the lock are released
+func (c *Client) retryUnlock(unlock func(ctx context.Context) error) error {
+	for {
+		err := unlock(context.Background())
+		if !errors.Is(err, ErrWritesNotReleased) {
+			return err
+		}
+		logwarning.Printf("%v, retrying...\n", err)
+		c.clock.Sleep(RETRY_INTERVAL)
+	}
+}
+
+func (m *Mutex) Lock() {
+	m.rw.Lock()
+}
+
+func (m *Mutex) LockContext(ctx context.Context) error {
+	return m.rw.LockContext(ctx)
+}
+
+func (m *Mutex) Unlock() {
+	m.rw.Unlock()
+}
+
+func (m *Mutex) UnlockContext(ctx context.Context) error {
+	return m.rw.UnlockContext(ctx)
+}
+
+var _ sync.Locker = (*Mutex)(nil)
+var _ sync.Locker = (*RWMutex)(nil)
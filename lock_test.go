@@ -0,0 +1,276 @@
+package ivy
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// Acquires the lock in its own goroutine and sends the holder once it is granted
+func acquireAsync(t *testing.T, lt *lockTable, name string, holder string, shared bool, granted chan<- string) {
+	t.Helper()
+	go func() {
+		if _, err := lt.acquire(context.Background(), name, holder, shared); err != nil {
+			t.Errorf("acquire %s: %v", holder, err)
+		}
+		granted <- holder
+	}()
+	waitFor(t, func() bool {
+		lt.mu.Lock()
+		defer lt.mu.Unlock()
+		for _, waiter := range lt.locks[name].queue {
+			if waiter.holder == holder {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func holders(lt *lockTable, name string) []string {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	names := []string{}
+	if lock, exists := lt.locks[name]; exists {
+		for holder := range lock.holders {
+			names = append(names, holder)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func receive(t *testing.T, granted <-chan string, want ...string) {
+	t.Helper()
+	got := []string{}
+	for range want {
+		select {
+		case holder := <-granted:
+			got = append(got, holder)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("granted %v, want %v", got, want)
+		}
+	}
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("granted %v, want %v", got, want)
+	}
+}
+
+func TestLockTableGrantsInArrivalOrder(t *testing.T) {
+	lt := newLockTable(newSimClock())
+	granted := make(chan string, 4)
+	if _, err := lt.acquire(context.Background(), "x", "a", false); err != nil {
+		t.Fatal(err)
+	}
+	acquireAsync(t, lt, "x", "b", false, granted)
+	acquireAsync(t, lt, "x", "c", true, granted)
+	acquireAsync(t, lt, "x", "d", true, granted)
+	acquireAsync(t, lt, "x", "e", false, granted)
+
+	lt.release("x", "a")
+	receive(t, granted, "b")
+	lt.release("x", "b")
+	// Readers queued next to each other share the lock
+	receive(t, granted, "c", "d")
+	lt.release("x", "c")
+	if got := holders(lt, "x"); !reflect.DeepEqual(got, []string{"d"}) {
+		t.Fatalf("holders %v, want [d]", got)
+	}
+	lt.release("x", "d")
+	receive(t, granted, "e")
+	lt.release("x", "e")
+	if _, exists := lt.locks["x"]; exists {
+		t.Error("free lock still in the table")
+	}
+}
+
+func TestLockTableReaderWaitsBehindWriter(t *testing.T) {
+	lt := newLockTable(newSimClock())
+	granted := make(chan string, 2)
+	if _, err := lt.acquire(context.Background(), "x", "r1", true); err != nil {
+		t.Fatal(err)
+	}
+	acquireAsync(t, lt, "x", "w", false, granted)
+	// Could share the lock with r1, but comes after w
+	acquireAsync(t, lt, "x", "r2", true, granted)
+	if got := holders(lt, "x"); !reflect.DeepEqual(got, []string{"r1"}) {
+		t.Fatalf("holders %v, want [r1]", got)
+	}
+
+	lt.release("x", "r1")
+	receive(t, granted, "w")
+	lt.release("x", "w")
+	receive(t, granted, "r2")
+}
+
+func TestLockTableLeaseExpires(t *testing.T) {
+	clock := newSimClock()
+	lt := newLockTable(clock)
+	granted := make(chan string, 1)
+	if _, err := lt.acquire(context.Background(), "x", "a", false); err != nil {
+		t.Fatal(err)
+	}
+	acquireAsync(t, lt, "x", "b", false, granted)
+
+	clock.advance(LOCK_LEASE / 2)
+	if !lt.renew("x", "a") {
+		t.Fatal("renewal of a held lock rejected")
+	}
+	clock.advance(LOCK_LEASE / 2)
+	if clock.fireNext(false) {
+		t.Fatal("renewed lease expired")
+	}
+	clock.advance(LOCK_LEASE / 2)
+	if !clock.fireNext(false) {
+		t.Fatal("lease did not expire")
+	}
+	receive(t, granted, "b")
+
+	if lt.renew("x", "a") {
+		t.Error("renewal of an expired hold accepted")
+	}
+	if lt.release("x", "a") {
+		t.Error("release of an expired hold accepted")
+	}
+}
+
+func TestLockTableWithdrawsCancelledAcquire(t *testing.T) {
+	lt := newLockTable(newSimClock())
+	if _, err := lt.acquire(context.Background(), "x", "a", false); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := lt.acquire(ctx, "x", "b", false)
+		done <- err
+	}()
+	waitFor(t, func() bool {
+		lt.mu.Lock()
+		defer lt.mu.Unlock()
+		return len(lt.locks["x"].queue) == 1
+	})
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+	lt.release("x", "a")
+	if _, exists := lt.locks["x"]; exists {
+		t.Error("withdrawn acquire was granted the lock")
+	}
+}
+
+func TestLockContextGivesUp(t *testing.T) {
+	dsm := newTestDSM(t, Config{}, 2)
+	m1, _ := dsm.clients[0].NewMutex("x")
+	m2, _ := dsm.clients[1].NewMutex("x")
+	if err := m1.LockContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := dsm.clock.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := m2.LockContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+	if err := m1.UnlockContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// The request of m2 left the queue, so the lock is free
+	dsm.waitFor(t, func() bool {
+		dsm.cm.locks.mu.Lock()
+		defer dsm.cm.locks.mu.Unlock()
+		return len(dsm.cm.locks.locks) == 0
+	})
+}
+
+func TestUnlockReportsLostLock(t *testing.T) {
+	dsm := newTestDSM(t, Config{}, 2)
+	rw1, _ := dsm.clients[0].NewRWMutex("x")
+	m2, _ := dsm.clients[1].NewMutex("x")
+	if err := rw1.LockContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// As if the lease had expired while the client could not reach the CM
+	dsm.cm.locks.release("x", rw1.writer.holder)
+
+	if err := m2.LockContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := rw1.UnlockContext(context.Background()); !errors.Is(err, ErrLockLost) {
+		t.Errorf("got %v, want %v", err, ErrLockLost)
+	}
+	if err := m2.UnlockContext(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
+
+// After a failover, or an expired lease, the CM does not grant a hold again on its renewal
+func TestLockRenewalOfUnknownHoldRejected(t *testing.T) {
+	dsm := newTestDSM(t, Config{}, 1)
+	rw, _ := dsm.clients[0].NewRWMutex("x")
+	if err := rw.RLockContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	hold := rw.readers[0]
+	dsm.cm.locks.release("x", hold.holder)
+
+	reply := dsm.clients[0].CallRPC(context.Background(), dsm.clients[0].lockMessage(LOCK_RENEW, hold), CENTRALMANAGER, -1, dsm.cm.IP)
+	if reply.Ack || reply.Err != ErrLockLost.Error() {
+		t.Errorf("renewal answered %+v, want %v", reply, ErrLockLost)
+	}
+	if holders := holders(dsm.cm.locks, "x"); len(holders) != 0 {
+		t.Errorf("hold restored: %v", holders)
+	}
+	if err := rw.RUnlockContext(context.Background()); !errors.Is(err, ErrLockLost) {
+		t.Errorf("got %v, want %v", err, ErrLockLost)
+	}
+}
+
+// Transport through which the manager refuses every lock
+type refusingTransport struct {
+	Transport
+}
+
+func (t refusingTransport) Send(ctx context.Context, target Target, msg Message) (Reply, error) {
+	if msg.Type == LOCK_ACQUIRE {
+		return Reply{Err: "refused"}, nil
+	}
+	return t.Transport.Send(ctx, target, msg)
+}
+
+// sync.Locker cannot return the error, and must not return as if the lock were held
+func TestLockPanicsWhenRefused(t *testing.T) {
+	dsm := newTestDSM(t, Config{}, 1)
+	c := dsm.clients[0]
+	c.transport = refusingTransport{c.transport}
+	rw, _ := c.NewRWMutex("x")
+	if err := rw.LockContext(context.Background()); err == nil {
+		t.Fatal("refused lock taken")
+	}
+	for name, lock := range map[string]func(){"Lock": rw.Lock, "RLock": rw.RLock} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s returned without the lock", name)
+				}
+			}()
+			lock()
+		}()
+	}
+}
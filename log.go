@@ -0,0 +1,19 @@
+package ivy
+
+import (
+	"io"
+
+	"github.com/fatih/color"
+)
+
+// Color coded logs
+var logsystem = color.New(color.FgCyan).Add(color.BgBlack)
+var logerror = color.New(color.FgHiRed).Add(color.BgBlack)
+var logwarning = color.New(color.FgYellow).Add(color.BgBlack)
+var logincoming = color.New(color.FgHiMagenta).Add(color.BgBlack)
+var logoutgoing = color.New(color.FgHiYellow).Add(color.BgBlack)
+
+// Sends the logs of every node in the process to w, e.g. io.Discard. They go to stdout by default.
+func SetLogOutput(w io.Writer) {
+	color.Output = w
+}
@@ -0,0 +1,24 @@
+package ivy
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// The nodes log every message they send and receive
+	SetLogOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// Address on the loopback interface that nothing listens on
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	port, err := GetFreePort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fmt.Sprintf("127.0.0.1:%d", port)
+}
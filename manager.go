@@ -0,0 +1,100 @@
+package ivy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+)
+
+// Manager algorithms, selected with Config.Mode
+const (
+	// A single CM (plus backup) manages every page
+	CENTRAL = "central"
+	// Improved centralized manager: the CM only tracks the owner of each page and serializes the
+	// requests on it. The owner keeps the copy set and invalidates the copies before giving up the page.
+	IMPROVED = "improved"
+	// Every client manages the pages that hash to it (Li & Hudak's fixed distributed manager).
+	// Each client runs a CentralManager for its slice of the page space next to its Client.
+	FIXED = "fixed"
+	// No manager: requests follow the probable owners to the owner (Li & Hudak's dynamic distributed manager)
+	DYNAMIC = "dynamic"
+)
+
+// ID of the client that manages pageNo in FIXED mode. Client IDs start at 1.
+func (c *Client) managerIDFor(pageNo string) int {
+	h := fnv.New32a()
+	h.Write([]byte(pageNo))
+	return int(h.Sum32()%uint32(c.nodes)) + 1
+}
+
+// Where READ_REQUEST/WRITE_REQUEST for pageNo are sent: the CM, the managing client in FIXED mode,
+// or the probable owner in DYNAMIC mode
+func (c *Client) requestTarget(pageNo string) (nodeType string, targetID int, targetIP string) {
+	if c.mode == DYNAMIC {
+		c.mu.Lock()
+		owner := c.probOwnerLocked(pageNo)
+		c.mu.Unlock()
+		return CLIENT, owner.ID, c.probOwnerIP(owner)
+	}
+	return CENTRALMANAGER, -1, c.managerIP(pageNo)
+}
+
+// IP of the node managing pageNo: the CM, or in FIXED mode the client the page hashes to
+func (c *Client) managerIP(pageNo string) string {
+	if c.mode != FIXED {
+		return c.cmIP()
+	}
+
+	managerID := c.managerIDFor(pageNo)
+	ip, err := c.peerIP(managerID)
+	if err != nil {
+		logerror.Printf("No manager for Page %s: %v\n", pageNo, err)
+		return "NIL"
+	}
+	return ip
+}
+
+// Sends a single-RPC operation on pageNo, such as SET_COHERENCE, to the page's manager.
+// Returns the manager's refusal as an error.
+func (c *Client) callManager(pageNo string, msg Message) error {
+	msg.FromID = c.ID
+	msg.FromIP = c.IP
+	msg.RequestID = c.pending.newRequestID(c.ID)
+	reply := c.CallRPC(context.Background(), msg, CENTRALMANAGER, -1, c.managerIP(pageNo))
+	if reply.Err != "" {
+		return errors.New(reply.Err)
+	}
+	if !reply.Ack {
+		return fmt.Errorf("%s for page %s not acknowledged by CM", msg.Type, pageNo)
+	}
+	return nil
+}
+
+// MetaData of the pages this client manages in FIXED mode, nil in the other modes
+func (c *Client) ManagedPages() map[string]PageInfo {
+	if c.manager == nil {
+		return nil
+	}
+	return c.manager.Pages()
+}
+
+// Looks up the IP of another client in client.json, caching it once found
+func (c *Client) peerIP(id int) (string, error) {
+	c.mu.Lock()
+	ip, known := c.peers[id]
+	c.mu.Unlock()
+	if known {
+		return ip, nil
+	}
+
+	for _, client := range c.registry.clients() {
+		if client.ID == id {
+			c.mu.Lock()
+			c.peers[id] = client.IP
+			c.mu.Unlock()
+			return client.IP, nil
+		}
+	}
+	return "", fmt.Errorf("client %d has not joined yet", id)
+}
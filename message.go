@@ -1,4 +1,6 @@
-package main
+package ivy
+
+import "time"
 
 const (
 	READ_REQUEST            = "READ_REQUEST"
@@ -13,6 +15,21 @@ const (
 	PULSE                   = "PULSE"
 	CHANGE_CM               = "CHANGE_CM"
 	IM_BACK                 = "IM_BACK"
+	UPDATE_COPY             = "UPDATE_COPY"
+	SET_COHERENCE           = "SET_COHERENCE"
+	UPGRADE_REQUEST         = "UPGRADE_REQUEST"
+	UPGRADE_GRANT           = "UPGRADE_GRANT"
+	ALLOCATE_PAGE           = "ALLOCATE_PAGE"
+	FREE_PAGE               = "FREE_PAGE"
+	DROP_PAGE               = "DROP_PAGE"
+	LOCK_ACQUIRE            = "LOCK_ACQUIRE"
+	LOCK_RELEASE            = "LOCK_RELEASE"
+	LOCK_RENEW              = "LOCK_RENEW"
+	BARRIER_WAIT            = "BARRIER_WAIT"
+	TXN_LOCK                = "TXN_LOCK"
+	TXN_UNLOCK              = "TXN_UNLOCK"
+	HOME_FETCH              = "HOME_FETCH"
+	HOME_DIFF               = "HOME_DIFF"
 )
 
 type Message struct {
@@ -20,11 +37,30 @@ type Message struct {
 	Payload Payload
 	FromID  int
 	FromIP  string
+	// Generated by the client that issued the READ_REQUEST/WRITE_REQUEST and carried by every
+	// message of that request, so the CM can deduplicate retries.
+	RequestID string
+	// Set on the WRITE_REQUESTs of a transaction, which run under the page locks the transaction holds
+	TxnID string
+	// Time the sender waits for the reply, from when the message was sent. The handler gives up once it
+	// has elapsed. 0 waits indefinitely.
+	Timeout time.Duration
 }
 
 type Reply struct {
-	Ack     bool
-	Payload map[string]PageInfo
+	Ack      bool
+	Payload  map[string]PageInfo
+	Requests map[string]RequestRecord
+	// Set when a retried request had already completed
+	Done bool
+	// Set when the request was refused and retrying will not help
+	Err string
+	// Home copy returned by HOME_FETCH, or its new version after a HOME_DIFF
+	Home HomePage
+	// Home copies, sent to the backup CM with the MetaData
+	Homes map[string]HomePage
+	// Write notices of the lock granted by LOCK_ACQUIRE
+	Notices map[string]int
 }
 
 type Payload struct {
@@ -40,6 +76,17 @@ type Payload struct {
 	Pulse                  Pulse
 	ChangeCM               ChangeCM
 	ImBack                 ImBack
+	UpdateCopy             UpdateCopy
+	SetCoherence           SetCoherence
+	UpgradeGrant           UpgradeGrant
+	AllocatePage           AllocatePage
+	FreePage               FreePage
+	DropPage               DropPage
+	Lock                   Lock
+	Barrier                Barrier
+	TxnLock                TxnLock
+	HomeFetch              HomeFetch
+	HomeDiff               HomeDiff
 }
 
 type ReadRequest struct {
@@ -55,6 +102,11 @@ type ReadForward struct {
 type PageSend struct {
 	Purpose string
 	Page    Page
+	// Travels with the page when ownership moves in DYNAMIC and IMPROVED modes
+	CopySet []ClientPointer
+	// Set on a WRITE under write-update when other clients still hold copies:
+	// the writer installs the page as READ so that its next write goes through the manager
+	Shared bool
 }
 
 type ReadConfirmation struct {
@@ -65,9 +117,11 @@ type ReadConfirmation struct {
 	SenderIP        string
 }
 
+// Writes Content at Offset in the page
 type WriteRequest struct {
 	PageNo  string
-	Content string
+	Offset  int
+	Content []byte
 }
 
 type InvalidateCopy struct {
@@ -84,7 +138,12 @@ type WriteForward struct {
 	WriteRequesterID int
 	WriteRequesterIP string
 	PageNumber       string
-	Content          string
+	Offset           int
+	Content          []byte
+	// Coherence policy of the page. Under write-update the owner pushes the write to the copy set,
+	// which is sent along unless the owner keeps it itself (IMPROVED mode).
+	Coherence string
+	CopySet   []ClientPointer
 }
 
 type WriteConfirmation struct {
@@ -104,3 +163,67 @@ type ChangeCM struct {
 type ImBack struct {
 	CMIP string
 }
+
+// Write to apply to a copy under write-update
+type UpdateCopy struct {
+	PageNumber string
+	Offset     int
+	Content    []byte
+}
+
+type SetCoherence struct {
+	PageNo string
+	Policy string
+}
+
+// Grants ownership to a client upgrading its READ copy. No page content travels: Offset/Content
+// is the requester's own write, applied to the copy it already holds.
+type UpgradeGrant struct {
+	PageNumber string
+	Offset     int
+	Content    []byte
+}
+
+type AllocatePage struct {
+	PageNo string
+}
+
+type FreePage struct {
+	PageNo string
+}
+
+// Sent by the CM to the owner of a page being freed
+type DropPage struct {
+	PageNumber string
+}
+
+// Carried by LOCK_ACQUIRE, LOCK_RELEASE and LOCK_RENEW. The hold is identified by the message's RequestID.
+type Lock struct {
+	Name   string
+	Shared bool
+	// Write notices left by a LOCK_RELEASE under release consistency: version of each page written
+	Notices map[string]int
+}
+
+// Carried by TXN_LOCK and TXN_UNLOCK. The transaction is identified by the message's RequestID.
+type TxnLock struct {
+	PageNo string
+	// How long the manager waits for the page lock before the transaction aborts
+	Timeout time.Duration
+}
+
+type HomeFetch struct {
+	PageNo string
+}
+
+// Writes made to a page since the last release, in order
+type HomeDiff struct {
+	PageNo string
+	Diffs  []Diff
+}
+
+// Arrival at barrier Name, which is released once N clients have arrived
+type Barrier struct {
+	Name string
+	N    int
+}
@@ -0,0 +1,327 @@
+package ivy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+const (
+	// How long Read/Write block waiting for a PAGE_SEND before giving up
+	REQUEST_TIMEOUT = 10 * time.Second
+	// How long a client waits for a PAGE_SEND before resending its request
+	RETRY_INTERVAL = 3 * time.Second
+	// How long the CM remembers completed requests for deduplication
+	REQUEST_RETENTION = 5 * time.Minute
+	// How long a request may hold the CM's lock on a page before it is released without a confirmation
+	PAGE_LOCK_LEASE = REQUEST_TIMEOUT
+	// How long a write waits for the whole copy set to acknowledge its INVALIDATE_COPY/UPDATE_COPY
+	FAN_OUT_TIMEOUT = 5 * time.Second
+	// How long a lock stays held by a client that stops renewing it
+	LOCK_LEASE = 10 * time.Second
+)
+
+// Configuration of a CM or Client node. Every node of a DSM must use the same Mode, Nodes, DataDir,
+// Consistency and kind of Transport.
+type Config struct {
+	// "host:port" the node listens on. Defaults to a free port on the outbound IP.
+	Addr string
+	// Manager algorithm: CENTRAL (default), IMPROVED, FIXED or DYNAMIC
+	Mode string
+	// Number of clients sharing the page space in FIXED mode
+	Nodes int
+	// Directory holding cm.json and client.json. Defaults to "data".
+	DataDir string
+	// Consistency model of the clients: SEQUENTIAL (default) or RELEASE
+	Consistency string
+	// Directory a Client records the history of its reads and writes to. Not recorded if empty.
+	HistoryDir string
+	// How the node exchanges messages. Defaults to RPCTransport.
+	Transport Transport
+	// Deadline for the reply to each message type the node sends, overriding the default of the type.
+	// 0 waits as long as the sender's ctx allows.
+	Timeouts map[string]time.Duration
+	// How the node sends again the messages it could not deliver. Not retried by default.
+	Retry RetryPolicy
+	// Source of time of the node, set by simulations. Defaults to the wall clock.
+	clock clock
+}
+
+// Fills in the defaults and checks the mode
+func (cfg Config) withDefaults() (Config, error) {
+	switch cfg.Mode {
+	case "":
+		cfg.Mode = CENTRAL
+	case CENTRAL, IMPROVED, DYNAMIC:
+	case FIXED:
+		if cfg.Nodes < 1 {
+			return cfg, errors.New("Nodes is required in fixed mode")
+		}
+	default:
+		return cfg, fmt.Errorf("unknown manager mode %s", cfg.Mode)
+	}
+	switch cfg.Consistency {
+	case "":
+		cfg.Consistency = SEQUENTIAL
+	case SEQUENTIAL:
+	case RELEASE:
+		if cfg.Mode == DYNAMIC {
+			return cfg, fmt.Errorf("%s consistency keeps pages at their manager, there is none in %s mode", RELEASE, DYNAMIC)
+		}
+	default:
+		return cfg, fmt.Errorf("unknown consistency model %s", cfg.Consistency)
+	}
+	if cfg.DataDir == "" {
+		cfg.DataDir = "data"
+	}
+	if cfg.Transport == nil {
+		cfg.Transport = &RPCTransport{}
+	}
+	if cfg.clock == nil {
+		cfg.clock = wallClock{}
+	}
+	if cfg.Addr == "" {
+		port, err := GetFreePort()
+		if err != nil {
+			return cfg, fmt.Errorf("assigning port number: %w", err)
+		}
+		cfg.Addr = GetOutboundIP().String() + ":" + strconv.Itoa(port)
+	}
+	return cfg, nil
+}
+
+// Registers a new CM in cfg.DataDir: the primary if there is no CM yet, otherwise the backup.
+// The CM handles messages once started.
+func NewCentralManager(cfg Config) (*CentralManager, error) {
+	cfg, err := cfg.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	reg := registry{dir: cfg.DataDir}
+	existingCMs := reg.cms()
+
+	cm := newCentralManager(cfg, cfg.Addr, len(existingCMs) == 0)
+	if err := reg.writeCMs(append(existingCMs, *cm)); err != nil {
+		return nil, fmt.Errorf("registering CM: %w", err)
+	}
+	if cm.IsPrimary {
+		logsystem.Println("Created CM and set as primary: ", cm.IP)
+	} else {
+		logsystem.Println("Created Backup CM: ", cm.IP)
+	}
+	return cm, nil
+}
+
+// Brings back the primary (or backup) CM registered in cfg.DataDir on its old address; cfg.Addr is ignored.
+// A restarted primary takes the MetaData back from the backup when it starts.
+func RestartCentralManager(cfg Config, primary bool) (*CentralManager, error) {
+	cfg, err := cfg.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	reg := registry{dir: cfg.DataDir}
+
+	var ip string
+	if primary {
+		ip, err = reg.primaryCMIP()
+	} else {
+		ip, err = reg.backupCMIP()
+	}
+	if err != nil {
+		return nil, err
+	}
+	cm := newCentralManager(cfg, ip, primary)
+	cm.reclaim = primary
+	return cm, nil
+}
+
+// Registers a new client in cfg.DataDir with the next free ID. The client handles messages once started.
+func NewClient(cfg Config) (*Client, error) {
+	cfg, err := cfg.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	reg := registry{dir: cfg.DataDir}
+
+	cmip, err := reg.clientCMIP(cfg.Mode)
+	if err != nil {
+		return nil, fmt.Errorf("getting primary CM IP: %w", err)
+	}
+	// Client IDs start at 1
+	existingClients := reg.clients()
+	id := getHighestClientID(existingClients) + 1
+	if id < 1 {
+		id = 1
+	}
+
+	client := newClient(cfg, id, cfg.Addr, cmip)
+	if client.history, err = openHistory(cfg.HistoryDir, id); err != nil {
+		return nil, err
+	}
+	if err := reg.writeClients(append(existingClients, *client)); err != nil {
+		return nil, fmt.Errorf("registering client: %w", err)
+	}
+	logsystem.Printf("Created new Client with ID %d\n", client.ID)
+	return client, nil
+}
+
+// Brings back the client registered in cfg.DataDir with the given ID on its old address; cfg.Addr is ignored.
+// The restarted client has none of the pages it held, and appends to its history.
+func RestartClient(cfg Config, id int) (*Client, error) {
+	cfg, err := cfg.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	reg := registry{dir: cfg.DataDir}
+
+	cmip, err := reg.clientCMIP(cfg.Mode)
+	if err != nil {
+		return nil, fmt.Errorf("getting primary CM IP: %w", err)
+	}
+	for _, registered := range reg.clients() {
+		if registered.ID != id {
+			continue
+		}
+		client := newClient(cfg, id, registered.IP, cmip)
+		if client.history, err = openHistory(cfg.HistoryDir, id); err != nil {
+			return nil, err
+		}
+		return client, nil
+	}
+	return nil, fmt.Errorf("no client %d in %s", id, reg.clientPath())
+}
+
+// Listens on the CM's address and handles incoming messages until Stop.
+// A backup CM starts checking the primary's pulse.
+func (cm *CentralManager) Start() error {
+	listener, err := cm.transport.Listen(cm.IP, map[string]Handler{
+		CENTRALMANAGER: cm.HandleIncomingMessage,
+	})
+	if err != nil {
+		return err
+	}
+	cm.listener = listener
+	logsystem.Printf("CM is running at IP address: %s...\n", cm.IP)
+
+	if cm.reclaim {
+		cm.reclaimPrimary()
+	}
+	cm.mu.Lock()
+	isPrimary := cm.IsPrimary
+	cm.mu.Unlock()
+	if !isPrimary {
+		cm.clock.Go(cm.pulseCheck)
+	}
+	return nil
+}
+
+// Stops handling incoming messages and checking the primary's pulse
+func (cm *CentralManager) Stop() error {
+	select {
+	case <-cm.done:
+		return nil
+	default:
+	}
+	close(cm.done)
+	closeIdleConnections(cm.transport)
+	if cm.listener == nil {
+		return nil
+	}
+	return cm.listener.Close()
+}
+
+// Asks the other CMs to give back the primary status along with the MetaData, then tells the clients
+func (cm *CentralManager) reclaimPrimary() {
+	imBack := Message{
+		Type: IM_BACK,
+		Payload: Payload{
+			ImBack: ImBack{
+				CMIP: cm.IP,
+			},
+		},
+	}
+
+	for _, other := range cm.registry.cms() {
+		if other.IP == cm.IP {
+			continue
+		}
+		reply := cm.CallRPC(context.Background(), imBack, CENTRALMANAGER, -1, other.IP)
+		if !reply.Ack {
+			continue
+		}
+		logsystem.Printf("Primary CM [%s] reclaiming Primary title\n", cm.IP)
+		cm.mu.Lock()
+		cm.MetaData = reply.Payload
+		cm.Requests = reply.Requests
+		cm.Homes = reply.Homes
+		// gob does not transmit empty maps
+		if cm.MetaData == nil {
+			cm.MetaData = map[string]PageInfo{}
+		}
+		if cm.Requests == nil {
+			cm.Requests = map[string]RequestRecord{}
+		}
+		if cm.Homes == nil {
+			cm.Homes = map[string]HomePage{}
+		}
+		cm.dropInProgressRequests()
+		cm.mu.Unlock()
+		logsystem.Println("MetaData has been restored")
+
+		// Get all clients to inform change of CM
+		for _, client := range cm.registry.clients() {
+			changeCM := Message{
+				Type: CHANGE_CM,
+				Payload: Payload{
+					ChangeCM: ChangeCM{
+						NewCMIP: cm.IP,
+					},
+				},
+			}
+			cm.CallRPC(context.Background(), changeCM, CLIENT, client.ID, client.IP)
+		}
+	}
+}
+
+// Listens on the client's address and handles incoming messages until Stop.
+// In FIXED mode the client's manager is served on the same address.
+func (c *Client) Start() error {
+	handlers := map[string]Handler{
+		CLIENT: c.HandleIncomingMessage,
+	}
+	if c.manager != nil {
+		handlers[CENTRALMANAGER] = c.manager.HandleIncomingMessage
+		logsystem.Printf("Client %d is managing its pages in %s mode\n", c.ID, c.mode)
+	}
+	listener, err := c.transport.Listen(c.IP, handlers)
+	if err != nil {
+		return err
+	}
+	c.listener = listener
+	logsystem.Printf("Client %d is running at IP address: %s...\n", c.ID, c.IP)
+	return nil
+}
+
+// Stops handling incoming messages and closes the history file
+func (c *Client) Stop() error {
+	if err := c.history.close(); err != nil {
+		return err
+	}
+	closeIdleConnections(c.transport)
+	if c.listener == nil {
+		return nil
+	}
+	err := c.listener.Close()
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+// Manager algorithm the client runs
+func (c *Client) Mode() string {
+	return c.mode
+}
@@ -0,0 +1,132 @@
+package ivy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// FIFO lock per page, used by the CM to process the operations on a page one at a time.
+// A lock is held by a request ID rather than a goroutine: it is acquired while handling the
+// READ_REQUEST/WRITE_REQUEST and released when the matching confirmation arrives, which is
+// handled by a different RPC. Different pages are locked independently.
+type pageLockTable struct {
+	mu    sync.Mutex
+	pages map[string]*pageLock
+	clock clock
+}
+
+type pageLock struct {
+	holder string
+	// Releases the lock if the holder never confirms, e.g. because the requester died
+	lease clockTimer
+	queue []*pageLockWaiter
+}
+
+type pageLockWaiter struct {
+	holder  string
+	granted chan struct{}
+}
+
+func newPageLockTable(clock clock) *pageLockTable {
+	return &pageLockTable{pages: make(map[string]*pageLock), clock: clock}
+}
+
+// Blocks until holder owns the lock on pageNo. Waiters are granted the lock in arrival order.
+// Acquiring a lock the holder already owns returns immediately. Returns false without waiting
+// if holder is already queued for the lock, as someone else is waiting on its behalf.
+// Returns an error if ctx is done before the lock is granted, and holder leaves the queue.
+func (lt *pageLockTable) acquire(ctx context.Context, pageNo string, holder string) (bool, error) {
+	lt.mu.Lock()
+	lock, exists := lt.pages[pageNo]
+	if !exists {
+		lock = &pageLock{}
+		lt.pages[pageNo] = lock
+	}
+
+	if lock.holder == "" {
+		lt.grant(pageNo, lock, holder)
+		lt.mu.Unlock()
+		return true, nil
+	}
+	if lock.holder == holder {
+		lt.mu.Unlock()
+		return true, nil
+	}
+	for _, queued := range lock.queue {
+		if queued.holder == holder {
+			lt.mu.Unlock()
+			return false, nil
+		}
+	}
+
+	waiter := &pageLockWaiter{holder: holder, granted: make(chan struct{})}
+	lock.queue = append(lock.queue, waiter)
+	lt.mu.Unlock()
+
+	logsystem.Printf("Request %s waiting for lock on Page %s\n", holder, pageNo)
+	if _, err := receiveContext(lt.clock, ctx, waiter.granted); err == nil {
+		return true, nil
+	}
+
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	for i, queued := range lock.queue {
+		if queued == waiter {
+			lock.queue = append(lock.queue[:i:i], lock.queue[i+1:]...)
+			return false, fmt.Errorf("waiting for the lock on page %s: %w", pageNo, ctx.Err())
+		}
+	}
+	// Granted just as ctx was done
+	return true, nil
+}
+
+// Grants holder the lock on pageNo only if nobody holds it. Returns false without waiting otherwise.
+func (lt *pageLockTable) tryAcquire(pageNo string, holder string) bool {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	lock, exists := lt.pages[pageNo]
+	if exists && lock.holder != holder {
+		return false
+	}
+	if !exists {
+		lock = &pageLock{}
+		lt.pages[pageNo] = lock
+		lt.grant(pageNo, lock, holder)
+	}
+	return true
+}
+
+// Releases the lock on pageNo if it is held by holder and hands it to the next waiter.
+// Returns false if holder did not hold the lock.
+func (lt *pageLockTable) release(pageNo string, holder string) bool {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	lock, exists := lt.pages[pageNo]
+	if !exists || lock.holder != holder {
+		return false
+	}
+	lock.lease.Stop()
+	lock.holder = ""
+
+	if len(lock.queue) == 0 {
+		delete(lt.pages, pageNo)
+		return true
+	}
+	next := lock.queue[0]
+	lock.queue = lock.queue[1:]
+	lt.grant(pageNo, lock, next.holder)
+	close(next.granted)
+	return true
+}
+
+// Must be called with lt.mu held
+func (lt *pageLockTable) grant(pageNo string, lock *pageLock, holder string) {
+	lock.holder = holder
+	lock.lease = lt.clock.AfterFunc(PAGE_LOCK_LEASE, func() {
+		if lt.release(pageNo, holder) {
+			logwarning.Printf("Lock on Page %s held by request %s expired\n", pageNo, holder)
+		}
+	})
+}
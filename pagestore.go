@@ -0,0 +1,233 @@
+package ivy
+
+// Transient page states while a request for the page is in flight
+const (
+	FETCHING_READ  = "FETCHING_READ"
+	FETCHING_WRITE = "FETCHING_WRITE"
+)
+
+// Page.Access is the state of the page in this client's PageStore:
+//
+//	NIL        --read fault-->      FETCHING_READ  --PAGE_SEND--> READ (NIL if invalidated while fetching)
+//	NIL/READ   --write fault-->     FETCHING_WRITE --PAGE_SEND--> READWRITE
+//	                                FETCHING_WRITE --UPGRADE_GRANT--> READWRITE (a READ copy upgraded in place)
+//	READ       --INVALIDATE_COPY--> NIL
+//	READ/READWRITE --UPDATE_COPY--> READ (with the new content, under write-update)
+//	READWRITE  --READ_FORWARD-->    READ
+//	any        --WRITE_FORWARD-->   NIL
+//
+// A fetch that fails or is abandoned falls back to NIL. A page that is not in the PageStore is NIL.
+// Every transition happens under c.mu, which is never held across an RPC.
+
+// Returns the local copy of pageNo if it can be read without a page fault
+func (c *Client) localPage(pageNo string) (Page, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	page, exists := c.PageStore[pageNo]
+	if !exists || (page.Access != READ && page.Access != READWRITE) {
+		return Page{}, false
+	}
+	return page, true
+}
+
+// Moves pageNo into a FETCHING state before its request is sent.
+// The current content is kept, as this client may still be asked to forward it.
+func (c *Client) beginFetch(pageNo string, state string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	page, exists := c.PageStore[pageNo]
+	if !exists {
+		page = Page{Number: pageNo}
+	}
+	page.Access = state
+	c.PageStore[pageNo] = page
+	delete(c.invalidatedFetches, pageNo)
+}
+
+// Falls back to NIL if pageNo is still in the given FETCHING state
+func (c *Client) abortFetch(pageNo string, state string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	page, exists := c.PageStore[pageNo]
+	if exists && page.Access == state {
+		page.Access = NIL
+		c.PageStore[pageNo] = page
+	}
+	delete(c.invalidatedFetches, pageNo)
+}
+
+// Installs a page received in a PAGE_SEND with the given access. Returns the page as installed.
+// If an INVALIDATE_COPY arrived while the read was being fetched, the page is installed as NIL:
+// the read still returns the fetched content, ordered before the write that invalidated it.
+func (c *Client) installPage(page Page, access string) Page {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	page.Access = access
+	if access == READ && c.invalidatedFetches[page.Number] {
+		logwarning.Printf("Page %s was invalidated while being fetched, installing it as %s\n", page.Number, NIL)
+		page.Access = NIL
+	}
+	delete(c.invalidatedFetches, page.Number)
+	if page.Access == READWRITE {
+		c.applyAtomicOpsLocked(&page)
+	}
+	c.PageStore[page.Number] = page
+	return page
+}
+
+// Handles UPGRADE_GRANT: applies this client's write to the copy it holds and takes READWRITE access.
+// The copy is still current even if the write was abandoned in the meantime, as the CM keeps
+// every other write to the page waiting until the upgrade is confirmed.
+func (c *Client) upgradePage(pageNo string, offset int, data []byte) (Page, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	page, exists := c.PageStore[pageNo]
+	if !exists {
+		return Page{}, false
+	}
+	page.Content = applyWrite(page.Content, offset, data)
+	page.Access = READWRITE
+	c.applyAtomicOpsLocked(&page)
+	c.PageStore[pageNo] = page
+	delete(c.invalidatedFetches, pageNo)
+	return page, true
+}
+
+// Handles INVALIDATE_COPY. Invalidating a page this client does not hold is a no-op.
+func (c *Client) invalidatePage(pageNo string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	page, exists := c.PageStore[pageNo]
+	if !exists {
+		logwarning.Printf("Page %s doesn't exist in Node %d's PageStore, nothing to invalidate\n", pageNo, c.ID)
+		return
+	}
+
+	switch page.Access {
+	case FETCHING_READ:
+		// The copy being fetched is already stale, remember that for when it lands
+		c.invalidatedFetches[pageNo] = true
+	case FETCHING_WRITE:
+		// The PAGE_SEND will carry the latest content
+	default:
+		page.Access = NIL
+		c.PageStore[pageNo] = page
+	}
+}
+
+// Handles UPDATE_COPY: the write is applied to a valid copy, which stays readable.
+// A copy being fetched is left alone, as its PAGE_SEND carries content at least as new.
+func (c *Client) updatePage(pageNo string, offset int, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	page, exists := c.PageStore[pageNo]
+	if !exists || (page.Access != READ && page.Access != READWRITE) {
+		logwarning.Printf("Node %d holds no valid copy of Page %s, nothing to update\n", c.ID, pageNo)
+		return
+	}
+	page.Content = applyWrite(page.Content, offset, data)
+	page.Access = READ
+	c.PageStore[pageNo] = page
+}
+
+// Handles DROP_PAGE: removes a freed page. A page being fetched is kept for its pending request.
+func (c *Client) dropPage(pageNo string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	page, exists := c.PageStore[pageNo]
+	if exists && (page.Access == FETCHING_READ || page.Access == FETCHING_WRITE) {
+		return
+	}
+	delete(c.PageStore, pageNo)
+	delete(c.copySets, pageNo)
+}
+
+// Handles READ_FORWARD: the owner keeps a READ copy and returns the page to send
+func (c *Client) downgradePage(pageNo string) (Page, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	page, exists := c.PageStore[pageNo]
+	if !exists {
+		return Page{}, false
+	}
+	if page.Access == READWRITE {
+		page.Access = READ
+		c.PageStore[pageNo] = page
+	}
+	return page, true
+}
+
+// Handles WRITE_FORWARD: applies the write to the owner's copy, gives up access and returns the page to send
+func (c *Client) surrenderPage(pageNo string, offset int, data []byte) (Page, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	page, exists := c.PageStore[pageNo]
+	if !exists {
+		return Page{}, false
+	}
+	if page.Access != FETCHING_WRITE {
+		// When the owner is the write requester itself, it stays FETCHING_WRITE until its PAGE_SEND lands
+		page.Access = NIL
+	}
+	page.Content = applyWrite(page.Content, offset, data)
+	c.PageStore[pageNo] = page
+	return page, true
+}
+
+// Handles WRITE_FORWARD under write-update: applies the write to the owner's copy, which stays
+// readable, and returns the page to send
+func (c *Client) sharePage(pageNo string, offset int, data []byte) (Page, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	page, exists := c.PageStore[pageNo]
+	if !exists {
+		return Page{}, false
+	}
+	if page.Access == READWRITE {
+		page.Access = READ
+	}
+	page.Content = applyWrite(page.Content, offset, data)
+	c.PageStore[pageNo] = page
+	return page, true
+}
+
+// Writes data at offset in the local copy if this client already holds the page with READWRITE access.
+// Returns false on a page fault.
+func (c *Client) writeLocal(pageNo string, offset int, data []byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	page, exists := c.PageStore[pageNo]
+	if exists {
+		if page.Access == READWRITE {
+			logsystem.Printf("Page %s exists in local storage with %s access\n", pageNo, page.Access)
+			logsystem.Printf("Writing new content in local page...\n")
+			page.Content = applyWrite(page.Content, offset, data)
+			c.PageStore[pageNo] = page
+			return true
+		} else {
+			logsystem.Printf("Page %s exists in local storage with %s access\n", pageNo, page.Access)
+			logsystem.Println("Page Fault...")
+		}
+	} else {
+		logsystem.Printf("Page %s does not exist in local storage\n", pageNo)
+		logsystem.Println("Page Fault...")
+	}
+	return false
+}
+
+// Copy of the PageStore, safe to print while RPCs are being handled
+func (c *Client) Pages() map[string]Page {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pages := make(map[string]Page, len(c.PageStore))
+	for pageNo, page := range c.PageStore {
+		pages[pageNo] = page
+	}
+	return pages
+}
+
+func (c *Client) cmIP() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.CMIP
+}
@@ -0,0 +1,233 @@
+package ivy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Consistency models, selected with Config.Consistency
+const (
+	// Every read sees the latest write (the IVY protocol)
+	SEQUENTIAL = "sequential"
+	// Lazy release consistency: a client only sees the writes of others after acquiring the lock
+	// they were released under, or after a barrier
+	RELEASE = "release"
+)
+
+// Home-based lazy release consistency: every page has a home copy at its manager, and clients keep
+// their writes to cached copies as diffs until they release a lock or wait at a barrier.
+
+// Home copy of a page, kept by its manager under release consistency
+type HomePage struct {
+	Content []byte
+	Version int
+}
+
+// Write to apply to a home copy
+type Diff struct {
+	Offset  int
+	Content []byte
+}
+
+// Errors for the operations that rely on the manager's page table, which release consistency does without
+func (c *Client) requireSequential(operation string) error {
+	if c.consistency == RELEASE {
+		return fmt.Errorf("%s not supported under %s consistency", operation, RELEASE)
+	}
+	return nil
+}
+
+// Read under release consistency: the cached copy if valid, otherwise the home copy
+func (c *Client) releaseRead(ctx context.Context, pageNo string) (Page, error) {
+	if page, exists := c.localPage(pageNo); exists {
+		return page, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return Page{}, err
+	}
+
+	reply := c.CallRPC(ctx, Message{
+		Type: HOME_FETCH,
+		Payload: Payload{
+			HomeFetch: HomeFetch{
+				PageNo: pageNo,
+			},
+		},
+		FromID: c.ID,
+		FromIP: c.IP,
+	}, CENTRALMANAGER, -1, c.managerIP(pageNo))
+	notFound := reply.Err == errNoSuchPage.Error()
+	if reply.Err != "" && !notFound {
+		return Page{}, errors.New(reply.Err)
+	}
+	if !reply.Ack && !notFound {
+		return Page{}, fmt.Errorf("%s for page %s not acknowledged by CM", HOME_FETCH, pageNo)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	diffs := []Diff{}
+	for _, homeDiff := range c.unsentDiffs {
+		if homeDiff.Payload.HomeDiff.PageNo == pageNo {
+			diffs = append(diffs, homeDiff.Payload.HomeDiff.Diffs...)
+		}
+	}
+	diffs = append(diffs, c.diffs[pageNo]...)
+	if notFound && len(diffs) == 0 {
+		return Page{}, fmt.Errorf("%w: %s", errNoSuchPage, pageNo)
+	}
+	// This client's own writes that have not been released yet stay visible to it
+	content := applyWrite(reply.Home.Content, 0, nil)
+	for _, diff := range diffs {
+		content = applyWrite(content, diff.Offset, diff.Content)
+	}
+	page := Page{Number: pageNo, Content: content, Access: READ}
+	c.PageStore[pageNo] = page
+	c.homeVersions[pageNo] = reply.Home.Version
+	return page, nil
+}
+
+// Write under release consistency: applied to the cached copy and kept as a diff until the next release
+func (c *Client) releaseWrite(ctx context.Context, pageNo string, offset int, data []byte) error {
+	if _, err := c.releaseRead(ctx, pageNo); err != nil && !errors.Is(err, errNoSuchPage) {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	page, exists := c.PageStore[pageNo]
+	if !exists {
+		// Nobody has written the page yet
+		page = Page{Number: pageNo, Access: READ}
+	}
+	// A copy dropped in the meantime stays dropped, the next read applies the diff to a fresh copy
+	page.Content = applyWrite(page.Content, offset, data)
+	c.PageStore[pageNo] = page
+	c.diffs[pageNo] = append(c.diffs[pageNo], Diff{Offset: offset, Content: data})
+	return nil
+}
+
+// Sends the diffs of every page written since the last release to their homes. Returns the write
+// notices for the pages whose diffs were applied. Diffs that could not be sent are resent under the
+// same RequestID on the next release, so their home applies them once.
+func (c *Client) flushDiffs(ctx context.Context) (map[string]int, error) {
+	c.mu.Lock()
+	pageNos := make([]string, 0, len(c.diffs))
+	for pageNo := range c.diffs {
+		pageNos = append(pageNos, pageNo)
+	}
+	sort.Strings(pageNos)
+	unsent := c.unsentDiffs
+	for _, pageNo := range pageNos {
+		unsent = append(unsent, Message{
+			Type: HOME_DIFF,
+			Payload: Payload{
+				HomeDiff: HomeDiff{
+					PageNo: pageNo,
+					Diffs:  c.diffs[pageNo],
+				},
+			},
+			FromID:    c.ID,
+			FromIP:    c.IP,
+			RequestID: c.pending.newRequestID(c.ID),
+		})
+	}
+	c.diffs = make(map[string][]Diff)
+	c.unsentDiffs = nil
+	c.mu.Unlock()
+
+	notices := map[string]int{}
+	for i, homeDiff := range unsent {
+		pageNo := homeDiff.Payload.HomeDiff.PageNo
+		reply := c.CallRPC(ctx, homeDiff, CENTRALMANAGER, -1, c.managerIP(pageNo))
+		if !reply.Ack {
+			c.mu.Lock()
+			c.unsentDiffs = append(unsent[i:], c.unsentDiffs...)
+			c.mu.Unlock()
+			return notices, fmt.Errorf("%s for page %s not acknowledged by CM", HOME_DIFF, pageNo)
+		}
+
+		version := reply.Home.Version
+		if version > notices[pageNo] {
+			notices[pageNo] = version
+		}
+		c.mu.Lock()
+		// The copy is only as new as the home if nobody else's diff came in between
+		if c.homeVersions[pageNo] == version-1 {
+			c.homeVersions[pageNo] = version
+		}
+		c.mu.Unlock()
+	}
+	return notices, nil
+}
+
+// Drops the cached copies older than the write notices received with a lock
+func (c *Client) applyNotices(notices map[string]int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for pageNo, version := range notices {
+		page, exists := c.PageStore[pageNo]
+		if !exists || page.Access == NIL || c.homeVersions[pageNo] >= version {
+			continue
+		}
+		logsystem.Printf("Write notice for Page %s version %d, dropping cached version %d\n", pageNo, version, c.homeVersions[pageNo])
+		page.Access = NIL
+		c.PageStore[pageNo] = page
+	}
+}
+
+// Drops every cached copy, after a barrier
+func (c *Client) dropCachedPages() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for pageNo, page := range c.PageStore {
+		page.Access = NIL
+		c.PageStore[pageNo] = page
+	}
+}
+
+func (cm *CentralManager) handleHomeFetch(msg Message, reply *Reply) error {
+	pageNo := msg.Payload.HomeFetch.PageNo
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	home, exists := cm.Homes[pageNo]
+	if !exists {
+		return errNoSuchPage
+	}
+	reply.Home = home
+	return nil
+}
+
+// Applies a HOME_DIFF once. A resent one is answered with the current version, which only makes the
+// notices drop more copies than needed.
+func (cm *CentralManager) handleHomeDiff(msg Message, reply *Reply) {
+	homeDiff := msg.Payload.HomeDiff
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.pruneRequests()
+	if !cm.completeRequest(msg.RequestID) {
+		reply.Home = HomePage{Version: cm.Homes[homeDiff.PageNo].Version}
+		return
+	}
+	home := cm.Homes[homeDiff.PageNo]
+	for _, diff := range homeDiff.Diffs {
+		home.Content = applyWrite(home.Content, diff.Offset, diff.Content)
+	}
+	home.Version++
+	cm.Homes[homeDiff.PageNo] = home
+	logsystem.Printf("Applied %d diffs from Client %d to home of Page %s, now version %d\n", len(homeDiff.Diffs), msg.FromID, homeDiff.PageNo, home.Version)
+	reply.Home = HomePage{Version: home.Version}
+}
+
+// Copy of the home pages, to sync the backup CM. Contents are never modified in place.
+func (cm *CentralManager) homesSnapshot() map[string]HomePage {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	homes := make(map[string]HomePage, len(cm.Homes))
+	for pageNo, home := range cm.Homes {
+		homes[pageNo] = home
+	}
+	return homes
+}
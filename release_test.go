@@ -0,0 +1,235 @@
+package ivy
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+func homeFetches(c *Client) int {
+	c.stats.mu.Lock()
+	defer c.stats.mu.Unlock()
+	return c.stats.sent[HOME_FETCH]
+}
+
+func readValue(t *testing.T, c *Client, pageNo string) string {
+	t.Helper()
+	page, err := c.Read(pageNo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return historyValue(page.Content)
+}
+
+func locked(t *testing.T, c *Client, name string, critical func()) {
+	t.Helper()
+	m, err := c.NewMutex(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.LockContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	critical()
+	if err := m.UnlockContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Writes reach another client when it acquires the lock they were released under, and not before
+func TestReleaseWritesSeenAfterAcquire(t *testing.T) {
+	dsm := newTestDSM(t, Config{Consistency: RELEASE}, 2)
+	c1, c2 := dsm.clients[0], dsm.clients[1]
+	locked(t, c1, "x", func() {
+		if err := c1.Write("0", []byte("a")); err != nil {
+			t.Fatal(err)
+		}
+	})
+	locked(t, c2, "x", func() {
+		if got := readValue(t, c2, "0"); got != "a" {
+			t.Errorf("page read as %q under the lock, want %q", got, "a")
+		}
+	})
+
+	// Kept as a diff until the writer releases
+	if err := c1.Write("0", []byte("b")); err != nil {
+		t.Fatal(err)
+	}
+	if got := readValue(t, c1, "0"); got != "b" {
+		t.Errorf("writer read its own write as %q", got)
+	}
+	locked(t, c1, "x", func() {})
+	// The copy of client 2 stays valid until it acquires the lock
+	if got := readValue(t, c2, "0"); got != "a" {
+		t.Errorf("page read as %q before acquiring, want the cached %q", got, "a")
+	}
+	locked(t, c2, "x", func() {
+		if got := readValue(t, c2, "0"); got != "b" {
+			t.Errorf("page read as %q after acquiring, want %q", got, "b")
+		}
+	})
+}
+
+// Acquiring a lock only drops the copies of the pages written under it
+func TestReleaseNoticesDropOnlyWrittenPages(t *testing.T) {
+	dsm := newTestDSM(t, Config{Consistency: RELEASE}, 2)
+	c1, c2 := dsm.clients[0], dsm.clients[1]
+	locked(t, c1, "x", func() {
+		for _, pageNo := range []string{"0", "1"} {
+			if err := c1.Write(pageNo, []byte("a")); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+	locked(t, c2, "x", func() {
+		readValue(t, c2, "0")
+		readValue(t, c2, "1")
+	})
+	locked(t, c1, "x", func() {
+		if err := c1.Write("0", []byte("b")); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	fetches := homeFetches(c2)
+	locked(t, c2, "x", func() {
+		if got := readValue(t, c2, "0"); got != "b" {
+			t.Errorf("page 0 read as %q, want %q", got, "b")
+		}
+		if got := readValue(t, c2, "1"); got != "a" {
+			t.Errorf("page 1 read as %q, want %q", got, "a")
+		}
+	})
+	if fetched := homeFetches(c2) - fetches; fetched != 1 {
+		t.Errorf("fetched %d pages from their homes, want only page 0", fetched)
+	}
+	// A client's own release leaves it with a copy as new as the home
+	fetches = homeFetches(c1)
+	locked(t, c1, "x", func() { readValue(t, c1, "0") })
+	if homeFetches(c1) != fetches {
+		t.Error("writer fetched the page it released")
+	}
+}
+
+// Diffs of different clients to the same page merge at its home, and a barrier makes them visible to all
+func TestReleaseBarrierMergesDiffs(t *testing.T) {
+	dsm := newTestDSM(t, Config{Consistency: RELEASE}, 2)
+	c1, c2 := dsm.clients[0], dsm.clients[1]
+	if err := c1.WriteAt(0, []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c2.WriteAt(1, []byte("b")); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := c2.ReadAt(0, 2); err != nil || string(got) != "\x00b" {
+		t.Errorf("read %q, %v before the barrier, want only its own write", got, err)
+	}
+
+	contend(t, dsm.clients, 1, func(c *Client) error { return c.Barrier("b", 2) })
+	for _, c := range dsm.clients {
+		if got, err := c.ReadAt(0, 2); err != nil || string(got) != "ab" {
+			t.Errorf("client %d read %q, %v after the barrier, want %q", c.ID, got, err, "ab")
+		}
+	}
+	dsm.cm.mu.Lock()
+	defer dsm.cm.mu.Unlock()
+	if home := dsm.cm.Homes[pageName(0)]; home.Version != 2 {
+		t.Errorf("home at version %d, want 2", home.Version)
+	}
+}
+
+// A diff whose reply was lost is resent with its RequestID, and its home applies it once
+func TestReleaseResentDiffAppliedOnce(t *testing.T) {
+	dsm := newTestDSM(t, Config{Consistency: RELEASE}, 2)
+	c1, c2 := dsm.clients[0], dsm.clients[1]
+	lost := false
+	c1.transport = &lossyTransport{Transport: c1.transport, dropReply: func(msg Message) bool {
+		if msg.Type != HOME_DIFF || lost {
+			return false
+		}
+		lost = true
+		return true
+	}}
+	if err := c1.Write("0", []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c1.flushDiffs(context.Background()); err == nil {
+		t.Fatal("flush succeeded without a reply")
+	}
+	// Lands at the home before the resent diff
+	if err := c2.Write("0", []byte("b")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c2.flushDiffs(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c1.flushDiffs(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	dsm.cm.mu.Lock()
+	home := dsm.cm.Homes["0"]
+	dsm.cm.mu.Unlock()
+	if home.Version != 2 || historyValue(home.Content) != "b" {
+		t.Errorf("home at version %d with %q, want version 2 with %q", home.Version, historyValue(home.Content), "b")
+	}
+}
+
+// The diffs wait for the next release when the caller's deadline has passed
+func TestReleaseFlushStopsAtDeadline(t *testing.T) {
+	dsm := newTestDSM(t, Config{Consistency: RELEASE}, 1)
+	c := dsm.clients[0]
+	if err := c.Write("0", []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.flushDiffs(ctx); err == nil {
+		t.Fatal("flush succeeded after its deadline")
+	}
+	if _, err := c.flushDiffs(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	dsm.cm.mu.Lock()
+	defer dsm.cm.mu.Unlock()
+	if home := dsm.cm.Homes["0"]; home.Version != 1 || historyValue(home.Content) != "a" {
+		t.Errorf("home at version %d with %q, want the write applied once", home.Version, historyValue(home.Content))
+	}
+}
+
+// An unlock that cannot send the writes made under the lock fails and keeps the lock
+func TestReleaseUnlockKeepsLockUntilWritesSent(t *testing.T) {
+	dsm := newTestDSM(t, Config{Consistency: RELEASE}, 2)
+	c1, c2 := dsm.clients[0], dsm.clients[1]
+	var unreachable atomic.Bool
+	unreachable.Store(true)
+	c1.transport = &lossyTransport{Transport: c1.transport, drop: func(msg Message) bool {
+		return msg.Type == HOME_DIFF && unreachable.Load()
+	}}
+	m1, _ := c1.NewMutex("x")
+	m2, _ := c2.NewMutex("x")
+	if err := m1.LockContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := c1.Write("0", []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := m1.UnlockContext(context.Background()); !errors.Is(err, ErrWritesNotReleased) {
+		t.Fatalf("got %v, want %v", err, ErrWritesNotReleased)
+	}
+	ctx, cancel := dsm.clock.WithTimeout(context.Background(), LOCK_LEASE)
+	defer cancel()
+	if err := m2.LockContext(ctx); err == nil {
+		t.Fatal("lock granted while its writes were not released")
+	}
+
+	unreachable.Store(false)
+	if err := m1.UnlockContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	locked(t, c2, "x", func() {
+		if got := readValue(t, c2, "0"); got != "a" {
+			t.Errorf("page read as %q, want %q", got, "a")
+		}
+	})
+}
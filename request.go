@@ -0,0 +1,111 @@
+package ivy
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Status of a request in the CM's request log
+const (
+	IN_PROGRESS = "IN_PROGRESS"
+	DONE        = "DONE"
+)
+
+// Entry in the CM's request log, used to deduplicate client retries
+type RequestRecord struct {
+	Status  string
+	Started time.Time
+}
+
+// Result of a read/write request, delivered once the PAGE_SEND has been confirmed with the CM
+type requestResult struct {
+	page Page
+	err  error
+}
+
+// A request sent by this client that has not completed yet
+type pendingRequest struct {
+	msg   Message
+	done  chan requestResult
+	retry chan struct{}
+}
+
+// Tracks the requests of a client that are still waiting for their PAGE_SEND, keyed by request ID
+type pendingTable struct {
+	mu       sync.Mutex
+	epoch    int64
+	seq      uint64
+	requests map[string]*pendingRequest
+}
+
+// Request IDs carry the time the table was created at, which sets apart the restarts of a client
+func newPendingTable(now time.Time) *pendingTable {
+	return &pendingTable{
+		epoch:    now.UnixNano(),
+		requests: make(map[string]*pendingRequest),
+	}
+}
+
+// Request IDs are unique across restarts of the same client
+func (pt *pendingTable) newRequestID(clientID int) string {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	pt.seq++
+	return fmt.Sprintf("%d-%x-%d", clientID, pt.epoch, pt.seq)
+}
+
+// Registers msg, which must already carry a RequestID, as pending
+func (pt *pendingTable) add(msg Message) *pendingRequest {
+	req := &pendingRequest{
+		msg:   msg,
+		done:  make(chan requestResult, 1),
+		retry: make(chan struct{}, 1),
+	}
+	pt.mu.Lock()
+	pt.requests[msg.RequestID] = req
+	pt.mu.Unlock()
+	return req
+}
+
+func (pt *pendingTable) remove(requestID string) {
+	pt.mu.Lock()
+	delete(pt.requests, requestID)
+	pt.mu.Unlock()
+}
+
+// Wakes up the caller waiting on requestID, if any
+func (pt *pendingTable) complete(requestID string, result requestResult) {
+	pt.mu.Lock()
+	req, exists := pt.requests[requestID]
+	delete(pt.requests, requestID)
+	pt.mu.Unlock()
+	if !exists {
+		return
+	}
+	req.done <- result
+}
+
+// Asks the caller waiting on requestID, if any, to resend it
+func (pt *pendingTable) retry(requestID string) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	if req, exists := pt.requests[requestID]; exists {
+		select {
+		case req.retry <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Asks every pending request to be resent, e.g. after CHANGE_CM
+func (pt *pendingTable) retryAll() {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	for _, req := range pt.requests {
+		select {
+		case req.retry <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package ivy
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+)
+
+// Typed values stored at an address of the shared address space. Getters and setters are ReadAt and
+// WriteAt calls, so they go through the coherence protocol like any other access, with the same
+// guarantee: an access that stays within one page is sequentially consistent, one spanning several
+// pages is not atomic. Get and Set of a SharedBytes, SharedMap or SharedSlice are each a single ReadAt
+// or WriteAt, so a value within one page is never seen half written.
+//
+// The updates of a SharedMap or SharedSlice (Set, Delete, Append) read and write the value in one
+// atomic operation. A value within one page is updated under exclusive ownership of the page, as by
+// CompareAndSwap. A value spanning several pages, or any value under release consistency, is updated
+// holding the lock named after its address, so those updates are not available in DYNAMIC mode.
+
+// Bytes of the length header in front of a SharedBytes, SharedMap or SharedSlice
+const SHARED_HEADER_SIZE = 4
+
+// int64 at an 8-byte aligned address, so it never spans two pages
+type SharedInt64 struct {
+	c    *Client
+	addr int
+}
+
+func NewSharedInt64(c *Client, addr int) (*SharedInt64, error) {
+	if addr < 0 || addr%8 != 0 {
+		return nil, fmt.Errorf("SharedInt64 address %d is not 8-byte aligned", addr)
+	}
+	return &SharedInt64{c: c, addr: addr}, nil
+}
+
+// Memory nobody has written yet reads as 0
+func (v *SharedInt64) Get() (int64, error) {
+	buf, err := v.c.ReadAt(v.addr, 8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.LittleEndian.Uint64(buf)), nil
+}
+
+func (v *SharedInt64) Set(n int64) error {
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, uint64(n))
+	return v.c.WriteAt(v.addr, buf)
+}
+
+// Byte string of up to size bytes, stored as a length header followed by the bytes.
+// Takes SHARED_HEADER_SIZE+size bytes of the address space from addr.
+type SharedBytes struct {
+	c    *Client
+	addr int
+	size int
+}
+
+func NewSharedBytes(c *Client, addr int, size int) (*SharedBytes, error) {
+	if addr < 0 || size < 0 {
+		return nil, fmt.Errorf("invalid SharedBytes region of %d bytes at address %d", size, addr)
+	}
+	return &SharedBytes{c: c, addr: addr, size: size}, nil
+}
+
+// Memory nobody has written yet reads as an empty byte string. Reads the header and the whole region
+// at once, so the length and the bytes come from the same Set.
+func (v *SharedBytes) Get() ([]byte, error) {
+	buf, err := v.c.ReadAt(v.addr, SHARED_HEADER_SIZE+v.size)
+	if err != nil {
+		return nil, err
+	}
+	return v.decode(buf)
+}
+
+func (v *SharedBytes) Set(b []byte) error {
+	buf, err := v.encode(b)
+	if err != nil {
+		return err
+	}
+	return v.c.WriteAt(v.addr, buf)
+}
+
+// Bytes held by the region read into buf
+func (v *SharedBytes) decode(buf []byte) ([]byte, error) {
+	n := int(binary.LittleEndian.Uint32(buf))
+	if n > v.size {
+		return nil, fmt.Errorf("SharedBytes at address %d holds %d bytes, more than its %d byte size", v.addr, n, v.size)
+	}
+	return buf[SHARED_HEADER_SIZE : SHARED_HEADER_SIZE+n], nil
+}
+
+// Header and bytes to write at the start of the region
+func (v *SharedBytes) encode(b []byte) ([]byte, error) {
+	if len(b) > v.size {
+		return nil, fmt.Errorf("%d bytes do not fit in the %d byte SharedBytes at address %d", len(b), v.size, v.addr)
+	}
+	buf := make([]byte, SHARED_HEADER_SIZE+len(b))
+	binary.LittleEndian.PutUint32(buf, uint32(len(b)))
+	copy(buf[SHARED_HEADER_SIZE:], b)
+	return buf, nil
+}
+
+// Replaces the bytes with update(bytes), with no other update in between
+func (v *SharedBytes) update(update func(b []byte) ([]byte, error)) error {
+	ctx, cancel := v.c.clock.WithTimeout(context.Background(), REQUEST_TIMEOUT)
+	defer cancel()
+	pageIndex := v.addr / PAGE_SIZE
+	if v.c.consistency == RELEASE || (v.addr+SHARED_HEADER_SIZE+v.size-1)/PAGE_SIZE != pageIndex {
+		return v.updateLocked(ctx, update)
+	}
+
+	offset := v.addr % PAGE_SIZE
+	var updateErr error
+	err := v.c.atomic(ctx, pageName(pageIndex), func(content []byte) []byte {
+		page := applyWrite(content, 0, nil)
+		var buf []byte
+		current, err := v.decode(page[offset : offset+SHARED_HEADER_SIZE+v.size])
+		if err == nil {
+			var updated []byte
+			if updated, err = update(current); err == nil {
+				buf, err = v.encode(updated)
+			}
+		}
+		updateErr = err
+		if err != nil {
+			return nil
+		}
+		return applyWrite(page, offset, buf)
+	})
+	if err != nil {
+		return err
+	}
+	return updateErr
+}
+
+// Same as update, holding the lock named after the address of the region
+func (v *SharedBytes) updateLocked(ctx context.Context, update func(b []byte) ([]byte, error)) error {
+	lock, err := v.c.NewMutex(fmt.Sprintf("shared@%d", v.addr))
+	if err != nil {
+		return fmt.Errorf("updating SharedBytes at address %d: %w", v.addr, err)
+	}
+	if err := lock.LockContext(ctx); err != nil {
+		return err
+	}
+	current, err := v.Get()
+	if err == nil {
+		var updated []byte
+		if updated, err = update(current); err == nil {
+			err = v.Set(updated)
+		}
+	}
+	if unlockErr := lock.UnlockContext(context.Background()); err == nil {
+		err = unlockErr
+	}
+	return err
+}
+
+// Map gob-encoded into a SharedBytes of size bytes. Every call reads the whole map, and every
+// update writes it back atomically.
+type SharedMap[K comparable, V any] struct {
+	bytes *SharedBytes
+}
+
+func NewSharedMap[K comparable, V any](c *Client, addr int, size int) (*SharedMap[K, V], error) {
+	b, err := NewSharedBytes(c, addr, size)
+	if err != nil {
+		return nil, err
+	}
+	return &SharedMap[K, V]{bytes: b}, nil
+}
+
+// Copy of the whole map
+func (m *SharedMap[K, V]) Load() (map[K]V, error) {
+	entries := map[K]V{}
+	if err := loadShared(m.bytes, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (m *SharedMap[K, V]) Get(key K) (V, bool, error) {
+	entries, err := m.Load()
+	if err != nil {
+		var zero V
+		return zero, false, err
+	}
+	value, exists := entries[key]
+	return value, exists, nil
+}
+
+func (m *SharedMap[K, V]) Set(key K, value V) error {
+	return updateShared(m.bytes, func(entries *map[K]V) error {
+		if *entries == nil {
+			*entries = map[K]V{}
+		}
+		(*entries)[key] = value
+		return nil
+	})
+}
+
+func (m *SharedMap[K, V]) Delete(key K) error {
+	return updateShared(m.bytes, func(entries *map[K]V) error {
+		delete(*entries, key)
+		return nil
+	})
+}
+
+func (m *SharedMap[K, V]) Len() (int, error) {
+	entries, err := m.Load()
+	return len(entries), err
+}
+
+// Slice gob-encoded into a SharedBytes of size bytes. Every call reads the whole slice, and every
+// update writes it back atomically.
+type SharedSlice[T any] struct {
+	bytes *SharedBytes
+}
+
+func NewSharedSlice[T any](c *Client, addr int, size int) (*SharedSlice[T], error) {
+	b, err := NewSharedBytes(c, addr, size)
+	if err != nil {
+		return nil, err
+	}
+	return &SharedSlice[T]{bytes: b}, nil
+}
+
+// Copy of the whole slice
+func (s *SharedSlice[T]) Load() ([]T, error) {
+	var elems []T
+	if err := loadShared(s.bytes, &elems); err != nil {
+		return nil, err
+	}
+	return elems, nil
+}
+
+func (s *SharedSlice[T]) Get(i int) (T, error) {
+	var zero T
+	elems, err := s.Load()
+	if err != nil {
+		return zero, err
+	}
+	if i < 0 || i >= len(elems) {
+		return zero, fmt.Errorf("index %d out of range of SharedSlice of length %d", i, len(elems))
+	}
+	return elems[i], nil
+}
+
+func (s *SharedSlice[T]) Set(i int, elem T) error {
+	return updateShared(s.bytes, func(elems *[]T) error {
+		if i < 0 || i >= len(*elems) {
+			return fmt.Errorf("index %d out of range of SharedSlice of length %d", i, len(*elems))
+		}
+		(*elems)[i] = elem
+		return nil
+	})
+}
+
+func (s *SharedSlice[T]) Append(elems ...T) error {
+	return updateShared(s.bytes, func(existing *[]T) error {
+		*existing = append(*existing, elems...)
+		return nil
+	})
+}
+
+func (s *SharedSlice[T]) Len() (int, error) {
+	elems, err := s.Load()
+	return len(elems), err
+}
+
+// Decodes the value in b into v. An empty SharedBytes leaves v unchanged.
+func loadShared(b *SharedBytes, v any) error {
+	encoded, err := b.Get()
+	if err != nil {
+		return err
+	}
+	return decodeShared(b, encoded, v)
+}
+
+// Replaces the value in b with the one update makes of it, with no other update in between
+func updateShared[T any](b *SharedBytes, update func(v *T) error) error {
+	return b.update(func(encoded []byte) ([]byte, error) {
+		var v T
+		if err := decodeShared(b, encoded, &v); err != nil {
+			return nil, err
+		}
+		if err := update(&v); err != nil {
+			return nil, err
+		}
+		return encodeShared(b, v)
+	})
+}
+
+func decodeShared(b *SharedBytes, encoded []byte, v any) error {
+	if len(encoded) == 0 {
+		return nil
+	}
+	if err := gob.NewDecoder(bytes.NewReader(encoded)).Decode(v); err != nil {
+		return fmt.Errorf("decoding shared value at address %d: %w", b.addr, err)
+	}
+	return nil
+}
+
+func encodeShared(b *SharedBytes, v any) ([]byte, error) {
+	var encoded bytes.Buffer
+	if err := gob.NewEncoder(&encoded).Encode(v); err != nil {
+		return nil, fmt.Errorf("encoding shared value at address %d: %w", b.addr, err)
+	}
+	return encoded.Bytes(), nil
+}
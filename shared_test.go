@@ -0,0 +1,179 @@
+package ivy
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestSharedInt64(t *testing.T) {
+	dsm := newTestDSM(t, Config{}, 2)
+	if _, err := NewSharedInt64(dsm.clients[0], 4); err == nil {
+		t.Error("unaligned SharedInt64 accepted")
+	}
+	v1, _ := NewSharedInt64(dsm.clients[0], 8)
+	v2, _ := NewSharedInt64(dsm.clients[1], 8)
+	if n, err := v2.Get(); err != nil || n != 0 {
+		t.Fatalf("unwritten value read as %d, %v", n, err)
+	}
+	if err := v1.Set(-42); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := v2.Get(); err != nil || n != -42 {
+		t.Errorf("read %d, %v, want -42", n, err)
+	}
+}
+
+func TestSharedBytes(t *testing.T) {
+	dsm := newTestDSM(t, Config{}, 2)
+	b1, _ := NewSharedBytes(dsm.clients[0], 100, 16)
+	b2, _ := NewSharedBytes(dsm.clients[1], 100, 16)
+	if got, err := b2.Get(); err != nil || len(got) != 0 {
+		t.Fatalf("unwritten value read as %q, %v", got, err)
+	}
+	if err := b1.Set([]byte("hello, world")); err != nil {
+		t.Fatal(err)
+	}
+	if err := b1.Set([]byte("hi")); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := b2.Get(); err != nil || string(got) != "hi" {
+		t.Errorf("read %q, %v, want %q", got, err, "hi")
+	}
+	if err := b1.Set(make([]byte, 17)); err == nil {
+		t.Error("value larger than the region accepted")
+	}
+}
+
+// The length and the bytes read come from the same Set, even while another client sets the value
+func TestSharedBytesGetNotTorn(t *testing.T) {
+	dsm := newTCPTestDSM(t, Config{}, 2)
+	writer, _ := NewSharedBytes(dsm.clients[0], 0, 32)
+	reader, _ := NewSharedBytes(dsm.clients[1], 0, 32)
+	values := [][]byte{bytes.Repeat([]byte("a"), 20), []byte("bbb")}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			if err := writer.Set(values[i%2]); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		got, err := reader.Get()
+		if err != nil {
+			t.Error(err)
+			break
+		}
+		if len(got) != 0 && !bytes.Equal(got, values[0]) && !bytes.Equal(got, values[1]) {
+			t.Errorf("read torn value %q", got)
+			break
+		}
+	}
+	wg.Wait()
+}
+
+func TestSharedMap(t *testing.T) {
+	dsm := newTestDSM(t, Config{}, 2)
+	m1, _ := NewSharedMap[string, int](dsm.clients[0], PAGE_SIZE, 256)
+	m2, _ := NewSharedMap[string, int](dsm.clients[1], PAGE_SIZE, 256)
+	if err := m1.Set("a", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := m2.Set("b", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := m1.Delete("a"); err != nil {
+		t.Fatal(err)
+	}
+	if value, exists, err := m2.Get("b"); err != nil || !exists || value != 2 {
+		t.Errorf("b read as %d, %v, %v", value, exists, err)
+	}
+	if _, exists, _ := m2.Get("a"); exists {
+		t.Error("deleted key still in the map")
+	}
+	if n, err := m2.Len(); err != nil || n != 1 {
+		t.Errorf("length %d, %v, want 1", n, err)
+	}
+	if err := m1.Set("big", 1<<62); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		if err := m1.Set(fmt.Sprint(i), i); err != nil {
+			return
+		}
+	}
+	t.Error("map outgrowing its region was stored")
+}
+
+func TestSharedSlice(t *testing.T) {
+	dsm := newTestDSM(t, Config{}, 2)
+	// Spans two pages
+	s1, _ := NewSharedSlice[string](dsm.clients[0], 2*PAGE_SIZE-10, 64)
+	s2, _ := NewSharedSlice[string](dsm.clients[1], 2*PAGE_SIZE-10, 64)
+	if err := s1.Append("x", "y"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s2.Set(1, "z"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s2.Set(2, "out of range"); err == nil {
+		t.Error("Set out of range accepted")
+	}
+	if got, err := s1.Load(); err != nil || !reflect.DeepEqual(got, []string{"x", "z"}) {
+		t.Errorf("loaded %v, %v", got, err)
+	}
+	if elem, err := s1.Get(0); err != nil || elem != "x" {
+		t.Errorf("element 0 read as %q, %v", elem, err)
+	}
+	if _, err := s1.Get(-1); err == nil {
+		t.Error("Get out of range accepted")
+	}
+}
+
+// Concurrent updates are all kept, whether the value is updated under ownership of its page or under
+// its lock
+func TestSharedSliceConcurrentUpdates(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		config Config
+		addr   int
+	}{
+		{"central", Config{Mode: CENTRAL}, 0},
+		{"dynamic", Config{Mode: DYNAMIC}, 0},
+		{"spanning pages", Config{Mode: CENTRAL}, PAGE_SIZE - 10},
+		{"release", Config{Consistency: RELEASE}, 0},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			dsm := newTestDSM(t, test.config, 2)
+			contend(t, dsm.clients, 5, func(c *Client) error {
+				s, _ := NewSharedSlice[int](c, test.addr, 256)
+				return s.Append(c.ID)
+			})
+
+			c := dsm.clients[0]
+			if c.consistency == RELEASE {
+				// Acquires the updates of the other client
+				lock, _ := c.NewMutex(fmt.Sprintf("shared@%d", test.addr))
+				lock.Lock()
+				defer lock.Unlock()
+			}
+			s, _ := NewSharedSlice[int](c, test.addr, 256)
+			got, err := s.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Ints(got)
+			if want := []int{1, 1, 1, 1, 1, 2, 2, 2, 2, 2}; !reflect.DeepEqual(got, want) {
+				t.Errorf("slice holds %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,841 @@
+package ivy
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Simulations run a CM, a backup CM and clients inside one process, over an in-memory network instead
+// of TCP. Messages still go through gob, so they arrive as they would over net/rpc. A scheduler
+// delivers one message at a time, once every goroutine of the simulation waits on it, picking the
+// next message with a generator seeded by the simulation's seed. The same generator decides which
+// messages are lost and when nodes crash and restart, and the timers of the protocol run on a clock
+// the scheduler moves, so a seed replays the same run.
+
+// Held by the running simulation
+var simulating sync.Mutex
+
+type SimConfig struct {
+	// Seed of the order of the messages, the faults and the workload
+	Seed int64
+	// Manager algorithm, as in Config. Defaults to CENTRAL.
+	Mode string
+	// Number of clients. Defaults to 3.
+	Clients int
+	// Runs a backup CM in CENTRAL and IMPROVED modes
+	Backup bool
+	// Reads and writes made by each client. Defaults to 20.
+	Ops int
+	// Number of pages the clients read and write, P0 to P<Pages-1>. Defaults to 3.
+	Pages int
+	// Probability that a message is lost
+	DropRate float64
+	// Probability that a CM crashes after a message is delivered, if the other CM is running
+	CrashRate float64
+	// Probability that a client crashes after a message is delivered, in CENTRAL and IMPROVED modes.
+	// It restarts without the pages it held, so the writes it owned are lost.
+	ClientCrashRate float64
+	// Number of messages delivered between the crash of a node and its restart. Defaults to 50.
+	RestartAfter int
+}
+
+type SimResult struct {
+	Seed int64
+	// Messages delivered and faults injected, in order
+	Trace []string
+	// Reads and writes of the clients
+	History []HistoryOp
+	Check   HistoryResult
+}
+
+func (cfg SimConfig) withDefaults() SimConfig {
+	if cfg.Mode == "" {
+		cfg.Mode = CENTRAL
+	}
+	if cfg.Clients < 1 {
+		cfg.Clients = 3
+	}
+	if cfg.Ops < 1 {
+		cfg.Ops = 20
+	}
+	if cfg.Pages < 1 {
+		cfg.Pages = 3
+	}
+	if cfg.RestartAfter < 1 {
+		cfg.RestartAfter = 50
+	}
+	return cfg
+}
+
+// State of a running simulation
+type simulation struct {
+	cfg     SimConfig
+	nodeCfg Config
+	network *memoryNetwork
+
+	// Guards cms, clients, restartAt, crashedClient, clientRestartAt, restarts and stopping
+	mu      sync.Mutex
+	cms     []*simCM
+	clients []*simClient
+	// Step at which the crashed CM restarts, 0 if none is down
+	restartAt int
+	// Client that crashed and the step at which it restarts
+	crashedClient   *simClient
+	clientRestartAt int
+	// Closed by each restart of a CM once done
+	restarts []chan struct{}
+	// Set once the workload is done, stops the faults
+	stopping bool
+}
+
+type simCM struct {
+	addr    string
+	primary bool
+	cm      *CentralManager
+	down    bool
+}
+
+type simClient struct {
+	addr   string
+	client *Client
+	// Set from the crash of the client until its workload restarts it
+	down bool
+	// Closed once the crashed client may restart
+	restart chan struct{}
+}
+
+// Runs a DSM with the workload and faults drawn from cfg.Seed, and checks the history of its reads and writes
+func Simulate(cfg SimConfig) (SimResult, error) {
+	simulating.Lock()
+	defer simulating.Unlock()
+	cfg = cfg.withDefaults()
+	result := SimResult{Seed: cfg.Seed}
+	dataDir, err := os.MkdirTemp("", "ivy-sim")
+	if err != nil {
+		return result, err
+	}
+	defer os.RemoveAll(dataDir)
+
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	sim := &simulation{
+		cfg:     cfg,
+		network: newMemoryNetwork(rand.New(rand.NewSource(rng.Int63())), cfg.DropRate),
+		nodeCfg: Config{
+			Mode:       cfg.Mode,
+			DataDir:    dataDir,
+			HistoryDir: filepath.Join(dataDir, "history"),
+		},
+	}
+	if cfg.Mode == FIXED {
+		sim.nodeCfg.Nodes = cfg.Clients
+	}
+	clk := sim.network.scheduler.clock
+	sim.nodeCfg.clock = clk
+	sim.network.scheduler.afterStep = sim.injectFaults
+	sim.network.scheduler.idle = sim.restartIdle
+	// This goroutine runs the simulation, so the scheduler waits for it like for the nodes' goroutines
+	clk.goroutines.enter()
+	go sim.network.scheduler.run()
+	defer sim.network.close()
+
+	if cfg.Mode == CENTRAL || cfg.Mode == IMPROVED {
+		numCMs := 1
+		if cfg.Backup {
+			numCMs = 2
+		}
+		for i := 1; i <= numCMs; i++ {
+			nodeCfg := sim.nodeCfg
+			nodeCfg.Addr = fmt.Sprintf("cm%d", i)
+			nodeCfg.Transport = sim.network.endpoint(nodeCfg.Addr)
+			cm, err := NewCentralManager(nodeCfg)
+			if err != nil {
+				return result, err
+			}
+			if err := cm.Start(); err != nil {
+				return result, err
+			}
+			defer cm.Stop()
+			sim.cms = append(sim.cms, &simCM{addr: nodeCfg.Addr, primary: i == 1, cm: cm})
+		}
+	}
+
+	for i := 1; i <= cfg.Clients; i++ {
+		nodeCfg := sim.nodeCfg
+		nodeCfg.Addr = fmt.Sprintf("client%d", i)
+		nodeCfg.Transport = sim.network.endpoint(nodeCfg.Addr)
+		c, err := NewClient(nodeCfg)
+		if err != nil {
+			return result, err
+		}
+		if err := c.Start(); err != nil {
+			return result, err
+		}
+		sim.clients = append(sim.clients, &simClient{addr: nodeCfg.Addr, client: c})
+	}
+
+	done := make(chan struct{}, len(sim.clients))
+	for _, node := range sim.clients {
+		node, workloadRng := node, rand.New(rand.NewSource(rng.Int63()))
+		clk.Go(func() {
+			defer func() { done <- struct{}{} }()
+			sim.runWorkload(node, workloadRng)
+		})
+	}
+	for range sim.clients {
+		clk.Select(done)
+	}
+	sim.mu.Lock()
+	sim.stopping = true
+	restarts := sim.restarts
+	sim.mu.Unlock()
+	for _, restarted := range restarts {
+		clk.Select(restarted)
+	}
+	sim.mu.Lock()
+	for _, node := range sim.clients {
+		if err := node.client.Stop(); err != nil {
+			sim.mu.Unlock()
+			return result, err
+		}
+	}
+	for _, node := range sim.cms {
+		node.cm.Stop()
+	}
+	sim.mu.Unlock()
+	// The scheduler would otherwise wait on the checker
+	sim.network.close()
+
+	result.Trace = sim.network.scheduler.log()
+	result.History, err = LoadHistory(sim.nodeCfg.HistoryDir)
+	if err != nil {
+		return result, err
+	}
+	result.Check, err = CheckHistory(result.History)
+	return result, err
+}
+
+// Random reads and writes by one client. Each write has its own value.
+func (sim *simulation) runWorkload(node *simClient, rng *rand.Rand) {
+	for i := 0; i < sim.cfg.Ops; i++ {
+		c, err := sim.liveClient(node)
+		if err != nil {
+			logerror.Printf("Could not restart %s: %v\n", node.addr, err)
+			return
+		}
+		pageNo := fmt.Sprintf("P%d", rng.Intn(sim.cfg.Pages))
+		if rng.Intn(2) == 0 {
+			c.Write(pageNo, []byte(fmt.Sprintf("Content %d by Client %d", i, c.ID)))
+		} else {
+			c.Read(pageNo)
+		}
+	}
+}
+
+// Client of node, restarted once the scheduler lets it if it crashed
+func (sim *simulation) liveClient(node *simClient) (*Client, error) {
+	sim.mu.Lock()
+	c, down, restart := node.client, node.down, node.restart
+	sim.mu.Unlock()
+	if !down {
+		return c, nil
+	}
+	// Closes the history of the crashed client before the restarted one appends to it
+	c.Stop()
+	sim.nodeCfg.clock.Select(restart)
+
+	nodeCfg := sim.nodeCfg
+	nodeCfg.Transport = sim.network.endpoint(node.addr)
+	restarted, err := RestartClient(nodeCfg, c.ID)
+	if err == nil {
+		err = restarted.Start()
+	}
+	if err != nil {
+		return nil, err
+	}
+	sim.mu.Lock()
+	node.client = restarted
+	node.down = false
+	sim.mu.Unlock()
+	return restarted, nil
+}
+
+// Crashes and restarts the nodes. Called by the scheduler after every step, with its random generator.
+func (sim *simulation) injectFaults(step int, rng *rand.Rand) {
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	if sim.stopping {
+		return
+	}
+	sim.injectCMFaults(step, rng)
+	if sim.cfg.ClientCrashRate > 0 && (sim.cfg.Mode == CENTRAL || sim.cfg.Mode == IMPROVED) {
+		sim.injectClientFaults(step, rng)
+	}
+}
+
+// Must be called with sim.mu held
+func (sim *simulation) injectCMFaults(step int, rng *rand.Rand) {
+	if len(sim.cms) < 2 {
+		return
+	}
+
+	if sim.restartAt != 0 {
+		if step >= sim.restartAt {
+			sim.restartCMs(step)
+		}
+		return
+	}
+
+	if rng.Float64() >= sim.cfg.CrashRate {
+		return
+	}
+	node := sim.cms[rng.Intn(len(sim.cms))]
+	sim.network.scheduler.note(fmt.Sprintf("%d: crash %s", step, node.addr))
+	node.down = true
+	sim.network.crash(node.addr)
+	node.cm.Stop()
+	sim.restartAt = step + sim.cfg.RestartAfter
+}
+
+// Crashes a client, which its workload restarts once the scheduler lets it. Must be called with
+// sim.mu held.
+func (sim *simulation) injectClientFaults(step int, rng *rand.Rand) {
+	if sim.crashedClient != nil {
+		if step >= sim.clientRestartAt {
+			sim.restartClient(step)
+		}
+		return
+	}
+
+	if rng.Float64() >= sim.cfg.ClientCrashRate {
+		return
+	}
+	node := sim.clients[rng.Intn(len(sim.clients))]
+	if node.down {
+		return
+	}
+	sim.network.scheduler.note(fmt.Sprintf("%d: crash %s", step, node.addr))
+	node.down = true
+	node.restart = make(chan struct{})
+	sim.network.crash(node.addr)
+	sim.crashedClient = node
+	sim.clientRestartAt = step + sim.cfg.RestartAfter
+}
+
+// Restarts the crashed nodes at once, as no message is left to count down to their restart. Called by
+// the scheduler when nothing else can happen, reports whether a node was down.
+func (sim *simulation) restartIdle(step int) bool {
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	if sim.stopping || (sim.restartAt == 0 && sim.crashedClient == nil) {
+		return false
+	}
+	if sim.restartAt != 0 {
+		sim.restartCMs(step)
+	}
+	if sim.crashedClient != nil {
+		sim.restartClient(step)
+	}
+	return true
+}
+
+// Must be called with sim.mu held
+func (sim *simulation) restartCMs(step int) {
+	sim.restartAt = 0
+	for _, node := range sim.cms {
+		if node.down {
+			sim.network.scheduler.note(fmt.Sprintf("%d: restart %s", step, node.addr))
+			node.down = false
+			// The restarted CM sends messages, which wait for the scheduler
+			restarted := make(chan struct{})
+			sim.restarts = append(sim.restarts, restarted)
+			sim.nodeCfg.clock.Go(func() {
+				defer close(restarted)
+				sim.restartCM(node)
+			})
+		}
+	}
+}
+
+// Lets the workload of the crashed client restart it. Must be called with sim.mu held.
+func (sim *simulation) restartClient(step int) {
+	node := sim.crashedClient
+	sim.network.scheduler.note(fmt.Sprintf("%d: restart %s", step, node.addr))
+	close(node.restart)
+	sim.crashedClient = nil
+}
+
+func (sim *simulation) restartCM(node *simCM) {
+	nodeCfg := sim.nodeCfg
+	nodeCfg.Transport = sim.network.endpoint(node.addr)
+	cm, err := RestartCentralManager(nodeCfg, node.primary)
+	if err == nil {
+		err = cm.Start()
+	}
+	if err != nil {
+		logerror.Printf("Could not restart %s: %v\n", node.addr, err)
+		return
+	}
+	sim.mu.Lock()
+	node.cm = cm
+	sim.mu.Unlock()
+}
+
+// In-memory network of a simulation
+type memoryNetwork struct {
+	mu        sync.Mutex
+	listeners map[string]*memoryListener
+	scheduler *scheduler
+}
+
+type memoryListener struct {
+	endpoint *memoryEndpoint
+	handlers map[string]Handler
+}
+
+// A node's attachment to the network. A crashed node's endpoint stays down, and its restart gets a new one.
+type memoryEndpoint struct {
+	network *memoryNetwork
+	addr    string
+	// Guarded by network.mu
+	down bool
+}
+
+func newMemoryNetwork(rng *rand.Rand, dropRate float64) *memoryNetwork {
+	return &memoryNetwork{
+		listeners: make(map[string]*memoryListener),
+		scheduler: newScheduler(rng, dropRate),
+	}
+}
+
+func (n *memoryNetwork) endpoint(addr string) *memoryEndpoint {
+	return &memoryEndpoint{network: n, addr: addr}
+}
+
+// Takes down the endpoint listening on addr. Its node can no longer send or receive messages.
+func (n *memoryNetwork) crash(addr string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if l, exists := n.listeners[addr]; exists {
+		l.endpoint.down = true
+		delete(n.listeners, addr)
+	}
+}
+
+func (n *memoryNetwork) close() {
+	n.scheduler.close()
+}
+
+// Handler for a message from one endpoint to the node of type nodeType at addr, and the endpoint it listens on
+func (n *memoryNetwork) handler(from *memoryEndpoint, addr string, nodeType string) (Handler, *memoryEndpoint, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if from.down {
+		return nil, nil, fmt.Errorf("%s is down", from.addr)
+	}
+	l, exists := n.listeners[addr]
+	if !exists {
+		return nil, nil, fmt.Errorf("connection refused: nothing listening on %s", addr)
+	}
+	handle, exists := l.handlers[nodeType]
+	if !exists {
+		return nil, nil, fmt.Errorf("no %s at %s", nodeType, addr)
+	}
+	return handle, l.endpoint, nil
+}
+
+func (n *memoryNetwork) isDown(e *memoryEndpoint) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return e.down
+}
+
+func (e *memoryEndpoint) Send(ctx context.Context, target Target, msg Message) (Reply, error) {
+	var sent Message
+	if err := gobCopy(msg, &sent); err != nil {
+		return Reply{}, err
+	}
+	delivered, err := e.network.scheduler.deliver(ctx, e.addr, target, sent)
+	if err != nil {
+		return Reply{}, err
+	}
+	if !delivered {
+		return Reply{}, fmt.Errorf("%w: message to %s lost", ErrNotDelivered, target.Addr)
+	}
+	handle, listener, err := e.network.handler(e, target.Addr, target.NodeType)
+	if err != nil {
+		return Reply{}, fmt.Errorf("%w: %w", ErrNotDelivered, err)
+	}
+	var reply Reply
+	if err := handle(sent, &reply); err != nil {
+		return Reply{}, err
+	}
+	if e.network.isDown(listener) || e.network.isDown(e) {
+		return Reply{}, errors.New("connection reset: node crashed")
+	}
+	var received Reply
+	if err := gobCopy(reply, &received); err != nil {
+		return Reply{}, err
+	}
+	return received, nil
+}
+
+func (e *memoryEndpoint) Listen(addr string, handlers map[string]Handler) (io.Closer, error) {
+	e.network.mu.Lock()
+	defer e.network.mu.Unlock()
+	if e.down {
+		return nil, fmt.Errorf("%s is down", e.addr)
+	}
+	if _, exists := e.network.listeners[addr]; exists {
+		return nil, fmt.Errorf("listening on %s: address already in use", addr)
+	}
+	e.network.listeners[addr] = &memoryListener{endpoint: e, handlers: handlers}
+	return closerFunc(func() error {
+		e.network.mu.Lock()
+		defer e.network.mu.Unlock()
+		if l, exists := e.network.listeners[addr]; exists && l.endpoint == e {
+			delete(e.network.listeners, addr)
+		}
+		return nil
+	}), nil
+}
+
+var _ Transport = (*memoryEndpoint)(nil)
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error {
+	return f()
+}
+
+// Copies a message or reply the way net/rpc would, through gob
+func gobCopy(from interface{}, to interface{}) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(from); err != nil {
+		return err
+	}
+	return gob.NewDecoder(&buf).Decode(to)
+}
+
+// Delivers the messages of a simulation one at a time, in an order drawn from a seeded generator,
+// and fires the timers of its simulated clock
+type scheduler struct {
+	mu       sync.Mutex
+	rng      *rand.Rand
+	dropRate float64
+	clock    *simClock
+	pending  []*delivery
+	// Messages sent so far by each sender, by key
+	sent    map[string]int
+	steps   int
+	trace   []string
+	closed  bool
+	stopped chan struct{}
+	// Called after each delivery, from the scheduler's goroutine
+	afterStep func(step int, rng *rand.Rand)
+	// Called from the scheduler's goroutine when nothing else can happen. Reports whether it did anything.
+	idle func(step int) bool
+}
+
+type delivery struct {
+	// Orders the waiting messages independently of when they arrived
+	key         string
+	description string
+	// Receives whether the message is delivered or lost
+	release chan bool
+}
+
+func newScheduler(rng *rand.Rand, dropRate float64) *scheduler {
+	s := &scheduler{
+		rng:      rng,
+		dropRate: dropRate,
+		clock:    newSimClock(),
+		sent:     make(map[string]int),
+		stopped:  make(chan struct{}),
+	}
+	s.clock.goroutines = newSimGoroutines()
+	return s
+}
+
+// Blocks until the scheduler picks the message, and reports whether it is delivered.
+// A message whose ctx is done first is withdrawn.
+func (s *scheduler) deliver(ctx context.Context, from string, to Target, msg Message) (bool, error) {
+	key := fmt.Sprintf("%s|%s|%s|%s|%d|%s|%s", to.Addr, to.NodeType, from, msg.Type, msg.FromID, requestPageNo(msg), msg.RequestID)
+	d := &delivery{
+		description: fmt.Sprintf("%s from %s to %s %s", msg.Type, from, to.NodeType, to.Addr),
+		release:     make(chan bool, 1),
+	}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return false, nil
+	}
+	// Tells apart the retries of the same message
+	s.sent[key]++
+	d.key = fmt.Sprintf("%s|%d", key, s.sent[key])
+	s.pending = append(s.pending, d)
+	s.mu.Unlock()
+
+	if i, delivered := s.clock.Select(d.release, ctx.Done()); i == 0 {
+		return delivered.(bool), nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, waiting := range s.pending {
+		if waiting == d {
+			s.pending = append(s.pending[:i], s.pending[i+1:]...)
+			return false, ctx.Err()
+		}
+	}
+	// Picked in the meantime
+	return <-d.release, nil
+}
+
+// Each step fires the timer that is due, or else delivers a message, or else moves the clock on to
+// the next timer, once every goroutine of the simulation waits
+func (s *scheduler) run() {
+	for {
+		if !s.settle() {
+			return
+		}
+		if s.clock.fireNext(false) {
+			continue
+		}
+
+		s.mu.Lock()
+		if len(s.pending) == 0 {
+			s.mu.Unlock()
+			if s.clock.fireNext(true) {
+				continue
+			}
+			if s.idle != nil && s.idle(s.steps) {
+				continue
+			}
+			// Nothing can happen until a goroutine joins the simulation
+			if !s.clock.goroutines.awaitStart(s.stopped) {
+				return
+			}
+			continue
+		}
+		sort.Slice(s.pending, func(i, j int) bool {
+			return s.pending[i].key < s.pending[j].key
+		})
+		i := s.rng.Intn(len(s.pending))
+		d := s.pending[i]
+		s.pending = append(s.pending[:i], s.pending[i+1:]...)
+		s.steps++
+		step := s.steps
+		delivered := s.rng.Float64() >= s.dropRate
+		if delivered {
+			s.trace = append(s.trace, fmt.Sprintf("%d: %s", step, d.description))
+		} else {
+			s.trace = append(s.trace, fmt.Sprintf("%d: %s (lost)", step, d.description))
+		}
+		s.mu.Unlock()
+
+		s.clock.advance(SIM_LATENCY)
+		d.release <- delivered
+		if s.afterStep == nil {
+			continue
+		}
+		// Faults come after the message is handled, not in the middle of it
+		if !s.settle() {
+			return
+		}
+		s.afterStep(step, s.rng)
+	}
+}
+
+// Waits until every goroutine of the simulation waits, after whatever the scheduler did last.
+// Returns false once the scheduler is closed.
+func (s *scheduler) settle() bool {
+	return s.clock.goroutines.settle(s.stopped, s.clock.cancelWatched)
+}
+
+// Goroutines of a simulation: those started by its clock, and those that joined it, such as the one
+// running Simulate. Each is running, or parked until the next round, in which every parked goroutine
+// checks again whether what it waits for is there. The simulation settles once a round ends without
+// any of them getting anything, and so would every later round.
+type simGoroutines struct {
+	mu      sync.Mutex
+	running int
+	parked  int
+	// Set when a goroutine started or got what it waited for since the last round
+	progressed bool
+	// Closed to start the next round
+	round   chan struct{}
+	stopped bool
+	// Signaled when running drops to 0, and when a goroutine starts
+	idle    chan struct{}
+	started chan struct{}
+}
+
+func newSimGoroutines() *simGoroutines {
+	return &simGoroutines{
+		round:   make(chan struct{}),
+		idle:    make(chan struct{}, 1),
+		started: make(chan struct{}, 1),
+	}
+}
+
+func (g *simGoroutines) start(f func()) {
+	g.enter()
+	go func() {
+		defer g.leave()
+		f()
+	}()
+}
+
+// Counts the calling goroutine as one of the simulation's until it leaves
+func (g *simGoroutines) enter() {
+	g.mu.Lock()
+	g.running++
+	g.progressed = true
+	g.mu.Unlock()
+	signal(g.started)
+}
+
+func (g *simGoroutines) leave() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.running--
+	if g.running == 0 {
+		signal(g.idle)
+	}
+}
+
+// Waits for the next round. Returns false at once if the simulation is over.
+func (g *simGoroutines) park() bool {
+	g.mu.Lock()
+	if g.stopped {
+		g.mu.Unlock()
+		return false
+	}
+	if g.running == 0 {
+		g.mu.Unlock()
+		panic("ivy: a goroutine that is not part of the simulation waited on its scheduler")
+	}
+	g.running--
+	g.parked++
+	if g.running == 0 {
+		signal(g.idle)
+	}
+	round := g.round
+	g.mu.Unlock()
+
+	<-round
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return !g.stopped
+}
+
+// Called by a goroutine that got what it waited for after parking
+func (g *simGoroutines) progress() {
+	g.mu.Lock()
+	g.progressed = true
+	g.mu.Unlock()
+}
+
+// Waits until no goroutine runs, starting rounds for as long as the parked goroutines make progress.
+// cancel is called before each round, and reports whether it cancelled contexts they may wait on.
+func (g *simGoroutines) settle(stopped <-chan struct{}, cancel func() bool) bool {
+	g.mu.Lock()
+	// The scheduler may have sent what a parked goroutine waits for
+	g.progressed = true
+	for {
+		if g.running > 0 {
+			g.mu.Unlock()
+			select {
+			case <-g.idle:
+			case <-stopped:
+				return false
+			}
+			g.mu.Lock()
+			continue
+		}
+		g.mu.Unlock()
+		cancelled := cancel()
+		g.mu.Lock()
+		if g.running > 0 {
+			continue
+		}
+		if !g.progressed && !cancelled {
+			g.mu.Unlock()
+			return true
+		}
+		g.progressed = false
+		if g.parked == 0 {
+			g.mu.Unlock()
+			return true
+		}
+		g.running, g.parked = g.parked, 0
+		close(g.round)
+		g.round = make(chan struct{})
+	}
+}
+
+// Waits until a goroutine starts or joins. Returns false once stopped is closed.
+func (g *simGoroutines) awaitStart(stopped <-chan struct{}) bool {
+	select {
+	case <-g.started:
+		return true
+	case <-stopped:
+		return false
+	}
+}
+
+// Releases the parked goroutines, which wait as usual from then on
+func (g *simGoroutines) stop() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.stopped {
+		g.stopped = true
+		close(g.round)
+	}
+}
+
+// Sends on a channel of capacity 1 unless a signal is already waiting
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// Adds a line to the trace
+func (s *scheduler) note(line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.trace = append(s.trace, line)
+}
+
+func (s *scheduler) log() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.trace...)
+}
+
+// Stops delivering messages. The messages still waiting are lost.
+func (s *scheduler) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	for _, d := range s.pending {
+		d.release <- false
+	}
+	s.pending = nil
+	close(s.stopped)
+	s.clock.goroutines.stop()
+}
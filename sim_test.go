@@ -0,0 +1,219 @@
+package ivy
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Seeds whose messages get lost, and whose CMs crash and restart where there is a backup CM to take
+// over, as do clients where a ClientCrashRate is set. There is no CM to crash in FIXED and DYNAMIC modes.
+var simTests = []SimConfig{
+	{Seed: 5, Mode: CENTRAL, Backup: true},
+	{Seed: 7, Mode: CENTRAL, Backup: true},
+	{Seed: 8, Mode: CENTRAL, Backup: true, ClientCrashRate: 0.02},
+	{Seed: 5, Mode: IMPROVED, Backup: true},
+	{Seed: 7, Mode: IMPROVED, Backup: true},
+	{Seed: 8, Mode: IMPROVED, Backup: true, ClientCrashRate: 0.02},
+	{Seed: 6, Mode: FIXED},
+	{Seed: 8, Mode: FIXED},
+	{Seed: 4, Mode: DYNAMIC},
+}
+
+func TestSimulate(t *testing.T) {
+	for _, cfg := range simTests {
+		cfg.Clients = 3
+		cfg.Ops = 10
+		cfg.DropRate = 0.05
+		cfg.CrashRate = 0.02
+		cfg.RestartAfter = 20
+		t.Run(fmt.Sprintf("%s/%d", cfg.Mode, cfg.Seed), func(t *testing.T) {
+			result, err := Simulate(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !result.Check.Linearizable {
+				t.Fatalf("history not linearizable, counterexample %+v\n%s", result.Check.Counterexample, strings.Join(result.Trace, "\n"))
+			}
+			trace := strings.Join(result.Trace, "\n")
+			if !strings.Contains(trace, "(lost)") {
+				t.Error("no message lost")
+			}
+			if cfg.Backup && !strings.Contains(trace, ": crash cm") {
+				t.Error("no CM crashed")
+			}
+			if cfg.ClientCrashRate > 0 && !strings.Contains(trace, ": restart client") {
+				t.Error("no client crashed and restarted")
+			}
+
+			replay, err := Simulate(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(replay.Trace, result.Trace) {
+				t.Errorf("replay of the seed differs:\n%s\nfirst run:\n%s", strings.Join(replay.Trace, "\n"), trace)
+			}
+			if !reflect.DeepEqual(replay.History, result.History) {
+				t.Error("replay of the seed has another history")
+			}
+		})
+	}
+}
+
+func TestSimClockFiresTimersInOrder(t *testing.T) {
+	clock := newSimClock()
+	fired := []string{}
+	clock.newTimer(2, 0, func(now time.Time) { fired = append(fired, "b") })
+	clock.newTimer(1, 0, func(now time.Time) { fired = append(fired, "a") })
+	clock.newTimer(2, 0, func(now time.Time) { fired = append(fired, "c") })
+	clock.newTimer(3, 0, func(now time.Time) { fired = append(fired, "stopped") }).Stop()
+	ticker := clock.newTimer(2, 2, func(now time.Time) { fired = append(fired, "tick") })
+
+	if clock.fireNext(false) {
+		t.Fatal("timer fired before its deadline")
+	}
+	for len(fired) < 6 && clock.fireNext(true) {
+	}
+	ticker.Stop()
+	if clock.fireNext(true) {
+		t.Error("timer fired after the ticker was stopped")
+	}
+	if want := []string{"a", "b", "c", "tick", "tick", "tick"}; !reflect.DeepEqual(fired, want) {
+		t.Errorf("fired %v, want %v", fired, want)
+	}
+	if got, want := clock.Now(), simEpoch.Add(6); !got.Equal(want) {
+		t.Errorf("clock at %v, want %v", got, want)
+	}
+}
+
+func TestSimClockWithTimeout(t *testing.T) {
+	clock := newSimClock()
+	ctx, cancel := clock.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	child, cancelChild := clock.WithTimeout(ctx, time.Minute)
+	defer cancelChild()
+	if deadline, _ := child.Deadline(); !deadline.Equal(simEpoch.Add(time.Second)) {
+		t.Errorf("child deadline %v, want the deadline of its parent", deadline)
+	}
+
+	clock.advance(time.Second - 1)
+	if clock.fireNext(false) || ctx.Err() != nil {
+		t.Fatal("context done before its deadline")
+	}
+	clock.advance(1)
+	clock.fireNext(false)
+	<-child.Done()
+	if ctx.Err() != context.DeadlineExceeded {
+		t.Errorf("got %v, want %v", ctx.Err(), context.DeadlineExceeded)
+	}
+}
+
+// CM and clients of a test, over the in-memory network of a simulation or over TCP
+type testDSM struct {
+	// Nil over TCP
+	network *memoryNetwork
+	clock   *simClock
+	cm      *CentralManager
+	clients []*Client
+}
+
+// Starts a DSM of cfg.Mode with n clients over the in-memory network of a simulation, without faults.
+// Timers run on the simulated clock, so leases and deadlines expire as soon as nothing else can
+// happen. There is no CM in FIXED and DYNAMIC modes.
+func newTestDSM(t *testing.T, cfg Config, n int) *testDSM {
+	t.Helper()
+	dsm := &testDSM{network: newTestNetwork(t)}
+	dsm.clock = dsm.network.scheduler.clock
+	cfg.clock = dsm.clock
+	dsm.start(t, cfg, n, func(name string) (string, Transport) {
+		return name, dsm.network.endpoint(name)
+	})
+	return dsm
+}
+
+// In-memory network without faults, whose scheduler delivers the messages and fires the timers of its
+// clock until the test ends. The test goroutine is one of the simulation's, so it must wait through the
+// clock and start its goroutines with it.
+func newTestNetwork(t *testing.T) *memoryNetwork {
+	t.Helper()
+	simulating.Lock()
+	network := newMemoryNetwork(rand.New(rand.NewSource(1)), 0)
+	network.scheduler.clock.goroutines.enter()
+	go network.scheduler.run()
+	t.Cleanup(func() {
+		network.close()
+		simulating.Unlock()
+	})
+	return network
+}
+
+// Same as newTestDSM, over TCP on the loopback interface and on the wall clock, for tests of races
+// that the scheduler of a simulation would keep from happening
+func newTCPTestDSM(t *testing.T, cfg Config, n int) *testDSM {
+	t.Helper()
+	dsm := &testDSM{}
+	dsm.start(t, cfg, n, func(string) (string, Transport) {
+		return freeAddr(t), &RPCTransport{}
+	})
+	return dsm
+}
+
+func (dsm *testDSM) start(t *testing.T, cfg Config, n int, node func(name string) (string, Transport)) {
+	t.Helper()
+	t.Cleanup(func() {
+		for _, c := range dsm.clients {
+			c.Stop()
+		}
+		if dsm.cm != nil {
+			dsm.cm.Stop()
+		}
+	})
+	cfg.DataDir = t.TempDir()
+	if cfg.Mode == FIXED {
+		cfg.Nodes = n
+	}
+	if cfg.Mode == "" || cfg.Mode == CENTRAL || cfg.Mode == IMPROVED {
+		cmCfg := cfg
+		cmCfg.Addr, cmCfg.Transport = node("cm1")
+		cm, err := NewCentralManager(cmCfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cm.Start(); err != nil {
+			t.Fatal(err)
+		}
+		dsm.cm = cm
+	}
+	for i := 1; i <= n; i++ {
+		clientCfg := cfg
+		clientCfg.Addr, clientCfg.Transport = node(fmt.Sprintf("client%d", i))
+		c, err := NewClient(clientCfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Start(); err != nil {
+			t.Fatal(err)
+		}
+		dsm.clients = append(dsm.clients, c)
+	}
+}
+
+// Same as waitFor, polling on the clock of the simulation so that its goroutines get on meanwhile
+func (dsm *testDSM) waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	if dsm.clock == nil {
+		waitFor(t, cond)
+		return
+	}
+	deadline := dsm.clock.Now().Add(time.Minute)
+	for !cond() {
+		if dsm.clock.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		dsm.clock.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,360 @@
+package ivy
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Simulated time of a simulation, shared by all its nodes. It only moves when the scheduler moves it:
+// by SIM_LATENCY for every message delivered, and on to the next timer when no message is waiting.
+// The scheduler fires the timers one at a time, in the order of their deadlines. The goroutines of the
+// nodes start and wait through the clock, so that the scheduler knows when they are all waiting.
+
+// Simulated time taken by the delivery of a message
+const SIM_LATENCY = time.Millisecond
+
+// Simulated time at which every simulation starts
+var simEpoch = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+type simClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	seq    int
+	timers []*simTimer
+	// Contexts to cancel once their parent from the context package is done
+	watched []*simContext
+	// Nil for a clock without a scheduler, whose goroutines run and wait as usual
+	goroutines *simGoroutines
+}
+
+type simTimer struct {
+	clock *simClock
+	at    time.Time
+	// Breaks ties between timers with the same deadline, in the order they were set
+	seq int
+	// Set for the timers of tickers
+	period time.Duration
+	// Called by the scheduler, without clock.mu held. Must not block.
+	fire func(now time.Time)
+	// Guarded by clock.mu
+	armed bool
+}
+
+func newSimClock() *simClock {
+	return &simClock{now: simEpoch}
+}
+
+func (c *simClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *simClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	c.newTimer(d, 0, func(now time.Time) {
+		ch <- now
+	})
+	return ch
+}
+
+func (c *simClock) Sleep(d time.Duration) {
+	c.Select(c.After(d))
+}
+
+func (c *simClock) AfterFunc(d time.Duration, f func()) clockTimer {
+	return c.newTimer(d, 0, func(time.Time) {
+		c.Go(f)
+	})
+}
+
+func (c *simClock) NewTicker(d time.Duration) clockTicker {
+	ticks := make(chan time.Time, 1)
+	return &simTicker{
+		ticks: ticks,
+		timer: c.newTimer(d, d, func(now time.Time) {
+			select {
+			case ticks <- now:
+			default:
+			}
+		}),
+	}
+}
+
+func (c *simClock) WithTimeout(parent context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	deadline := c.Now().Add(d)
+	if parentDeadline, ok := parent.Deadline(); ok && !parentDeadline.After(deadline) {
+		return context.WithCancel(parent)
+	}
+	ctx := &simContext{parent: parent, deadline: deadline, done: make(chan struct{})}
+	timer := c.newTimer(d, 0, func(time.Time) {
+		ctx.cancel(context.DeadlineExceeded)
+	})
+	stop := c.propagateCancel(parent, ctx)
+	return ctx, func() {
+		ctx.cancel(context.Canceled)
+		timer.Stop()
+		stop()
+	}
+}
+
+// Cancels ctx once parent is done. A parent from the context package has no hook to do so as it is
+// cancelled, so the scheduler checks it before each round.
+func (c *simClock) propagateCancel(parent context.Context, ctx *simContext) (stop func()) {
+	if parent.Done() == nil {
+		return func() {}
+	}
+	if err := parent.Err(); err != nil {
+		ctx.cancel(err)
+		return func() {}
+	}
+	if p, ok := parent.(*simContext); ok {
+		stopFunc := p.AfterFunc(func() {
+			ctx.cancel(p.Err())
+		})
+		return func() { stopFunc() }
+	}
+	if c.goroutines == nil {
+		stopFunc := context.AfterFunc(parent, func() {
+			ctx.cancel(parent.Err())
+		})
+		return func() { stopFunc() }
+	}
+	c.mu.Lock()
+	c.watched = append(c.watched, ctx)
+	c.mu.Unlock()
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for i, watched := range c.watched {
+			if watched == ctx {
+				c.watched = append(c.watched[:i], c.watched[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// Cancels the watched contexts whose parent is done. Reports whether there were any.
+func (c *simClock) cancelWatched() bool {
+	c.mu.Lock()
+	var cancelled []*simContext
+	watched := c.watched[:0]
+	for _, ctx := range c.watched {
+		if ctx.parent.Err() != nil {
+			cancelled = append(cancelled, ctx)
+		} else {
+			watched = append(watched, ctx)
+		}
+	}
+	c.watched = watched
+	c.mu.Unlock()
+	for _, ctx := range cancelled {
+		ctx.cancel(ctx.parent.Err())
+	}
+	return len(cancelled) > 0
+}
+
+func (c *simClock) Go(f func()) {
+	if c.goroutines == nil {
+		go f()
+		return
+	}
+	c.goroutines.start(f)
+}
+
+// Under a scheduler, the channels are polled in order, so the first ready one is picked whatever
+// order they got ready in, and the goroutine parks until the next round while none is
+func (c *simClock) Select(chans ...any) (int, any) {
+	if c.goroutines == nil {
+		return selectChans(chans, true)
+	}
+	parked := false
+	for {
+		for i := range chans {
+			if ready, value := selectChans(chans[i:i+1], false); ready == 0 {
+				if parked {
+					c.goroutines.progress()
+				}
+				return i, value
+			}
+		}
+		if !c.goroutines.park() {
+			// The simulation is over
+			return selectChans(chans, true)
+		}
+		parked = true
+	}
+}
+
+func (c *simClock) newTimer(d time.Duration, period time.Duration, fire func(time.Time)) *simTimer {
+	t := &simTimer{clock: c, period: period, fire: fire}
+	t.Reset(d)
+	return t
+}
+
+func (t *simTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	return t.disarm()
+}
+
+func (t *simTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	wasArmed := t.disarm()
+	t.clock.seq++
+	t.at = t.clock.now.Add(d)
+	t.seq = t.clock.seq
+	t.armed = true
+	t.clock.timers = append(t.clock.timers, t)
+	return wasArmed
+}
+
+// Must be called with clock.mu held
+func (t *simTimer) disarm() bool {
+	if !t.armed {
+		return false
+	}
+	t.armed = false
+	for i, armed := range t.clock.timers {
+		if armed == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// Must be called with c.mu held
+func (c *simClock) nextTimer() *simTimer {
+	var next *simTimer
+	for _, t := range c.timers {
+		if next == nil || t.at.Before(next.at) || (t.at.Equal(next.at) && t.seq < next.seq) {
+			next = t
+		}
+	}
+	return next
+}
+
+// Fires the next timer if it is due. With skip, first moves the clock on to the next timer if there
+// is one. Reports whether a timer fired.
+func (c *simClock) fireNext(skip bool) bool {
+	c.mu.Lock()
+	t := c.nextTimer()
+	if t == nil || (!skip && t.at.After(c.now)) {
+		c.mu.Unlock()
+		return false
+	}
+	if t.at.After(c.now) {
+		c.now = t.at
+	}
+	now := c.now
+	t.disarm()
+	if t.period > 0 {
+		c.seq++
+		t.at = t.at.Add(t.period)
+		t.seq = c.seq
+		t.armed = true
+		c.timers = append(c.timers, t)
+	}
+	c.mu.Unlock()
+	t.fire(now)
+	return true
+}
+
+func (c *simClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+type simTicker struct {
+	ticks chan time.Time
+	timer *simTimer
+}
+
+func (t *simTicker) Ticks() <-chan time.Time {
+	return t.ticks
+}
+
+func (t *simTicker) Stop() {
+	t.timer.Stop()
+}
+
+// Context done once the simulated clock reaches its deadline. It cancels its children as it is
+// cancelled, like the contexts of the context package do, instead of from goroutines of their own.
+type simContext struct {
+	parent   context.Context
+	deadline time.Time
+	done     chan struct{}
+
+	mu  sync.Mutex
+	err error
+	// Called as the context is cancelled, nil once stopped
+	afterFuncs []*func()
+}
+
+func (ctx *simContext) Deadline() (time.Time, bool) {
+	return ctx.deadline, true
+}
+
+func (ctx *simContext) Done() <-chan struct{} {
+	return ctx.done
+}
+
+func (ctx *simContext) Err() error {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	return ctx.err
+}
+
+func (ctx *simContext) Value(key any) any {
+	return ctx.parent.Value(key)
+}
+
+// Used by the context package to cancel the children of ctx
+func (ctx *simContext) AfterFunc(f func()) (stop func() bool) {
+	ctx.mu.Lock()
+	if ctx.err != nil {
+		ctx.mu.Unlock()
+		f()
+		return func() bool { return false }
+	}
+	entry := &f
+	ctx.afterFuncs = append(ctx.afterFuncs, entry)
+	ctx.mu.Unlock()
+	return func() bool {
+		ctx.mu.Lock()
+		defer ctx.mu.Unlock()
+		for i, registered := range ctx.afterFuncs {
+			if registered == entry {
+				ctx.afterFuncs[i] = nil
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func (ctx *simContext) cancel(err error) {
+	ctx.mu.Lock()
+	if ctx.err != nil {
+		ctx.mu.Unlock()
+		return
+	}
+	ctx.err = err
+	close(ctx.done)
+	afterFuncs := ctx.afterFuncs
+	ctx.afterFuncs = nil
+	ctx.mu.Unlock()
+	for _, f := range afterFuncs {
+		if f != nil {
+			(*f)()
+		}
+	}
+}
+
+var _ clock = (*simClock)(nil)
+var _ clock = wallClock{}
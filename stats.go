@@ -0,0 +1,66 @@
+package ivy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Number of messages a node has sent and received, by message type.
+// Used to compare the message load of the manager modes.
+type messageStats struct {
+	mu       sync.Mutex
+	sent     map[string]int
+	received map[string]int
+}
+
+// Messages sent and received by the client, by message type
+func (c *Client) Stats() string {
+	return c.stats.String()
+}
+
+// Messages sent and received by the CM, by message type
+func (cm *CentralManager) Stats() string {
+	return cm.stats.String()
+}
+
+func newMessageStats() *messageStats {
+	return &messageStats{sent: make(map[string]int), received: make(map[string]int)}
+}
+
+func (s *messageStats) countSent(msgType string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent[msgType]++
+}
+
+func (s *messageStats) countReceived(msgType string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.received[msgType]++
+}
+
+// One line per direction, e.g. "sent 5: PAGE_SEND=2 READ_FORWARD=3"
+func (s *messageStats) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fmt.Sprintf("sent %s\nreceived %s", formatCounts(s.sent), formatCounts(s.received))
+}
+
+func formatCounts(counts map[string]int) string {
+	msgTypes := make([]string, 0, len(counts))
+	total := 0
+	for msgType, count := range counts {
+		msgTypes = append(msgTypes, msgType)
+		total += count
+	}
+	sort.Strings(msgTypes)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d:", total)
+	for _, msgType := range msgTypes {
+		fmt.Fprintf(&sb, " %s=%d", msgType, counts[msgType])
+	}
+	return sb.String()
+}
@@ -0,0 +1,213 @@
+package ivy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/rpc"
+	"sync"
+	"time"
+)
+
+// How a node exchanges messages with the other nodes. Every node of a DSM must use the same kind of
+// transport. RPCTransport, net/rpc over TCP, is the default; simulations use an in-memory network.
+type Transport interface {
+	// Delivers msg to the target node and returns its reply, or an error if it could not be
+	// delivered or ctx is done first. The error wraps ErrNotDelivered if the message certainly did
+	// not reach the target's handler.
+	Send(ctx context.Context, target Target, msg Message) (Reply, error)
+	// Hands the messages sent to addr to the handler of their node type (CLIENT or CENTRALMANAGER)
+	// until the returned closer is closed
+	Listen(addr string, handlers map[string]Handler) (io.Closer, error)
+}
+
+// Handles a message received by a node, filling in its reply
+type Handler func(msg Message, reply *Reply) error
+
+// Node a message is sent to
+type Target struct {
+	// CLIENT or CENTRALMANAGER. A client in FIXED mode hosts both on the same address.
+	NodeType string
+	// Client ID, -1 for a CM
+	ID   int
+	Addr string
+}
+
+// net/rpc over TCP. Messages are sent to "<node type>.HandleIncomingMessage" over pooled connections.
+// The zero value is ready to use, and must not be copied once used.
+type RPCTransport struct {
+	// Idle connections kept to each peer. Defaults to DEFAULT_MAX_IDLE_CONNS. A negative value
+	// disables pooling: every message gets a new connection, closed once the reply is in.
+	MaxIdleConns int
+	// How long an idle connection is kept. Defaults to DEFAULT_IDLE_TIMEOUT.
+	IdleTimeout time.Duration
+
+	pool connPool
+}
+
+func (t *RPCTransport) Send(ctx context.Context, target Target, msg Message) (Reply, error) {
+	serviceMethod := fmt.Sprintf("%s.HandleIncomingMessage", target.NodeType)
+	maxIdle, timeout := t.MaxIdleConns, t.IdleTimeout
+	if maxIdle == 0 {
+		maxIdle = DEFAULT_MAX_IDLE_CONNS
+	}
+	if timeout <= 0 {
+		timeout = DEFAULT_IDLE_TIMEOUT
+	}
+
+	if maxIdle > 0 {
+		for conn := t.pool.get(target.Addr, timeout); conn != nil; conn = t.pool.get(target.Addr, timeout) {
+			reply, err := conn.call(ctx, serviceMethod, msg)
+			if err == nil {
+				t.pool.put(target.Addr, conn, maxIdle)
+				return reply, nil
+			}
+			if !errors.Is(err, ErrNotDelivered) {
+				return reply, err
+			}
+			// The peer closed the connection as the message went out
+		}
+	}
+
+	conn, err := t.pool.dial(ctx, target.Addr)
+	if err != nil {
+		return Reply{}, fmt.Errorf("%w: dialing RPC: %w", ErrNotDelivered, err)
+	}
+	reply, err := conn.call(ctx, serviceMethod, msg)
+	if err != nil {
+		return reply, err
+	}
+	if maxIdle > 0 {
+		t.pool.put(target.Addr, conn, maxIdle)
+	} else {
+		conn.client.Close()
+	}
+	return reply, nil
+}
+
+// Closes the idle connections. Connections in use are closed when their message completes.
+func (t *RPCTransport) CloseIdleConnections() {
+	t.pool.closeIdle()
+}
+
+// Number of connections dialed so far
+func (t *RPCTransport) Dialed() int {
+	t.pool.mu.Lock()
+	defer t.pool.mu.Unlock()
+	return t.pool.dialed
+}
+
+// Closing the returned closer stops accepting connections and closes the open ones, which the peers' pools then drop
+func (t *RPCTransport) Listen(addr string, handlers map[string]Handler) (io.Closer, error) {
+	server := rpc.NewServer()
+	for nodeType, handle := range handlers {
+		if err := server.RegisterName(nodeType, &rpcHandler{handle: handle}); err != nil {
+			return nil, fmt.Errorf("registering %s's RPC methods: %w", nodeType, err)
+		}
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listening on %s: %w", addr, err)
+	}
+	l := &rpcListener{Listener: listener, conns: make(map[net.Conn]bool)}
+	go l.serve(server)
+	return l, nil
+}
+
+var _ Transport = (*RPCTransport)(nil)
+
+// Closes the idle connections of transports that keep them, such as RPCTransport
+func closeIdleConnections(t Transport) {
+	if closer, ok := t.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+// Receiver of the RPCs for one node type
+type rpcHandler struct {
+	handle Handler
+}
+
+func (h *rpcHandler) HandleIncomingMessage(msg Message, reply *Reply) error {
+	return h.handle(msg, reply)
+}
+
+// Listener that also closes the connections it accepted when it is closed
+type rpcListener struct {
+	net.Listener
+	// Guards conns and closed
+	mu     sync.Mutex
+	conns  map[net.Conn]bool
+	closed bool
+}
+
+// Serves each connection on its own goroutine until the listener is closed
+func (l *rpcListener) serve(server *rpc.Server) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		l.mu.Lock()
+		if l.closed {
+			l.mu.Unlock()
+			conn.Close()
+			return
+		}
+		l.conns[conn] = true
+		l.mu.Unlock()
+
+		go func() {
+			server.ServeConn(conn)
+			l.mu.Lock()
+			delete(l.conns, conn)
+			l.mu.Unlock()
+		}()
+	}
+}
+
+func (l *rpcListener) Close() error {
+	l.mu.Lock()
+	l.closed = true
+	for conn := range l.conns {
+		conn.Close()
+	}
+	l.mu.Unlock()
+	return l.Listener.Close()
+}
+
+// Sends msg through the node's transport under ctx and the deadline of its type, counting every
+// attempt in stats. A message that could not be delivered is sent again as the retry policy allows.
+// A message that could not be delivered or was not answered in time gets a Reply without Ack, which
+// callers treat as a lost message.
+func sendMessage(ctx context.Context, t Transport, stats *messageStats, calls callPolicy, msg Message, target Target) Reply {
+	if timeout := calls.timeout(msg.Type); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = calls.clock.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	backoff := calls.retry.Backoff
+	for attempt := 0; ; attempt++ {
+		if deadline, ok := ctx.Deadline(); ok {
+			msg.Timeout = deadline.Sub(calls.clock.Now())
+		}
+		stats.countSent(msg.Type)
+		reply, err := t.Send(ctx, target, msg)
+		if err == nil {
+			return reply
+		}
+		if attempt >= calls.retry.Retries || !errors.Is(err, ErrNotDelivered) || ctx.Err() != nil {
+			logerror.Printf("Error calling RPC from Msg [%s]: %v\n", msg.Type, err)
+			return Reply{}
+		}
+		logwarning.Printf("Msg [%s] not delivered, retrying in %v: %v\n", msg.Type, backoff, err)
+		if _, err := receiveContext(calls.clock, ctx, calls.clock.After(backoff)); err != nil {
+			logerror.Printf("Gave up retrying Msg [%s]: %v\n", msg.Type, err)
+			return Reply{}
+		}
+		backoff = calls.nextBackoff(backoff)
+	}
+}
@@ -0,0 +1,88 @@
+package ivy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/rpc"
+	"testing"
+)
+
+// Listens on addr with a CLIENT handler that acknowledges every message
+func listenAck(t *testing.T, transport Transport, addr string) io.Closer {
+	t.Helper()
+	l, err := transport.Listen(addr, map[string]Handler{CLIENT: func(msg Message, reply *Reply) error {
+		reply.Ack = true
+		return nil
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func sendAck(t *testing.T, transport *RPCTransport, addr string) {
+	t.Helper()
+	reply, err := transport.Send(context.Background(), Target{NodeType: CLIENT, Addr: addr}, Message{Type: READ_REQUEST})
+	if err != nil || !reply.Ack {
+		t.Fatalf("got %+v, %v", reply, err)
+	}
+}
+
+func TestRPCTransportReusesConnections(t *testing.T) {
+	addr := freeAddr(t)
+	l := listenAck(t, &RPCTransport{}, addr)
+	defer l.Close()
+
+	transport := &RPCTransport{}
+	defer transport.CloseIdleConnections()
+	for i := 0; i < 5; i++ {
+		sendAck(t, transport, addr)
+	}
+	if dialed := transport.Dialed(); dialed != 1 {
+		t.Errorf("dialed %d connections, want 1", dialed)
+	}
+
+	unpooled := &RPCTransport{MaxIdleConns: -1}
+	for i := 0; i < 3; i++ {
+		sendAck(t, unpooled, addr)
+	}
+	if dialed := unpooled.Dialed(); dialed != 3 {
+		t.Errorf("dialed %d connections without pooling, want 3", dialed)
+	}
+}
+
+// A restarted peer closed the pooled connections, which are not used for the next message
+func TestRPCTransportReconnectsAfterPeerRestart(t *testing.T) {
+	addr := freeAddr(t)
+	transport := &RPCTransport{}
+	defer transport.CloseIdleConnections()
+	for i := 1; i <= 20; i++ {
+		l := listenAck(t, &RPCTransport{}, addr)
+		sendAck(t, transport, addr)
+		sendAck(t, transport, addr)
+		l.Close()
+		if dialed := transport.Dialed(); dialed != i {
+			t.Fatalf("dialed %d connections for %d peers", dialed, i)
+		}
+	}
+
+	_, err := transport.Send(context.Background(), Target{NodeType: CLIENT, Addr: addr}, Message{Type: READ_REQUEST})
+	if !errors.Is(err, ErrNotDelivered) {
+		t.Errorf("got %v with the peer down, want an error wrapping ErrNotDelivered", err)
+	}
+}
+
+// A message that could not be written out in full cannot have reached the handler
+func TestPooledConnWriteFailureNotDelivered(t *testing.T) {
+	local, remote := net.Pipe()
+	conn := &pooledConn{conn: &trackedConn{Conn: local}}
+	conn.client = rpc.NewClient(conn.conn)
+	remote.Close()
+
+	_, err := conn.call(context.Background(), "CLIENT.HandleIncomingMessage", Message{Type: READ_REQUEST})
+	if !errors.Is(err, ErrNotDelivered) {
+		t.Errorf("got %v, want an error wrapping ErrNotDelivered", err)
+	}
+}
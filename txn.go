@@ -0,0 +1,241 @@
+package ivy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Multi-page transactions. Commit takes the manager's lock on every page of the transaction with a
+// TXN_LOCK, one page at a time in page number order, so two transactions never wait for each other
+// in a cycle. While the transaction holds the locks, the manager keeps every other request for those
+// pages waiting. The transaction then takes write ownership of each page with WRITE_REQUESTs carrying
+// its TxnID, which run under the locks it holds, applies all its writes to the pages at once under
+// c.mu, and releases the locks with TXN_UNLOCK.
+//
+// A page lock that cannot be taken within the timeout aborts the transaction before anything is
+// written. The page locks have a lease of PAGE_LOCK_LEASE, which a transaction must commit within.
+
+// How long Commit waits for the page locks and pages of a transaction before aborting it
+const TXN_TIMEOUT = 5 * time.Second
+
+// Returned by Commit when the transaction could not get hold of its pages. Nothing was written.
+var ErrTransactionAborted = errors.New("transaction aborted")
+
+// Writes to several pages that other clients see all at once or not at all
+type Transaction struct {
+	c   *Client
+	ops []txnOp
+}
+
+type txnOp struct {
+	pageNo string
+	// Returns the new content of the page, as applyWrite does
+	apply func(content []byte) []byte
+}
+
+func (c *Client) NewTransaction() *Transaction {
+	return &Transaction{c: c}
+}
+
+// Replaces the content of pageNo when the transaction commits, as Write does
+func (t *Transaction) Write(pageNo string, content []byte) error {
+	if err := checkWrite(0, len(content)); err != nil {
+		return err
+	}
+	data := append([]byte{}, content...)
+	t.ops = append(t.ops, txnOp{pageNo: pageNo, apply: func([]byte) []byte {
+		return applyWrite(nil, 0, data)
+	}})
+	return nil
+}
+
+// Writes buf at addr when the transaction commits
+func (t *Transaction) WriteAt(addr int, buf []byte) error {
+	spans, err := pageSpans(addr, len(buf))
+	if err != nil {
+		return err
+	}
+	for _, span := range spans {
+		offset := span.offset
+		data := append([]byte{}, buf[span.start:span.end]...)
+		t.ops = append(t.ops, txnOp{pageNo: span.pageNo, apply: func(content []byte) []byte {
+			return applyWrite(content, offset, data)
+		}})
+	}
+	return nil
+}
+
+// Replaces the content of pageNo with update(content) when the transaction commits. update sees the
+// writes made earlier in the transaction, must not modify the content it is given, and must return
+// at most PAGE_SIZE bytes.
+func (t *Transaction) Update(pageNo string, update func(content []byte) []byte) {
+	t.ops = append(t.ops, txnOp{pageNo: pageNo, apply: func(content []byte) []byte {
+		updated := update(content)
+		if len(updated) > PAGE_SIZE {
+			updated = updated[:PAGE_SIZE]
+		}
+		return applyWrite(nil, 0, updated)
+	}})
+}
+
+// Applies the transaction's writes, waiting up to TXN_TIMEOUT for its pages
+func (t *Transaction) Commit() error {
+	ctx, cancel := t.c.clock.WithTimeout(context.Background(), TXN_TIMEOUT)
+	defer cancel()
+	return t.CommitContext(ctx)
+}
+
+// Same as Commit, but aborts when ctx is done
+func (t *Transaction) CommitContext(ctx context.Context) error {
+	c := t.c
+	if c.mode == DYNAMIC {
+		return fmt.Errorf("transactions lock their pages at the manager, there is none in %s mode", DYNAMIC)
+	}
+	if err := c.requireSequential("transactions"); err != nil {
+		return err
+	}
+	pageNos := []string{}
+	seen := map[string]bool{}
+	for _, op := range t.ops {
+		if !seen[op.pageNo] {
+			seen[op.pageNo] = true
+			pageNos = append(pageNos, op.pageNo)
+		}
+	}
+	sort.Strings(pageNos)
+	txnID := c.pending.newRequestID(c.ID)
+
+	locked := []string{}
+	defer func() {
+		for _, pageNo := range locked {
+			c.unlockForTxn(txnID, pageNo)
+		}
+	}()
+	var firstLocked time.Time
+	for _, pageNo := range pageNos {
+		if err := c.lockForTxn(ctx, txnID, pageNo); err != nil {
+			return fmt.Errorf("%w: %w", ErrTransactionAborted, err)
+		}
+		if len(locked) == 0 {
+			firstLocked = c.clock.Now()
+		}
+		locked = append(locked, pageNo)
+	}
+
+	for _, pageNo := range pageNos {
+		if err := c.ownForTxn(ctx, txnID, pageNo); err != nil {
+			return fmt.Errorf("%w: taking ownership of page %s: %w", ErrTransactionAborted, pageNo, err)
+		}
+	}
+	if c.clock.Now().Sub(firstLocked) >= PAGE_LOCK_LEASE {
+		return fmt.Errorf("%w: page locks may have expired", ErrTransactionAborted)
+	}
+	if err := c.applyTxn(t.ops); err != nil {
+		return fmt.Errorf("%w: %v", ErrTransactionAborted, err)
+	}
+	logsystem.Printf("Transaction %s committed to pages %v\n", txnID, pageNos)
+	return nil
+}
+
+// Takes the manager's lock on pageNo for the transaction
+func (c *Client) lockForTxn(ctx context.Context, txnID string, pageNo string) error {
+	timeout := REQUEST_TIMEOUT
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = deadline.Sub(c.clock.Now())
+	}
+	if timeout <= 0 {
+		return fmt.Errorf("locking page %s: %w", pageNo, context.DeadlineExceeded)
+	}
+	reply := c.CallRPC(ctx, c.txnLockMessage(TXN_LOCK, txnID, pageNo, timeout), CENTRALMANAGER, -1, c.managerIP(pageNo))
+	if reply.Ack {
+		return nil
+	}
+	// The manager gives up at the same deadline
+	if ctx.Err() != nil {
+		return fmt.Errorf("locking page %s: %w", pageNo, ctx.Err())
+	}
+	if reply.Err != "" {
+		return errors.New(reply.Err)
+	}
+	return fmt.Errorf("%s for page %s not acknowledged by CM", TXN_LOCK, pageNo)
+}
+
+func (c *Client) unlockForTxn(txnID string, pageNo string) {
+	reply := c.CallRPC(context.Background(), c.txnLockMessage(TXN_UNLOCK, txnID, pageNo, 0), CENTRALMANAGER, -1, c.managerIP(pageNo))
+	if !reply.Ack {
+		logwarning.Printf("Msg [%s] for Page %s not acknowledged, the lock will be released when its lease expires\n", TXN_UNLOCK, pageNo)
+	}
+}
+
+func (c *Client) txnLockMessage(msgType string, txnID string, pageNo string, timeout time.Duration) Message {
+	return Message{
+		Type: msgType,
+		Payload: Payload{
+			TxnLock: TxnLock{
+				PageNo:  pageNo,
+				Timeout: timeout,
+			},
+		},
+		FromID:    c.ID,
+		FromIP:    c.IP,
+		RequestID: txnID,
+	}
+}
+
+// Fetches pageNo with READWRITE access under the transaction's lock, unless this client already has it
+func (c *Client) ownForTxn(ctx context.Context, txnID string, pageNo string) error {
+	c.mu.Lock()
+	page, exists := c.PageStore[pageNo]
+	c.mu.Unlock()
+	if exists && page.Access == READWRITE {
+		return nil
+	}
+
+	writeRequest := c.newWriteFault(pageNo, 0, nil)
+	writeRequest.TxnID = txnID
+	c.beginFetch(pageNo, FETCHING_WRITE)
+	_, err := c.awaitRequest(ctx, writeRequest)
+	if err != nil {
+		c.abortFetch(pageNo, FETCHING_WRITE)
+	}
+	if err == errAlreadyCompleted {
+		return nil
+	}
+	return err
+}
+
+// Applies every op if this client holds all their pages with READWRITE access, and none otherwise
+func (c *Client) applyTxn(ops []txnOp) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, op := range ops {
+		if page, exists := c.PageStore[op.pageNo]; !exists || page.Access != READWRITE {
+			return fmt.Errorf("page %s was not held exclusively, as it is a write-update page with other copies", op.pageNo)
+		}
+	}
+	for _, op := range ops {
+		page := c.PageStore[op.pageNo]
+		page.Content = op.apply(page.Content)
+		c.PageStore[op.pageNo] = page
+	}
+	return nil
+}
+
+// Waits for the lock on a page of a transaction. The lock is released by TXN_UNLOCK, or when its lease expires.
+func (cm *CentralManager) handleTxnLock(ctx context.Context, msg Message) error {
+	lock := msg.Payload.TxnLock
+	if lock.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = cm.clock.WithTimeout(ctx, lock.Timeout)
+		defer cancel()
+	}
+	if _, err := cm.pageLocks.acquire(ctx, lock.PageNo, msg.RequestID); err != nil {
+		logwarning.Printf("Transaction %s could not lock Page %s within %v\n", msg.RequestID, lock.PageNo, lock.Timeout)
+		return fmt.Errorf("page %s not locked within %v", lock.PageNo, lock.Timeout)
+	}
+	logsystem.Printf("Page %s locked for transaction %s\n", lock.PageNo, msg.RequestID)
+	return nil
+}
@@ -0,0 +1,147 @@
+package ivy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTransactionCommit(t *testing.T) {
+	dsm := newTestDSM(t, Config{}, 2)
+	c1, c2 := dsm.clients[0], dsm.clients[1]
+	// Client 2 holds copies that the commit must invalidate
+	if err := c2.Write("1", []byte("old")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c2.Read("1"); err != nil {
+		t.Fatal(err)
+	}
+
+	txn := c1.NewTransaction()
+	txn.Write("1", []byte("x"))
+	txn.WriteAt(2*PAGE_SIZE-1, []byte("yz"))
+	// Sees the write to page 1 made earlier in the transaction
+	txn.Update("1", func(content []byte) []byte {
+		return append(append([]byte{}, content[:1]...), '!')
+	})
+	if err := txn.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if page, err := c2.Read("1"); err != nil || historyValue(page.Content) != "x!" {
+		t.Errorf("page read as %q, %v, want %q", historyValue(page.Content), err, "x!")
+	}
+	// Written across two pages that nobody had written before
+	if got, err := c2.ReadAt(2*PAGE_SIZE-1, 2); err != nil || string(got) != "yz" {
+		t.Errorf("read %q, %v, want %q", got, err, "yz")
+	}
+}
+
+// Transactions writing the same pages commit one after the other, so the pages end up with the
+// writes of the same transaction
+func TestTransactionsUnderContention(t *testing.T) {
+	for _, mode := range []string{CENTRAL, IMPROVED, FIXED} {
+		t.Run(mode, func(t *testing.T) {
+			dsm := newTestDSM(t, Config{Mode: mode}, 3)
+			contend(t, dsm.clients, 3, func(c *Client) error {
+				txn := c.NewTransaction()
+				value := []byte(fmt.Sprintf("client %d", c.ID))
+				// Locked in page number order whatever the order of the writes
+				txn.Write("2", value)
+				txn.Write("0", value)
+				txn.Write("1", value)
+				return txn.Commit()
+			})
+			values := map[string]bool{}
+			for _, pageNo := range []string{"0", "1", "2"} {
+				page, err := dsm.clients[0].Read(pageNo)
+				if err != nil {
+					t.Fatal(err)
+				}
+				values[historyValue(page.Content)] = true
+			}
+			if len(values) != 1 {
+				t.Errorf("pages hold the writes of several transactions: %v", values)
+			}
+		})
+	}
+}
+
+// A transaction that cannot lock its pages aborts, and leaves the pages to the next one
+func TestTransactionAbortsOnTimeout(t *testing.T) {
+	dsm := newTestDSM(t, Config{}, 2)
+	c1, c2 := dsm.clients[0], dsm.clients[1]
+	if err := c1.Write("0", []byte("before")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dsm.cm.pageLocks.acquire(context.Background(), "1", "other"); err != nil {
+		t.Fatal(err)
+	}
+	txn := c2.NewTransaction()
+	txn.Write("0", []byte("aborted"))
+	txn.Write("1", []byte("aborted"))
+	start := dsm.clock.Now()
+	if err := txn.Commit(); !errors.Is(err, ErrTransactionAborted) {
+		t.Fatalf("got %v, want %v", err, ErrTransactionAborted)
+	}
+	if waited := dsm.clock.Now().Sub(start); waited < TXN_TIMEOUT {
+		t.Errorf("aborted after %v, want %v", waited, TXN_TIMEOUT)
+	}
+	if page, err := c1.Read("0"); err != nil || historyValue(page.Content) != "before" {
+		t.Errorf("page read as %q, %v", historyValue(page.Content), err)
+	}
+
+	dsm.cm.pageLocks.release("1", "other")
+	txn = c2.NewTransaction()
+	txn.Write("0", []byte("committed"))
+	txn.Write("1", []byte("committed"))
+	if err := txn.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if page, err := c1.Read("1"); err != nil || historyValue(page.Content) != "committed" {
+		t.Errorf("page read as %q, %v", historyValue(page.Content), err)
+	}
+}
+
+func TestTransactionNeedsManager(t *testing.T) {
+	for _, cfg := range []Config{{Mode: DYNAMIC}, {Consistency: RELEASE}} {
+		t.Run(cfg.Mode+cfg.Consistency, func(t *testing.T) {
+			dsm := newTestDSM(t, cfg, 1)
+			txn := dsm.clients[0].NewTransaction()
+			txn.Write("0", []byte("x"))
+			if err := txn.Commit(); err == nil {
+				t.Error("transaction committed")
+			}
+		})
+	}
+}
+
+func TestTransactionCommitContextDeadline(t *testing.T) {
+	dsm := newTestDSM(t, Config{}, 1)
+	// Another transaction holds page 1
+	if _, err := dsm.cm.pageLocks.acquire(context.Background(), "1", "other"); err != nil {
+		t.Fatal(err)
+	}
+	txn := dsm.clients[0].NewTransaction()
+	txn.Write("0", []byte("a"))
+	txn.Write("1", []byte("b"))
+
+	ctx, cancel := dsm.clock.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := txn.CommitContext(ctx)
+	if !errors.Is(err, ErrTransactionAborted) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want %v at the deadline", err, ErrTransactionAborted)
+	}
+	// The TXN_LOCK left the queue of page 1, and page 0 was unlocked
+	dsm.waitFor(t, func() bool {
+		dsm.cm.pageLocks.mu.Lock()
+		defer dsm.cm.pageLocks.mu.Unlock()
+		_, locked := dsm.cm.pageLocks.pages["0"]
+		return !locked && len(dsm.cm.pageLocks.pages["1"].queue) == 0
+	})
+	// Nothing was written, not even to the page locked first
+	if _, err := dsm.clients[0].Read("0"); !errors.Is(err, errNoSuchPage) {
+		t.Errorf("got %v, want %v", err, errNoSuchPage)
+	}
+}
@@ -0,0 +1,97 @@
+package ivy
+
+import (
+	"context"
+	"fmt"
+)
+
+// Read-to-write upgrade. A client writing a page it holds a READ copy of sends UPGRADE_REQUEST
+// instead of WRITE_REQUEST. The CM invalidates every other copy, including the owner's, and grants
+// ownership with UPGRADE_GRANT: the page content does not travel. The requester confirms with the
+// usual WRITE_CONFIRMATION.
+//
+// Upgrades are only done in CENTRAL and FIXED modes, where the CM knows the copy set. Whenever the
+// CM cannot upgrade in place, e.g. because the requester's copy was invalidated while the request
+// was queued, the request is handled as a WRITE_REQUEST.
+
+func (cm *CentralManager) handleUpgradeRequest(ctx context.Context, msg Message) error {
+	pageNo := msg.Payload.WriteRequest.PageNo
+	offset := msg.Payload.WriteRequest.Offset
+	content := msg.Payload.WriteRequest.Content
+	requesterID := msg.FromID
+
+	cm.mu.Lock()
+	pageInfo, exists := cm.MetaData[pageNo]
+	cm.mu.Unlock()
+	if !exists || pageInfo.Coherence == WRITE_UPDATE || cm.mode == IMPROVED || !holdsCopy(pageInfo, requesterID) {
+		logwarning.Printf("Cannot upgrade Page %s in place for Client %d, handling it as a %s\n", pageNo, requesterID, WRITE_REQUEST)
+		return cm.handleWriteRequest(ctx, msg)
+	}
+
+	invalidateCopy := Message{
+		Type: INVALIDATE_COPY,
+		Payload: Payload{
+			InvalidateCopy: InvalidateCopy{
+				WriteRequesterID: requesterID,
+				PageNumber:       pageNo,
+			},
+		},
+		RequestID: msg.RequestID,
+	}
+	err := fanOut(ctx, cm.clock, INVALIDATE_COPY, pageNo, otherHolders(pageInfo.CopySet, pageInfo.Owner, requesterID), func(ctx context.Context, clientPointer ClientPointer) Reply {
+		return cm.CallRPC(ctx, invalidateCopy, CLIENT, clientPointer.ID, clientPointer.IP)
+	})
+	if err != nil {
+		logerror.Println("Cannot grant upgrade")
+		return err
+	}
+
+	upgradeGrant := Message{
+		Type: UPGRADE_GRANT,
+		Payload: Payload{
+			UpgradeGrant: UpgradeGrant{
+				PageNumber: pageNo,
+				Offset:     offset,
+				Content:    content,
+			},
+		},
+		RequestID: msg.RequestID,
+	}
+	reply := cm.CallRPC(ctx, upgradeGrant, CLIENT, requesterID, msg.FromIP)
+	if !reply.Ack {
+		logerror.Printf("Msg [%s] from CM not acknowledged by Client %d\n", UPGRADE_GRANT, requesterID)
+		return fmt.Errorf("%s for page %s not acknowledged by Client %d", UPGRADE_GRANT, pageNo, requesterID)
+	}
+	return nil
+}
+
+// Whether the CM knows clientID to hold a valid copy of the page
+func holdsCopy(pageInfo PageInfo, clientID int) bool {
+	if pageInfo.Owner.ID == clientID {
+		return true
+	}
+	for _, clientPointer := range pageInfo.CopySet {
+		if clientPointer.ID == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+// Takes ownership of a page this client holds a READ copy of, then confirms with the CM
+func (c *Client) handleUpgradeGrant(msg Message) bool {
+	pageNo := msg.Payload.UpgradeGrant.PageNumber
+	page, exists := c.upgradePage(pageNo, msg.Payload.UpgradeGrant.Offset, msg.Payload.UpgradeGrant.Content)
+	if !exists {
+		logerror.Printf("Page %s granted for upgrade does not exist in Client %d's PageStore\n", pageNo, c.ID)
+		return false
+	}
+
+	if err := c.confirmWrite(pageNo, msg.RequestID); err != nil {
+		// Resent as after a lost WRITE_CONFIRMATION reply in handlePageSend
+		c.pending.retry(msg.RequestID)
+		return true
+	}
+	c.pending.complete(msg.RequestID, requestResult{page: page})
+	return true
+}
@@ -1,12 +1,12 @@
-package main
+package ivy
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
-	"log"
+	"errors"
 	"net"
-	"net/rpc"
 	"os"
+	"path/filepath"
 )
 
 const (
@@ -14,38 +14,14 @@ const (
 	CENTRALMANAGER = "CentralManager"
 )
 
-func (cm *CentralManager) CallRPC(msg Message, nodeType string, targetID int, targetIP string) (reply Reply) {
-	logoutgoing.Printf("CM with IP: %s is sending message %s to Client [%d] with IP: %s\n", cm.IP, msg.Type, targetID, targetIP)
-	clnt, err := rpc.Dial("tcp", targetIP)
-	if err != nil {
-		logerror.Println("Error dialing RPC: ", err)
-		reply.Ack = false
-		return reply
-	}
-	err = clnt.Call(fmt.Sprintf("%s.HandleIncomingMessage", nodeType), msg, &reply)
-	if err != nil {
-		logerror.Printf("Error calling RPC from Msg [%s]: %v\n", msg.Type, err)
-		reply.Ack = false
-		return reply
-	}
-	return reply
+func (cm *CentralManager) CallRPC(ctx context.Context, msg Message, nodeType string, targetID int, targetIP string) Reply {
+	logoutgoing.Printf("CM with IP: %s is sending message %s to %s [%d] with IP: %s\n", cm.IP, msg.Type, nodeType, targetID, targetIP)
+	return sendMessage(ctx, cm.transport, cm.stats, cm.calls, msg, Target{NodeType: nodeType, ID: targetID, Addr: targetIP})
 }
 
-func (client *Client) CallRPC(msg Message, nodeType string, targetID int, targetIP string) (reply Reply) {
+func (client *Client) CallRPC(ctx context.Context, msg Message, nodeType string, targetID int, targetIP string) Reply {
 	logoutgoing.Printf("Client [%d] with IP: [%s] is sending message %s to %s [%d] with IP [%s]\n", client.ID, client.IP, msg.Type, nodeType, targetID, targetIP)
-	clnt, err := rpc.Dial("tcp", targetIP)
-	if err != nil {
-		logerror.Println("Error dialing RPC: ", err)
-		reply.Ack = false
-		return reply
-	}
-	err = clnt.Call(fmt.Sprintf("%s.HandleIncomingMessage", nodeType), msg, &reply)
-	if err != nil {
-		logerror.Printf("Error calling RPC from Msg [%s]: %v\n", msg.Type, err)
-		reply.Ack = false
-		return reply
-	}
-	return reply
+	return sendMessage(ctx, client.transport, client.stats, client.calls, msg, Target{NodeType: nodeType, ID: targetID, Addr: targetIP})
 }
 
 /*
@@ -54,7 +30,8 @@ Function to automatically get the outbound IP without user input in .env file
 func GetOutboundIP() net.IP {
 	conn, err := net.Dial("udp", "8.8.8.8:80")
 	if err != nil {
-		log.Fatal(err)
+		logerror.Println("Could not determine outbound IP, using loopback: ", err)
+		return net.IPv4(127, 0, 0, 1)
 	}
 	defer conn.Close()
 
@@ -80,7 +57,21 @@ func GetFreePort() (int, error) {
 	return l.Addr().(*net.TCPAddr).Port, nil
 }
 
-func writeCMToFile(cms []CentralManager) error {
+// cm.json and client.json in a data directory shared by all the nodes, from which nodes learn
+// each other's IPs and new clients get their IDs
+type registry struct {
+	dir string
+}
+
+func (r registry) cmPath() string {
+	return filepath.Join(r.dir, "cm.json")
+}
+
+func (r registry) clientPath() string {
+	return filepath.Join(r.dir, "client.json")
+}
+
+func (r registry) writeCMs(cms []CentralManager) error {
 	// Serialize CM to JSON
 	cmJSON, err := json.MarshalIndent(cms, "", "  ")
 	if err != nil {
@@ -88,7 +79,10 @@ func writeCMToFile(cms []CentralManager) error {
 	}
 
 	// Write JSON to cm.json file
-	err = os.WriteFile(CMPATH, cmJSON, 0644)
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return err
+	}
+	err = os.WriteFile(r.cmPath(), cmJSON, 0644)
 	if err != nil {
 		return err
 	}
@@ -96,7 +90,7 @@ func writeCMToFile(cms []CentralManager) error {
 	return nil
 }
 
-func writeClientToFile(clients []Client) error {
+func (r registry) writeClients(clients []Client) error {
 	// Serialize Clients to JSON
 	clientJSON, err := json.MarshalIndent(clients, "", "  ")
 	if err != nil {
@@ -104,7 +98,10 @@ func writeClientToFile(clients []Client) error {
 	}
 
 	// Write JSON to client.json file
-	err = os.WriteFile(CLIENTPATH, clientJSON, 0644)
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return err
+	}
+	err = os.WriteFile(r.clientPath(), clientJSON, 0644)
 	if err != nil {
 		return err
 	}
@@ -112,52 +109,36 @@ func writeClientToFile(clients []Client) error {
 	return nil
 }
 
-func getPrimaryCMIP() (string, error) {
+func (r registry) primaryCMIP() (string, error) {
 	// Read cm.json to get the IP of the primary CM
-	fileContent, err := os.ReadFile(CMPATH)
-	if err != nil {
-		logerror.Println("Error reading cm.json: ", err)
-		return "NIL", err // Handle error accordingly
-	}
-
-	var cms []CentralManager
-	if err := json.Unmarshal(fileContent, &cms); err != nil {
-		logerror.Println("Error Unmarshalling []CM: ", err)
-	}
-
-	// Find the primary CM and return its IP
-	for _, cm := range cms {
+	for _, cm := range r.cms() {
 		if cm.IsPrimary {
 			return cm.IP, nil
 		}
 	}
 
-	logerror.Println("No Primary CM found: ", err)
-	return "NIL", err
+	logerror.Println("No Primary CM found")
+	return "NIL", errors.New("no primary CM in " + r.cmPath())
 }
 
-func getBackupCMIP() (string, error) {
-	// Read cm.json to get the IP of the primary CM
-	fileContent, err := os.ReadFile(CMPATH)
-	if err != nil {
-		logerror.Println("Error reading cm.json: ", err)
-		return "NIL", err // Handle error accordingly
-	}
-
-	var cms []CentralManager
-	if err := json.Unmarshal(fileContent, &cms); err != nil {
-		logerror.Println("Error Unmarshalling []CM: ", err)
+// CM a new client talks to. There is no CM outside CENTRAL and IMPROVED modes.
+func (r registry) clientCMIP(mode string) (string, error) {
+	if mode != CENTRAL && mode != IMPROVED {
+		return NIL, nil
 	}
+	return r.primaryCMIP()
+}
 
-	// Find the primary CM and return its IP
-	for _, cm := range cms {
+func (r registry) backupCMIP() (string, error) {
+	// Read cm.json to get the IP of the backup CM
+	for _, cm := range r.cms() {
 		if !cm.IsPrimary {
 			return cm.IP, nil
 		}
 	}
 
-	logerror.Println("No Primary CM found: ", err)
-	return "NIL", err
+	logerror.Println("No Backup CM found")
+	return "NIL", errors.New("no backup CM in " + r.cmPath())
 }
 
 func getHighestClientID(clients []Client) int {
@@ -174,11 +155,13 @@ func getHighestClientID(clients []Client) int {
 	return highestID
 }
 
-func getAllClients() []Client {
+func (r registry) clients() []Client {
 	// Read client.json to get all Clients
-	fileContent, err := os.ReadFile(CLIENTPATH)
+	fileContent, err := os.ReadFile(r.clientPath())
 	if err != nil {
-		logerror.Println("Error reading client.json: ", err)
+		if !os.IsNotExist(err) {
+			logerror.Println("Error reading client.json: ", err)
+		}
 		return []Client{}
 	}
 
@@ -190,11 +173,13 @@ func getAllClients() []Client {
 	return clientArr
 }
 
-func getAllCMs() []CentralManager {
+func (r registry) cms() []CentralManager {
 	// Read cm.json to get all CMs
-	fileContent, err := os.ReadFile(CMPATH)
+	fileContent, err := os.ReadFile(r.cmPath())
 	if err != nil {
-		logerror.Println("Error reading cm.json: ", err)
+		if !os.IsNotExist(err) {
+			logerror.Println("Error reading cm.json: ", err)
+		}
 		return []CentralManager{}
 	}
 